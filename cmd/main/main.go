@@ -8,12 +8,14 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"tikube-backend/logger-service/module"
 	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/middleware"
 	"tikube-backend/shared/mysql"
 	"tikube-backend/shared/redis"
 	"tikube-backend/shared/utils"
@@ -22,6 +24,9 @@ import (
 
 func main() {
 
+	// Structured JSON logs, the log package writes through the same handler
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
 	dotErr := godotenv.Load()
 	if dotErr != nil {
 		log.Fatalf("Error loading .env file: %v", dotErr)
@@ -33,16 +38,9 @@ func main() {
 
 	dir, _ := os.Getwd()
 
-	sqlBytes, readErr := os.ReadFile(filepath.Join(dir, "/cmd/sql/setup.sql"))
-	if readErr != nil {
-		log.Fatalf("Error reading SQL file: %v", readErr)
-	}
-	sqlScript := string(sqlBytes)
-
-	// Execute the SQL script
-	_, execErr := db.Exec(sqlScript)
-	if execErr != nil {
-		log.Fatalf("Error executing SQL script: %v", execErr)
+	// Create the tables and apply the migrations that are missing
+	if err := mysql.Migrate(db, filepath.Join(dir, "cmd/sql")); err != nil {
+		log.Fatalf("Error migrating database: %v", err)
 	}
 
 	// Initialize Kafka producer and consumer
@@ -63,11 +61,11 @@ func main() {
 	rateLimiter := redis_rate.NewLimiter(rdb)
 
 	//Mounting modules
-	module.LoggerModule(r, db, redisCache, rdb, rateLimiter, consumer, producer)
+	listeners := module.LoggerModule(r, db, redisCache, rdb, rateLimiter, consumer, producer)
 
 	server := &http.Server{
 		Addr:           ":8080",
-		Handler:        r,
+		Handler:        shared_middleware.AccessLogMiddleware(slog.Default(), r),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20, // 2^20 shifting 1 left by 20 = 1,048,576
@@ -80,6 +78,20 @@ func main() {
 		}
 	}()
 
+	// Start the syslog and other network receivers that are configured
+	for _, listener := range listeners {
+		if !listener.Enabled() {
+			continue
+		}
+		listener := listener
+		log.Printf("Starting %s listener", listener.Name())
+		go func() {
+			if err := listener.ListenAndServe(); err != nil && !errors.Is(err, utils.ErrListenerClosed) {
+				log.Fatalf("%s ListenAndServe(): %v", listener.Name(), err)
+			}
+		}()
+	}
+
 	// Setting up a channel to listen for OS signals
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, os.Kill)
@@ -88,6 +100,13 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// Stop receiving logs before the producer they are published with is closed
+	for _, listener := range listeners {
+		if err := listener.Close(); err != nil {
+			log.Printf("Error closing %s listener: %v", listener.Name(), err)
+		}
+	}
+
 	dbErr := db.Close()
 	if dbErr != nil {
 		log.Fatal("Error disconnecting from mysql database: ", dbErr)
@@ -0,0 +1,2 @@
+ALTER TABLE logs ADD COLUMN fingerprint VARCHAR(64) NOT NULL DEFAULT '' AFTER message;
+CREATE INDEX idx_logs_fingerprint ON logs (fingerprint, createdAt);
@@ -0,0 +1,2 @@
+-- Owner of the lease on a running export job, every update of a running job checks it.
+ALTER TABLE export_jobs ADD COLUMN claimedBy VARCHAR(32) NULL AFTER status;
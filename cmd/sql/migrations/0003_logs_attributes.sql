@@ -0,0 +1,5 @@
+-- Structured attributes, and the indexes behind sorting and filtering by time, level and source.
+ALTER TABLE logs ADD COLUMN attributes JSON NULL AFTER fingerprint;
+CREATE INDEX idx_logs_created_at ON logs (createdAt, id);
+CREATE INDEX idx_logs_level_created_at ON logs (logLevel, createdAt);
+CREATE INDEX idx_logs_source_created_at ON logs (source, createdAt);
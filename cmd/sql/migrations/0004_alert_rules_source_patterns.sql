@@ -0,0 +1,4 @@
+-- Alert rule sources follow the source filter: PAYMENTS* used to match PAYROLL too, it becomes
+-- PAYMENTS:*, which matches PAYMENTS and the sources below it. A lone * matches every source.
+UPDATE alert_rules SET source = '' WHERE TRIM(source) = '*';
+UPDATE alert_rules SET source = CONCAT(TRIM(TRAILING '*' FROM TRIM(source)), ':*') WHERE TRIM(source) LIKE '%*' AND TRIM(source) NOT LIKE '%:*';
@@ -0,0 +1,5 @@
+-- W3C trace context of the log, used to find every log of a trace or span.
+ALTER TABLE logs ADD COLUMN traceId CHAR(32) NOT NULL DEFAULT '' AFTER message;
+ALTER TABLE logs ADD COLUMN spanId CHAR(16) NOT NULL DEFAULT '' AFTER traceId;
+CREATE INDEX idx_logs_trace_id ON logs (traceId, createdAt);
+CREATE INDEX idx_logs_span_id ON logs (spanId);
@@ -0,0 +1,4 @@
+-- When an issue was resolved. Only an occurrence that happened after it reopens the issue, so a
+-- replayed or backdated log doesn't. Issues resolved before this migration use their last update.
+ALTER TABLE issues ADD COLUMN resolvedAt DATETIME NULL AFTER lastSeen;
+UPDATE issues SET resolvedAt = updatedAt, updatedAt = updatedAt WHERE status = 'resolved' AND resolvedAt IS NULL;
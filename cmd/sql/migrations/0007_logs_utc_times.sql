@@ -0,0 +1,10 @@
+-- Sessions are pinned to UTC since this migration was added, before that CURRENT_TIMESTAMP wrote the
+-- times of the logs in the server's zone. They are read back in that zone and rewritten in UTC:
+-- UNIX_TIMESTAMP follows the session zone, daylight saving time included, the date arithmetic
+-- doesn't. logs is the only table older than the pin, the others were created with it. On a server
+-- in UTC nothing changes, on a large table the update takes a while.
+SET time_zone = @@global.time_zone;
+UPDATE logs SET
+    createdAt = DATE_ADD('1970-01-01 00:00:00', INTERVAL UNIX_TIMESTAMP(createdAt) SECOND),
+    updatedAt = DATE_ADD('1970-01-01 00:00:00', INTERVAL UNIX_TIMESTAMP(updatedAt) SECOND);
+SET time_zone = '+00:00';
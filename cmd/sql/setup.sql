@@ -1,3 +1,5 @@
+-- Tables as they were first created. Columns and indexes added later are in migrations/, so that
+-- they also reach databases that already have the table.
 CREATE TABLE IF NOT EXISTS logs (
     id INT AUTO_INCREMENT PRIMARY KEY,
     logLevel VARCHAR(50) NOT NULL,
@@ -5,4 +7,74 @@ CREATE TABLE IF NOT EXISTS logs (
     message TEXT NOT NULL,
     createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-);
\ No newline at end of file
+);
+
+CREATE TABLE IF NOT EXISTS issues (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    fingerprint VARCHAR(64) NOT NULL UNIQUE,
+    logLevel VARCHAR(50) NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'open',
+    occurrences BIGINT NOT NULL DEFAULT 1,
+    firstSeen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    lastSeen DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_issues_last_seen (status, lastSeen)
+);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    levels VARCHAR(255) NOT NULL DEFAULT '',
+    source VARCHAR(255) NOT NULL DEFAULT '',
+    threshold INT NOT NULL DEFAULT 0,
+    windowSeconds INT NOT NULL,
+    cooldownSeconds INT NOT NULL DEFAULT 0,
+    webhookUrl VARCHAR(2048) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_history (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    ruleId INT NOT NULL,
+    state VARCHAR(20) NOT NULL,
+    count BIGINT NOT NULL,
+    message TEXT NOT NULL,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_alert_history_rule (ruleId, createdAt),
+    FOREIGN KEY (ruleId) REFERENCES alert_rules(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    owner VARCHAR(255) NOT NULL,
+    filter JSON NOT NULL,
+    columns JSON NOT NULL,
+    timeRange JSON NULL,
+    shareToken VARCHAR(64) NULL UNIQUE,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_saved_searches_owner (owner)
+);
+
+CREATE TABLE IF NOT EXISTS export_jobs (
+    id VARCHAR(32) PRIMARY KEY,
+    format VARCHAR(16) NOT NULL,
+    filter JSON NOT NULL,
+    maxRows INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    rowsWritten BIGINT NOT NULL DEFAULT 0,
+    lastLogId BIGINT NOT NULL DEFAULT 0,
+    sizeBytes BIGINT NOT NULL DEFAULT 0,
+    error TEXT NULL,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updatedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    completedAt DATETIME NULL,
+    expiresAt DATETIME NULL,
+    INDEX idx_export_jobs_status (status, updatedAt)
+);
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// APIError is a non-2xx response of the logger service.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Code       string
+	// RequestId identifies the request in the service's logs.
+	RequestId string
+}
+
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+	if e.RequestId != "" {
+		message += " (request " + e.RequestId + ")"
+	}
+	return message
+}
+
+// Temporary reports whether the request may succeed when retried.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Client calls the logger service's HTTP API.
+type Client struct {
+	endpoint string
+	apiKey   string
+	http     *http.Client
+	// stream has no overall timeout, only one for the response headers
+	stream *http.Client
+}
+
+func NewClient(config Config, timeout time.Duration) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = timeout
+
+	return &Client{
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		apiKey:   config.ApiKey,
+		http:     &http.Client{Timeout: timeout},
+		stream:   &http.Client{Transport: transport},
+	}
+}
+
+// Get decodes the JSON response of GET path?query into dst.
+func (c *Client) Get(ctx context.Context, path string, query url.Values, dst any) error {
+	res, err := c.Open(ctx, path, query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if err := json.NewDecoder(res.Body).Decode(dst); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return nil
+}
+
+// Open sends GET path?query and returns the response when it is successful. The caller closes the body.
+func (c *Client) Open(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	return c.open(ctx, c.http, "application/json", path, query)
+}
+
+// Stream is Open for responses that don't end, such as tails. The timeout only applies until the
+// response headers are received.
+func (c *Client) Stream(ctx context.Context, path string, query url.Values) (*http.Response, error) {
+	return c.open(ctx, c.stream, "application/x-ndjson", path, query)
+}
+
+func (c *Client) open(ctx context.Context, client *http.Client, accept string, path string, query url.Values) (*http.Response, error) {
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("User-Agent", "tikube-cli")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	// Errors of the service are application/problem+json, a proxy in front of it may answer otherwise
+	var problem struct {
+		Detail    string `json:"detail"`
+		Code      string `json:"code"`
+		RequestId string `json:"requestId"`
+	}
+	apiErr := &APIError{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(body))}
+	if json.Unmarshal(body, &problem) == nil && problem.Detail != "" {
+		apiErr.Message, apiErr.Code, apiErr.RequestId = problem.Detail, problem.Code, problem.RequestId
+	}
+	if apiErr.RequestId == "" {
+		apiErr.RequestId = res.Header.Get("X-Request-ID")
+	}
+	return nil, apiErr
+}
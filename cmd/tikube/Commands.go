@@ -0,0 +1,403 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// usageError is reported with exit code 2, like the errors of the flag package. An empty message
+// means the error was already printed.
+type usageError struct {
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// globalFlags are accepted by every command.
+type globalFlags struct {
+	configPath string
+	endpoint   string
+	apiKey     string
+	output     string
+	noColor    bool
+	timeout    time.Duration
+}
+
+func newFlagSet(name, usage string) (*flag.FlagSet, *globalFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: tikube %s [flags]\n\n%s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+
+	g := &globalFlags{}
+	fs.StringVar(&g.configPath, "config", "", "config file (default "+defaultConfigPath()+")")
+	fs.StringVar(&g.endpoint, "endpoint", "", "logger service URL, overrides the config file")
+	fs.StringVar(&g.apiKey, "api-key", "", "API key sent as a bearer token, overrides the config file")
+	fs.StringVar(&g.output, "o", string(outputTable), "output format: table, json or raw")
+	fs.BoolVar(&g.noColor, "no-color", false, "disable colors, also disabled by NO_COLOR or when not writing to a terminal")
+	fs.DurationVar(&g.timeout, "timeout", 30*time.Second, "timeout of each request")
+	return fs, g
+}
+
+// parseFlags parses args, commands take no positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		// The flag package already printed the error and the usage
+		return &usageError{}
+	}
+	if fs.NArg() > 0 {
+		return usagef("unexpected argument %q", fs.Arg(0))
+	}
+	return nil
+}
+
+func (g *globalFlags) client() (*Client, error) {
+	path, explicit := g.configPath, g.configPath != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	config, err := loadConfig(path, explicit)
+	if err != nil {
+		return nil, err
+	}
+	if g.endpoint != "" {
+		config.Endpoint = g.endpoint
+	}
+	if g.apiKey != "" {
+		config.ApiKey = g.apiKey
+	}
+	if _, err := url.ParseRequestURI(config.Endpoint); err != nil {
+		return nil, usagef("invalid endpoint %q", config.Endpoint)
+	}
+	return NewClient(config, g.timeout), nil
+}
+
+func (g *globalFlags) printer() (*Printer, error) {
+	format, err := parseOutputFormat(g.output)
+	if err != nil {
+		return nil, &usageError{message: err.Error()}
+	}
+	return &Printer{w: os.Stdout, format: format, color: useColor(os.Stdout, g.noColor)}, nil
+}
+
+// filterFlags map onto the log filter parameters of the API.
+type filterFlags struct {
+	levels   string
+	minLevel string
+	sources  string
+	query    string
+	since    string
+	until    string
+	tz       string
+}
+
+func (f *filterFlags) register(fs *flag.FlagSet, withRange bool) {
+	fs.StringVar(&f.levels, "level", "", "comma separated levels, e.g. ERROR,FATAL")
+	fs.StringVar(&f.minLevel, "min-level", "", "least severe level shown, e.g. WARN")
+	fs.StringVar(&f.sources, "source", "", "comma separated sources, PAYMENTS:* matches PAYMENTS and every source under it")
+	fs.StringVar(&f.query, "q", "", "LQL query, e.g. 'message:timeout AND attributes.region:eu'")
+	if withRange {
+		fs.StringVar(&f.since, "since", "", "start of the range: a duration (15m, 1h, 7d) or a time (2024-05-01T10:00:00Z, now-1d/d)")
+		fs.StringVar(&f.until, "until", "", "end of the range, in the same forms as --since")
+		fs.StringVar(&f.tz, "tz", "", "time zone of times without one, e.g. Europe/Paris (default UTC)")
+	}
+}
+
+// relativeDuration is a --since value to subtract from now, in the units the API's date math accepts.
+var relativeDuration = regexp.MustCompile(`^[0-9]+[smhdwMy]$`)
+
+func rangeBound(value string) string {
+	if relativeDuration.MatchString(value) {
+		return "now-" + value
+	}
+	return value
+}
+
+func (f *filterFlags) apply(query url.Values) {
+	if f.levels != "" {
+		query.Set("level_filter", strings.ToUpper(f.levels))
+	}
+	if f.minLevel != "" {
+		query.Set("min_level", f.minLevel)
+	}
+	if f.sources != "" {
+		query.Set("source_filter", f.sources)
+	}
+	if f.query != "" {
+		query.Set("q", f.query)
+	}
+	if f.since != "" || f.until != "" {
+		query.Set("date_filter", rangeBound(f.since)+","+rangeBound(f.until))
+	}
+	if f.tz != "" {
+		query.Set("tz", f.tz)
+	}
+}
+
+func runLogs(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("logs", "Lists the logs matching the filters, newest first.\n\n"+
+		"  tikube logs --level ERROR --since 1h --source PAYMENTS:*")
+	var filters filterFlags
+	filters.register(fs, true)
+	limit := fs.Int("limit", 100, "number of logs listed")
+	page := fs.Int("page", 0, "page of results, starting at 0")
+	sort := fs.String("sort", "createdAt:desc", "sort order, e.g. createdAt:asc or level:desc,createdAt:desc")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *limit <= 0 || *page < 0 {
+		return usagef("--limit must be positive and --page can't be negative")
+	}
+
+	client, err := g.client()
+	if err != nil {
+		return err
+	}
+	printer, err := g.printer()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	filters.apply(query)
+	query.Set("limit", strconv.Itoa(*limit))
+	query.Set("offset", strconv.Itoa(*page))
+	query.Set("sort", *sort)
+
+	var result utils.PaginationResult[utils.Log]
+	if err := client.Get(ctx, "/logger/logs", query, &result); err != nil {
+		return err
+	}
+	if err := printer.Logs(result.Data); err != nil {
+		return err
+	}
+	if shown := *page**limit + len(result.Data); printer.format == outputTable && shown < result.Total {
+		_, _ = fmt.Fprintf(os.Stderr, "%d of %d logs shown, use --page %d for more\n", shown, result.Total, *page+1)
+	}
+	return nil
+}
+
+const (
+	// Logs asked for when a broken tail reconnects, so that those stored in the meantime are printed.
+	// It is the largest backlog the service accepts.
+	tailResumeBacklog = 1000
+	tailRetryDelay    = 2 * time.Second
+)
+
+// runTail follows /logger/logs/tail, which sends the last -n logs and then the new ones as the
+// service stores them. When the stream breaks, tail reconnects and skips the logs already printed.
+func runTail(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("tail", "Prints the last logs matching the filters, then the new ones as they arrive.\n\n"+
+		"  tikube tail --min-level WARN --source PAYMENTS:*")
+	var filters filterFlags
+	filters.register(fs, false)
+	lines := fs.Int("n", 10, "number of existing logs printed first")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *lines < 0 || *lines > tailResumeBacklog {
+		return usagef("-n must be between 0 and %d", tailResumeBacklog)
+	}
+
+	client, err := g.client()
+	if err != nil {
+		return err
+	}
+	printer, err := g.printer()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	filters.apply(query)
+	query.Set("backlog", strconv.Itoa(*lines))
+
+	var lastId int64
+	for {
+		// The backlog of a reconnection holds logs that were printed before it broke
+		printedBefore := lastId
+		err := followTail(ctx, client, query, func(log utils.Log) error {
+			if log.Id <= printedBefore {
+				return nil
+			}
+			lastId = max(lastId, log.Id)
+			return printer.Log(log)
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Keep following through restarts of the service, a rejected request won't succeed later
+		var apiErr *APIError
+		var printErr *printError
+		if errors.As(err, &apiErr) && !apiErr.Temporary() || errors.As(err, &printErr) {
+			return err
+		}
+		if err == nil {
+			err = errors.New("the service closed the stream")
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "tikube: %v, reconnecting\n", err)
+
+		if lastId > 0 {
+			query.Set("backlog", strconv.Itoa(tailResumeBacklog))
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(tailRetryDelay):
+		}
+	}
+}
+
+// printError is an error writing a log to the output, tail stops rather than reconnecting.
+type printError struct {
+	err error
+}
+
+func (e *printError) Error() string {
+	return e.err.Error()
+}
+
+func (e *printError) Unwrap() error {
+	return e.err
+}
+
+// followTail calls handle with every log of the stream until it ends.
+func followTail(ctx context.Context, client *Client, query url.Values, handle func(utils.Log) error) error {
+	res, err := client.Stream(ctx, "/logger/logs/tail", query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	decoder := json.NewDecoder(res.Body)
+	for {
+		var log utils.Log
+		if err := decoder.Decode(&log); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := handle(log); err != nil {
+			return &printError{err: err}
+		}
+	}
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("export", "Downloads the logs matching the filters.\n\n"+
+		"  tikube export --format csv --since 1d --level ERROR > errors.csv")
+	var filters filterFlags
+	filters.register(fs, true)
+	format := fs.String("format", "csv", "file format: csv, ndjson or parquet")
+	limit := fs.Int("limit", 0, "largest number of logs exported (default the service's maximum)")
+	out := fs.String("out", "", "file written instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *limit < 0 {
+		return usagef("--limit can't be negative")
+	}
+	if *out == "" && strings.EqualFold(*format, "parquet") && isTerminal(os.Stdout) {
+		return usagef("parquet is binary, use --out or redirect stdout to a file")
+	}
+
+	client, err := g.client()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	filters.apply(query)
+	query.Set("format", *format)
+	if *limit > 0 {
+		query.Set("limit", strconv.Itoa(*limit))
+	}
+
+	res, err := client.Open(ctx, "/logger/logs/export", query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if *out == "" {
+		_, err = io.Copy(os.Stdout, res.Body)
+		return err
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, res.Body); err != nil {
+		_ = f.Close()
+		_ = os.Remove(*out)
+		return err
+	}
+	return f.Close()
+}
+
+func runIssues(ctx context.Context, args []string) error {
+	fs, g := newFlagSet("issues", "Lists the issues, the groups of similar error logs.\n\n"+
+		"  tikube issues --status open --source PAYMENTS")
+	status := fs.String("status", "", "comma separated statuses: open, resolved, ignored")
+	source := fs.String("source", "", "source of the issues")
+	limit := fs.Int("limit", 20, "number of issues listed")
+	page := fs.Int("page", 0, "page of results, starting at 0")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *limit <= 0 || *page < 0 {
+		return usagef("--limit must be positive and --page can't be negative")
+	}
+
+	client, err := g.client()
+	if err != nil {
+		return err
+	}
+	printer, err := g.printer()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *status != "" {
+		query.Set("status_filter", strings.ToLower(*status))
+	}
+	if *source != "" {
+		query.Set("source", *source)
+	}
+	query.Set("limit", strconv.Itoa(*limit))
+	query.Set("offset", strconv.Itoa(*page))
+
+	var result utils.PaginationResult[utils.Issue]
+	if err := client.Get(ctx, "/logger/issues", query, &result); err != nil {
+		return err
+	}
+	return printer.Issues(result.Data)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultEndpoint = "http://localhost:8080"
+
+// Config is read from $XDG_CONFIG_HOME/tikube/config.json (~/.config/tikube/config.json on Linux,
+// the platform's config directory elsewhere) or the file named by TIKUBE_CONFIG:
+//
+//	{"endpoint": "https://logger.example.com", "apiKey": "..."}
+//
+// TIKUBE_ENDPOINT and TIKUBE_API_KEY override the file, and the --endpoint and --api-key flags
+// override both.
+type Config struct {
+	Endpoint string `json:"endpoint"`
+	ApiKey   string `json:"apiKey"`
+}
+
+func defaultConfigPath() string {
+	if path := os.Getenv("TIKUBE_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "tikube", "config.json")
+}
+
+// loadConfig reads the config file at path. A missing file is not an error unless the path was
+// given explicitly, every setting has a default or can come from the environment.
+func loadConfig(path string, explicit bool) (Config, error) {
+	var config Config
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		case err != nil:
+			return config, err
+		default:
+			if err := json.Unmarshal(data, &config); err != nil {
+				return config, fmt.Errorf("invalid config file %s: %w", path, err)
+			}
+		}
+	}
+
+	if endpoint := os.Getenv("TIKUBE_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+	}
+	if apiKey := os.Getenv("TIKUBE_API_KEY"); apiKey != "" {
+		config.ApiKey = apiKey
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = defaultEndpoint
+	}
+	return config, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"tikube-backend/shared/utils"
+	"time"
+	"unicode/utf8"
+)
+
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputRaw   outputFormat = "raw"
+)
+
+func parseOutputFormat(value string) (outputFormat, error) {
+	switch format := outputFormat(strings.ToLower(value)); format {
+	case outputTable, outputJSON, outputRaw:
+		return format, nil
+	}
+	return "", fmt.Errorf("-o must be one of json, table, raw")
+}
+
+const (
+	colorReset   = "\x1b[0m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorGray    = "\x1b[90m"
+)
+
+var levelColors = map[utils.LogLevel]string{
+	utils.TRACE: colorGray,
+	utils.DEBUG: colorCyan,
+	utils.INFO:  colorGreen,
+	utils.WARN:  colorYellow,
+	utils.ERROR: colorRed,
+	utils.FATAL: colorMagenta,
+}
+
+var statusColors = map[utils.IssueStatus]string{
+	utils.IssueOpen:     colorRed,
+	utils.IssueResolved: colorGreen,
+	utils.IssueIgnored:  colorGray,
+}
+
+// Printer writes logs and issues in the selected output format. JSON is written one object per line
+// so that it can be piped to jq, including while tailing.
+type Printer struct {
+	w      io.Writer
+	format outputFormat
+	color  bool
+}
+
+// useColor is false when the output is not a terminal or NO_COLOR is set, see https://no-color.org.
+func useColor(f *os.File, disabled bool) bool {
+	if disabled || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(f)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *Printer) paint(color, text string) string {
+	if !p.color || color == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// Logs prints a page of logs, as a table with a header in table format.
+func (p *Printer) Logs(logs []utils.Log) error {
+	if p.format != outputTable {
+		for _, log := range logs {
+			if err := p.Log(log); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows := [][]cell{{{text: "TIME"}, {text: "LEVEL"}, {text: "SOURCE"}, {text: "MESSAGE"}}}
+	for _, log := range logs {
+		rows = append(rows, []cell{
+			{text: formatTime(log.CreatedAt), color: colorGray},
+			{text: string(log.LogLevel), color: levelColors[log.LogLevel]},
+			{text: log.Source},
+			{text: singleLine(log.Message)},
+		})
+	}
+	return p.table(rows)
+}
+
+// Log prints a single log. Tail prints logs as they arrive, so the level is padded instead of aligned.
+func (p *Printer) Log(log utils.Log) error {
+	var err error
+	switch p.format {
+	case outputJSON:
+		err = json.NewEncoder(p.w).Encode(log)
+	case outputRaw:
+		_, err = fmt.Fprintln(p.w, log.Message)
+	default:
+		_, err = fmt.Fprintf(p.w, "%s %s %s %s\n",
+			p.paint(colorGray, formatTime(log.CreatedAt)),
+			p.paint(levelColors[log.LogLevel], fmt.Sprintf("%-5s", log.LogLevel)),
+			log.Source,
+			singleLine(log.Message))
+	}
+	return err
+}
+
+func (p *Printer) Issues(issues []utils.Issue) error {
+	switch p.format {
+	case outputJSON:
+		enc := json.NewEncoder(p.w)
+		for _, issue := range issues {
+			if err := enc.Encode(issue); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputRaw:
+		for _, issue := range issues {
+			if _, err := fmt.Fprintln(p.w, issue.Message); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	rows := [][]cell{{{text: "ID"}, {text: "STATUS"}, {text: "LEVEL"}, {text: "COUNT"}, {text: "LAST SEEN"}, {text: "SOURCE"}, {text: "MESSAGE"}}}
+	for _, issue := range issues {
+		rows = append(rows, []cell{
+			{text: fmt.Sprint(issue.Id)},
+			{text: string(issue.Status), color: statusColors[issue.Status]},
+			{text: string(issue.LogLevel), color: levelColors[issue.LogLevel]},
+			{text: fmt.Sprint(issue.Occurrences)},
+			{text: formatTime(issue.LastSeen), color: colorGray},
+			{text: issue.Source},
+			{text: singleLine(issue.Message)},
+		})
+	}
+	return p.table(rows)
+}
+
+type cell struct {
+	text  string
+	color string
+}
+
+// table aligns the columns on their visible width. text/tabwriter can't be used because it counts
+// the bytes of the color escapes.
+func (p *Printer) table(rows [][]cell) error {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], utf8.RuneCountInString(c.text))
+		}
+	}
+
+	var b strings.Builder
+	for _, row := range rows {
+		b.Reset()
+		for i, c := range row {
+			b.WriteString(p.paint(c.color, c.text))
+			// The last column is not padded so that long messages don't leave trailing spaces
+			if i < len(row)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c.text)+2))
+			}
+		}
+		b.WriteByte('\n')
+		if _, err := io.WriteString(p.w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// singleLine keeps multi-line messages such as stack traces on their row.
+func singleLine(message string) string {
+	return strings.NewReplacer("\r\n", "↵", "\n", "↵", "\t", " ").Replace(message)
+}
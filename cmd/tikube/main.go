@@ -0,0 +1,86 @@
+// Command tikube queries the logger service from the command line.
+//
+//	tikube logs --level ERROR --since 1h --source PAYMENTS:*
+//	tikube tail --min-level WARN
+//	tikube export --format csv --since 1d > logs.csv
+//	tikube issues --status open
+//
+// The exit code is 0 on success, 1 when a request failed and 2 when the command line is invalid.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"logs", "list logs", runLogs},
+	{"tail", "print new logs as they arrive", runTail},
+	{"export", "download logs as csv, ndjson or parquet", runExport},
+	{"issues", "list issues", runIssues},
+}
+
+func usage() {
+	_, _ = fmt.Fprintln(os.Stderr, "Usage: tikube <command> [flags]\n\nCommands:")
+	for _, c := range commands {
+		_, _ = fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.description)
+	}
+	_, _ = fmt.Fprintln(os.Stderr, "\nRun tikube <command> -h for the flags of a command.")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage()
+		return 0
+	}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		err := c.run(ctx, args[1:])
+		var usageErr *usageError
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.As(err, &usageErr):
+			if usageErr.message == "" {
+				return 2
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "tikube %s: %v\nRun tikube %s -h for usage.\n", c.name, err, c.name)
+			return 2
+		default:
+			_, _ = fmt.Fprintf(os.Stderr, "tikube %s: %v\n", c.name, err)
+			return 1
+		}
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "tikube: unknown command %q\n", args[0])
+	usage()
+	return 2
+}
@@ -0,0 +1,188 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"strconv"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+type Format string
+
+const (
+	CSV     Format = "csv"
+	NDJSON  Format = "ndjson"
+	Parquet Format = "parquet"
+)
+
+// Writer encodes logs one at a time so that exports never hold the whole result in memory.
+// Flush pushes buffered rows to the underlying writer, except for Parquet which writes whole row
+// groups, and Close must be called to write any trailer.
+type Writer interface {
+	Write(log *utils.Log) error
+	Flush() error
+	Close() error
+}
+
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case CSV, NDJSON, Parquet:
+		return Format(value), nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+func (f Format) ContentType() string {
+	switch f {
+	case CSV:
+		return "text/csv; charset=utf-8"
+	case NDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/vnd.apache.parquet"
+	}
+}
+
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Appendable reports whether rows can be appended to a partially written file, which is what
+// resuming an interrupted export needs. Parquet files end with a footer so they can't be.
+func (f Format) Appendable() bool {
+	return f != Parquet
+}
+
+// NewWriter returns a writer for the format. withHeader controls the CSV header row, it is
+// skipped when appending to an existing file.
+func NewWriter(format Format, w io.Writer, withHeader bool) (Writer, error) {
+	switch format {
+	case CSV:
+		cw := &csvWriter{w: csv.NewWriter(w)}
+		if withHeader {
+			if err := cw.w.Write(utils.LogColumns); err != nil {
+				return nil, err
+			}
+		}
+		return cw, nil
+	case NDJSON:
+		return &ndjsonWriter{encoder: json.NewEncoder(w)}, nil
+	case Parquet:
+		return &parquetWriter{w: parquet.NewGenericWriter[parquetLog](w, parquet.MaxRowsPerRowGroup(parquetRowGroupSize))}, nil
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+// Write writes the fields in the order of utils.LogColumns, which is the header row.
+func (cw *csvWriter) Write(log *utils.Log) error {
+	attributes := ""
+	if len(log.Attributes) > 0 {
+		data, err := json.Marshal(log.Attributes)
+		if err != nil {
+			return err
+		}
+		attributes = string(data)
+	}
+
+	return cw.w.Write([]string{
+		strconv.FormatInt(log.Id, 10),
+		string(log.LogLevel),
+		log.Source,
+		log.Message,
+		log.Fingerprint,
+		log.TraceId,
+		log.SpanId,
+		attributes,
+		log.CreatedAt.UTC().Format(time.RFC3339Nano),
+		log.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	})
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	return cw.Flush()
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (nw *ndjsonWriter) Write(log *utils.Log) error {
+	return nw.encoder.Encode(log)
+}
+
+func (nw *ndjsonWriter) Flush() error {
+	return nil
+}
+
+func (nw *ndjsonWriter) Close() error {
+	return nil
+}
+
+// Rows are written to the underlying writer one row group at a time, once a group is full.
+const parquetRowGroupSize = 10_000
+
+type parquetLog struct {
+	Id          int64     `parquet:"id"`
+	LogLevel    string    `parquet:"logLevel,dict"`
+	Source      string    `parquet:"source,dict"`
+	Message     string    `parquet:"message"`
+	Fingerprint string    `parquet:"fingerprint,optional"`
+	TraceId     string    `parquet:"traceId,optional"`
+	SpanId      string    `parquet:"spanId,optional"`
+	Attributes  string    `parquet:"attributes,optional,json"`
+	CreatedAt   time.Time `parquet:"createdAt,timestamp(millisecond)"`
+	UpdatedAt   time.Time `parquet:"updatedAt,timestamp(millisecond)"`
+}
+
+type parquetWriter struct {
+	w *parquet.GenericWriter[parquetLog]
+}
+
+func (pw *parquetWriter) Write(log *utils.Log) error {
+	row := parquetLog{
+		Id:          log.Id,
+		LogLevel:    string(log.LogLevel),
+		Source:      log.Source,
+		Message:     log.Message,
+		Fingerprint: log.Fingerprint,
+		TraceId:     log.TraceId,
+		SpanId:      log.SpanId,
+		CreatedAt:   log.CreatedAt.UTC(),
+		UpdatedAt:   log.UpdatedAt.UTC(),
+	}
+	if len(log.Attributes) > 0 {
+		data, err := json.Marshal(log.Attributes)
+		if err != nil {
+			return err
+		}
+		row.Attributes = string(data)
+	}
+
+	_, err := pw.w.Write([]parquetLog{row})
+	return err
+}
+
+// Flush does nothing, ending the row group at every flush of the exports would make them small.
+// The parquet writer ends a group when it holds parquetRowGroupSize rows and on Close.
+func (pw *parquetWriter) Flush() error {
+	return nil
+}
+
+func (pw *parquetWriter) Close() error {
+	return pw.w.Close()
+}
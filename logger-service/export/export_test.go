@@ -0,0 +1,50 @@
+package export
+
+import (
+	"bytes"
+	"github.com/parquet-go/parquet-go"
+	"testing"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// The services flush every few hundred rows, which must not end a Parquet row group.
+func TestParquetRowGroups(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewWriter(Parquet, &out, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const rows = 2*parquetRowGroupSize + 500
+	now := time.Now()
+	for id := int64(1); id <= rows; id++ {
+		log := utils.Log{Id: id, LogLevel: utils.INFO, Source: "PAYMENTS", Message: "paid", CreatedAt: now, UpdatedAt: now}
+		if err := writer.Write(&log); err != nil {
+			t.Fatal(err)
+		}
+		if id%500 == 0 {
+			if err := writer.Flush(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.NumRows() != rows {
+		t.Errorf("file has %d rows, want %d", file.NumRows(), rows)
+	}
+	var sizes []int64
+	for _, group := range file.RowGroups() {
+		sizes = append(sizes, group.NumRows())
+	}
+	if len(sizes) != 3 || sizes[0] != parquetRowGroupSize || sizes[1] != parquetRowGroupSize || sizes[2] != 500 {
+		t.Errorf("row groups have %v rows, want %d, %d and 500", sizes, parquetRowGroupSize, parquetRowGroupSize)
+	}
+}
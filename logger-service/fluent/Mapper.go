@@ -0,0 +1,108 @@
+package fluent
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/utils"
+)
+
+// Record fields holding the message, level and trace context, in order of preference. "log" is
+// used by the tail and docker inputs, "MESSAGE" by systemd.
+var (
+	messageKeys = []string{"message", "msg", "log", "MESSAGE"}
+	levelKeys   = []string{"level", "severity", "log_level", "lvl"}
+	traceIdKeys = []string{"trace_id", "traceId", "traceID"}
+	spanIdKeys  = []string{"span_id", "spanId", "spanID"}
+)
+
+// ToLogs converts the events of a message. The tag is the source, with its dots turned into source
+// separators so that app.payments is listed under APP. The remaining record fields are attributes.
+func ToLogs(msg *Message) []model.CreateLogSchema {
+	source := strings.ReplaceAll(msg.Tag, ".", utils.SourceSeparator)
+
+	logs := make([]model.CreateLogSchema, 0, len(msg.Events))
+	for _, event := range msg.Events {
+		record := make(map[string]any, len(event.Record))
+		for key, value := range event.Record {
+			record[key] = normalize(value)
+		}
+
+		log := model.CreateLogSchema{LogLevel: utils.INFO, Source: source}
+
+		if message, ok := takeString(record, messageKeys); ok {
+			log.Message = strings.TrimRight(message, "\r\n")
+		} else if data, err := json.Marshal(record); err == nil {
+			log.Message = string(data)
+		}
+		if value, ok := takeString(record, levelKeys); ok {
+			if level, ok := utils.ParseLogLevel(value); ok {
+				log.LogLevel = level
+			} else {
+				record["level"] = value
+			}
+		}
+		if traceId, ok := takeString(record, traceIdKeys); ok {
+			log.TraceId = strings.ToLower(traceId)
+			if spanId, ok := takeString(record, spanIdKeys); ok {
+				log.SpanId = strings.ToLower(spanId)
+			}
+		}
+
+		if attributes := toAttributes(record); len(attributes) > 0 {
+			log.Attributes = attributes
+		}
+		if !event.Time.IsZero() && event.Time.Unix() > 0 {
+			timestamp := event.Time
+			log.Timestamp = &timestamp
+		}
+
+		logs = append(logs, log)
+	}
+	return logs
+}
+
+// takeString removes and returns the first of keys holding a string.
+func takeString(record map[string]any, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := record[key].(string); ok {
+			delete(record, key)
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// toAttributes keeps the fields in key order so that the same ones are kept when there are too many.
+func toAttributes(record map[string]any) map[string]any {
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attributes := map[string]any{}
+	for _, key := range keys {
+		model.AddAttribute(attributes, key, record[key])
+	}
+	return attributes
+}
+
+// normalize turns the raw bytes older clients send for strings into strings, so they are stored as
+// text rather than base64.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+	}
+	return value
+}
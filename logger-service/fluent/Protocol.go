@@ -0,0 +1,214 @@
+// Package fluent receives logs over the Fluentd Forward protocol v1, as sent by Fluentd and Fluent Bit.
+// The Message, Forward, PackedForward and CompressedPackedForward modes are supported, the
+// handshake (shared key authentication) and the UDP heartbeat are not.
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+	"io"
+	"time"
+)
+
+// ext type of EventTime, seconds and nanoseconds as two big endian uint32.
+const eventTimeExt = 0
+
+var ErrTooLarge = errors.New("forward message is too large")
+
+// Event is a single record of a forward message.
+type Event struct {
+	Time   time.Time
+	Record map[string]any
+}
+
+// Message is a decoded forward message in any of the modes. Chunk is set when the client asks for an ack.
+type Message struct {
+	Tag    string
+	Events []Event
+	Chunk  string
+}
+
+// ReadMessage decodes the next forward message. maxSize bounds the decompressed entries of a
+// CompressedPackedForward message.
+func ReadMessage(dec *msgpack.Decoder, maxSize int64) (*Message, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return nil, err
+	}
+	if n < 2 || n > 4 {
+		return nil, fmt.Errorf("forward message must have 2 to 4 elements, got %d", n)
+	}
+
+	tag, err := dec.DecodeString()
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag: %w", err)
+	}
+	msg := &Message{Tag: tag}
+
+	code, err := dec.PeekCode()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []byte
+	remaining := n - 2
+	switch {
+	case isArray(code):
+		// Forward mode: [tag, [[time, record], ...], option]
+		count, err := dec.DecodeArrayLen()
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < count; i++ {
+			event, err := readEntry(dec)
+			if err != nil {
+				return nil, err
+			}
+			msg.Events = append(msg.Events, event)
+		}
+	case msgpcode.IsString(code) || msgpcode.IsBin(code):
+		// PackedForward mode: [tag, msgpack stream of [time, record], option]
+		if entries, err = dec.DecodeBytes(); err != nil {
+			return nil, err
+		}
+	default:
+		// Message mode: [tag, time, record, option]
+		if n < 3 {
+			return nil, errors.New("message mode needs a time and a record")
+		}
+		event, err := readEvent(dec)
+		if err != nil {
+			return nil, err
+		}
+		msg.Events = append(msg.Events, event)
+		remaining--
+	}
+
+	var compressed string
+	if remaining > 0 {
+		options, err := dec.DecodeMap()
+		if err != nil {
+			return nil, fmt.Errorf("invalid option: %w", err)
+		}
+		msg.Chunk, _ = options["chunk"].(string)
+		compressed, _ = options["compressed"].(string)
+	}
+
+	if entries != nil {
+		if msg.Events, err = readPackedEntries(entries, compressed, maxSize); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// readPackedEntries decodes the entries of a PackedForward message, gunzipping them first when
+// the option says they are compressed.
+func readPackedEntries(entries []byte, compressed string, maxSize int64) ([]Event, error) {
+	var reader io.Reader = bytes.NewReader(entries)
+	switch compressed {
+	case "", "text":
+	case "gzip":
+		// Fluent Bit concatenates gzip members, which gzip.Reader reads as one stream
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip entries: %w", err)
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		data, err := io.ReadAll(io.LimitReader(gz, maxSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip entries: %w", err)
+		}
+		if int64(len(data)) > maxSize {
+			return nil, ErrTooLarge
+		}
+		reader = bytes.NewReader(data)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compressed)
+	}
+
+	dec := msgpack.NewDecoder(reader)
+	var events []Event
+	for {
+		event, err := readEntry(dec)
+		if errors.Is(err, io.EOF) {
+			return events, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
+
+// readEntry reads a [time, record] pair.
+func readEntry(dec *msgpack.Decoder) (Event, error) {
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return Event{}, err
+	}
+	if n != 2 {
+		return Event{}, fmt.Errorf("entry must be [time, record], got %d elements", n)
+	}
+	return readEvent(dec)
+}
+
+func readEvent(dec *msgpack.Decoder) (Event, error) {
+	t, err := readTime(dec)
+	if err != nil {
+		return Event{}, err
+	}
+	record, err := dec.DecodeMap()
+	if err != nil {
+		return Event{}, fmt.Errorf("invalid record: %w", err)
+	}
+	return Event{Time: t, Record: record}, nil
+}
+
+// readTime reads an EventTime or the integer seconds sent by older clients.
+func readTime(dec *msgpack.Decoder) (time.Time, error) {
+	code, err := dec.PeekCode()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	switch {
+	case msgpcode.IsExt(code) || msgpcode.IsFixedExt(code):
+		id, length, err := dec.DecodeExtHeader()
+		if err != nil {
+			return time.Time{}, err
+		}
+		if id != eventTimeExt || length != 8 {
+			return time.Time{}, fmt.Errorf("unsupported time extension %d of %d bytes", id, length)
+		}
+		var buf [8]byte
+		if err := dec.ReadFull(buf[:]); err != nil {
+			return time.Time{}, err
+		}
+		seconds, nanos := binary.BigEndian.Uint32(buf[:4]), binary.BigEndian.Uint32(buf[4:])
+		return time.Unix(int64(seconds), int64(nanos)).UTC(), nil
+	case code == msgpcode.Float || code == msgpcode.Double:
+		seconds, err := dec.DecodeFloat64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.UnixMilli(int64(seconds * 1000)).UTC(), nil
+	default:
+		seconds, err := dec.DecodeInt64()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time: %w", err)
+		}
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+}
+
+func isArray(code byte) bool {
+	return msgpcode.IsFixedArray(code) || code == msgpcode.Array16 || code == msgpcode.Array32
+}
@@ -0,0 +1,215 @@
+package fluent
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+const (
+	// Largest forward message accepted, compressed entries are limited to the same size once decompressed.
+	maxMessageSize = utils.MaxPayloadSize
+	// Connections without any traffic for this long are closed.
+	idleTimeout = 5 * time.Minute
+	// How long a message waits for Kafka to confirm its records before it is left unacked.
+	publishTimeout = 30 * time.Second
+)
+
+// Publisher is the ingestion path shared with the HTTP endpoints, implemented by LoggerService.
+type Publisher interface {
+	PublishLogsConfirmed(ctx context.Context, logs []model.CreateLogSchema) (rejected int, reason error, err error)
+}
+
+type Server struct {
+	addr      string
+	publisher Publisher
+
+	mu       sync.Mutex
+	closed   bool
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+// NewServerFromEnv listens on FLUENT_FORWARD_ADDR, usually :24224. The server is disabled when it is not set.
+func NewServerFromEnv(publisher Publisher) *Server {
+	return NewServer(os.Getenv("FLUENT_FORWARD_ADDR"), publisher)
+}
+
+func NewServer(addr string, publisher Publisher) *Server {
+	return &Server{addr: addr, publisher: publisher, conns: map[net.Conn]struct{}{}}
+}
+
+func (s *Server) Name() string {
+	return "fluent forward"
+}
+
+func (s *Server) Enabled() bool {
+	return s.addr != ""
+}
+
+// ListenAndServe blocks until Close is called, after which it returns utils.ErrListenerClosed.
+func (s *Server) ListenAndServe() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return utils.ErrListenerClosed
+	}
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return utils.ErrListenerClosed
+			}
+			log.Printf("Error accepting fluent forward connection: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return utils.ErrListenerClosed
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the listener and closes the open connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// serveConn reads forward messages until the client disconnects. A message that can't be decoded
+// leaves the stream out of sync, so the connection is closed and the client resends it.
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	reader := &limitedReader{r: bufio.NewReader(conn)}
+	dec := msgpack.NewDecoder(reader)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		reader.remaining = maxMessageSize
+
+		msg, err := ReadMessage(dec, maxMessageSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !s.isClosed() {
+				log.Printf("Error reading fluent forward message from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		rejected, reason, err := s.publisher.PublishLogsConfirmed(ctx, ToLogs(msg))
+		cancel()
+		// Invalid records would be rejected again, the chunk is acked without them
+		if rejected > 0 {
+			log.Printf("Rejected %d of %d records of a fluent forward message from %s: %v", rejected, len(msg.Events), conn.RemoteAddr(), reason)
+		}
+		if err != nil {
+			log.Printf("Error publishing fluent forward message from %s: %v", conn.RemoteAddr(), err)
+			// The chunk is only acked once its valid records are in Kafka. Closing the connection
+			// makes the client resend it, records that were published may then be stored twice.
+			if msg.Chunk != "" {
+				return
+			}
+			continue
+		}
+
+		if msg.Chunk != "" {
+			ack, err := msgpack.Marshal(map[string]string{"ack": msg.Chunk})
+			if err != nil {
+				return
+			}
+			if _, err := conn.Write(ack); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// limitedReader bounds the bytes read for a single message, so a length prefix can't make the
+// decoder buffer more than maxMessageSize. It implements io.ByteScanner so that msgpack reads from
+// it directly instead of adding a buffer of its own.
+type limitedReader struct {
+	r         *bufio.Reader
+	remaining int64
+}
+
+func (lr *limitedReader) Read(p []byte) (int, error) {
+	if lr.remaining <= 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > lr.remaining {
+		p = p[:lr.remaining]
+	}
+	n, err := lr.r.Read(p)
+	lr.remaining -= int64(n)
+	return n, err
+}
+
+func (lr *limitedReader) ReadByte() (byte, error) {
+	if lr.remaining <= 0 {
+		return 0, ErrTooLarge
+	}
+	b, err := lr.r.ReadByte()
+	if err == nil {
+		lr.remaining--
+	}
+	return b, err
+}
+
+func (lr *limitedReader) UnreadByte() error {
+	if err := lr.r.UnreadByte(); err != nil {
+		return err
+	}
+	lr.remaining++
+	return nil
+}
@@ -0,0 +1,230 @@
+package fluent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/vmihailenco/msgpack/v5"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"tikube-backend/logger-service/model"
+	"time"
+)
+
+type fakePublisher struct {
+	rejected int
+	err      error
+}
+
+func (fp *fakePublisher) PublishLogsConfirmed(_ context.Context, logs []model.CreateLogSchema) (int, error, error) {
+	var reason error
+	if fp.rejected > 0 {
+		reason = errors.New("message is required")
+	}
+	return fp.rejected, reason, fp.err
+}
+
+// A chunk is acked unless Kafka fails, invalid records would be rejected again when resent.
+func TestServeConnAck(t *testing.T) {
+	tests := []struct {
+		name      string
+		publisher *fakePublisher
+		acked     bool
+	}{
+		{"published", &fakePublisher{}, true},
+		{"invalid records", &fakePublisher{rejected: 1}, true},
+		{"kafka failure", &fakePublisher{err: errors.New("kafka is down")}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			go NewServer("", tt.publisher).serveConn(server)
+
+			msg, err := msgpack.Marshal([]any{"app", []any{
+				[]any{int64(1700000000), map[string]any{"log": "hello"}},
+				[]any{int64(1700000000), map[string]any{"log": ""}},
+			}, map[string]any{"chunk": "c1"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = client.SetDeadline(time.Now().Add(5 * time.Second))
+			if _, err := client.Write(msg); err != nil {
+				t.Fatal(err)
+			}
+
+			var ack map[string]string
+			err = msgpack.NewDecoder(client).Decode(&ack)
+			if tt.acked && (err != nil || ack["ack"] != "c1") {
+				t.Errorf("got %v, %v, want the ack of c1", ack, err)
+			}
+			if !tt.acked && err == nil {
+				t.Errorf("got %v, want the connection closed without an ack", ack)
+			}
+		})
+	}
+}
+
+// eventTime encodes t as an EventTime, the fixext 8 of type 0 Fluent Bit and Fluentd v1 send.
+func eventTime(t time.Time) msgpack.RawMessage {
+	return append([]byte{0xd7, eventTimeExt}, binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, uint32(t.Unix())), uint32(t.Nanosecond()))...)
+}
+
+func mustMarshal(t *testing.T, v ...any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	for _, value := range v {
+		if err := enc.Encode(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func mustGzip(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadMessage(t *testing.T) {
+	at := time.Date(2024, 5, 15, 13, 47, 21, 123_456_789, time.UTC)
+	second := time.Date(2024, 5, 15, 13, 47, 22, 0, time.UTC)
+	first := map[string]any{"log": "charge failed", "level": "error"}
+	next := map[string]any{"log": "retrying"}
+	entries := mustMarshal(t, []any{eventTime(at), first}, []any{eventTime(second), next})
+
+	tests := []struct {
+		name string
+		data []byte
+		want Message
+	}{
+		{
+			// ["app", EventTime(1715780841, 123456789), {"log": "hello"}, {"chunk": "c1"}]
+			"message",
+			[]byte{
+				0x94, 0xa3, 'a', 'p', 'p',
+				0xd7, 0x00, 0x66, 0x44, 0xbc, 0xe9, 0x07, 0x5b, 0xcd, 0x15,
+				0x81, 0xa3, 'l', 'o', 'g', 0xa5, 'h', 'e', 'l', 'l', 'o',
+				0x81, 0xa5, 'c', 'h', 'u', 'n', 'k', 0xa2, 'c', '1',
+			},
+			Message{Tag: "app", Events: []Event{{at, map[string]any{"log": "hello"}}}, Chunk: "c1"},
+		},
+		{
+			"message with integer seconds",
+			mustMarshal(t, []any{"app", int64(1715780841), next}),
+			Message{Tag: "app", Events: []Event{{time.Unix(1715780841, 0).UTC(), next}}},
+		},
+		{
+			"forward",
+			mustMarshal(t, []any{"app", []any{[]any{eventTime(at), first}, []any{1715780842.0, next}}, map[string]any{"chunk": "c2"}}),
+			Message{Tag: "app", Events: []Event{{at, first}, {second, next}}, Chunk: "c2"},
+		},
+		{
+			"packed forward as str",
+			mustMarshal(t, []any{"app", string(entries), map[string]any{"chunk": "c3", "size": 2}}),
+			Message{Tag: "app", Events: []Event{{at, first}, {second, next}}, Chunk: "c3"},
+		},
+		{
+			"packed forward as bin",
+			mustMarshal(t, []any{"app", entries}),
+			Message{Tag: "app", Events: []Event{{at, first}, {second, next}}},
+		},
+		{
+			"packed forward as text",
+			mustMarshal(t, []any{"app", entries, map[string]any{"compressed": "text"}}),
+			Message{Tag: "app", Events: []Event{{at, first}, {second, next}}},
+		},
+		{
+			// Fluent Bit compresses each record on its own and concatenates the gzip members
+			"compressed packed forward",
+			mustMarshal(t, []any{"app", append(
+				mustGzip(t, mustMarshal(t, []any{eventTime(at), first})),
+				mustGzip(t, mustMarshal(t, []any{eventTime(second), next}))...,
+			), map[string]any{"chunk": "c4", "compressed": "gzip", "size": 2}}),
+			Message{Tag: "app", Events: []Event{{at, first}, {second, next}}, Chunk: "c4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMessage(msgpack.NewDecoder(bytes.NewReader(tt.data)), 1<<20)
+			if err != nil {
+				t.Fatalf("ReadMessage returned %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("ReadMessage = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// Clients send messages back to back on one connection.
+func TestReadMessageStream(t *testing.T) {
+	data := mustMarshal(t,
+		[]any{"first", int64(1715780841), map[string]any{"log": "one"}},
+		[]any{"second", []any{[]any{int64(1715780842), map[string]any{"log": "two"}}}},
+	)
+
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	for _, tag := range []string{"first", "second"} {
+		msg, err := ReadMessage(dec, 1<<20)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Tag != tag || len(msg.Events) != 1 {
+			t.Errorf("ReadMessage = %+v, want a single event tagged %s", msg, tag)
+		}
+	}
+	if _, err := ReadMessage(dec, 1<<20); !errors.Is(err, io.EOF) {
+		t.Errorf("ReadMessage at the end of the stream returned %v, want io.EOF", err)
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	record := map[string]any{"log": "hello"}
+	large := mustMarshal(t, []any{int64(1715780841), map[string]any{"log": strings.Repeat("a", 2048)}})
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not an array", mustMarshal(t, "app"), nil},
+		{"too few elements", mustMarshal(t, []any{"app"}), nil},
+		{"too many elements", mustMarshal(t, []any{"app", 1, record, map[string]any{}, 5}), nil},
+		{"tag not a string", mustMarshal(t, []any{42, int64(1715780841), record}), nil},
+		{"message without a record", mustMarshal(t, []any{"app", int64(1715780841)}), nil},
+		{"record not a map", mustMarshal(t, []any{"app", int64(1715780841), "hello"}), nil},
+		{"invalid time", mustMarshal(t, []any{"app", true, record}), nil},
+		{"unsupported time extension", []byte{0x93, 0xa3, 'a', 'p', 'p', 0xd7, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0x80}, nil},
+		{"entry with three elements", mustMarshal(t, []any{"app", []any{[]any{int64(1715780841), record, "extra"}}}), nil},
+		{"option not a map", mustMarshal(t, []any{"app", int64(1715780841), record, "c1"}), nil},
+		{"unsupported compression", mustMarshal(t, []any{"app", large, map[string]any{"compressed": "zstd"}}), nil},
+		{"invalid gzip", mustMarshal(t, []any{"app", large, map[string]any{"compressed": "gzip"}}), nil},
+		{"decompressed too large", mustMarshal(t, []any{"app", mustGzip(t, large), map[string]any{"compressed": "gzip"}}), ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMessage(msgpack.NewDecoder(bytes.NewReader(tt.data)), 1024)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("ReadMessage = %+v, %v, want an error", got, err)
+			}
+		})
+	}
+}
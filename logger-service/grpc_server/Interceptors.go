@@ -0,0 +1,242 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis_rate/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"net"
+	"strconv"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/tracing"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// requestIdMetadata is the gRPC counterpart of the X-Request-ID header.
+const requestIdMetadata = "x-request-id"
+
+// httpCodes maps the http_error status codes onto gRPC codes, following the mapping gRPC gateways use.
+var httpCodes = map[int]codes.Code{
+	400: codes.InvalidArgument,
+	401: codes.Unauthenticated,
+	403: codes.PermissionDenied,
+	404: codes.NotFound,
+	409: codes.AlreadyExists,
+	413: codes.ResourceExhausted,
+	415: codes.InvalidArgument,
+	429: codes.ResourceExhausted,
+	431: codes.InvalidArgument,
+}
+
+// toStatus is the gRPC counterpart of ErrorHandlerMiddleware: http_error errors keep their message,
+// other errors that are not already a status become an internal error whose cause is only logged.
+func toStatus(ctx context.Context, method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var httpErr *http_error.HTTPError
+	if errors.As(err, &httpErr) {
+		code, ok := httpCodes[httpErr.StatusCode]
+		if !ok {
+			code = codes.Internal
+		}
+		if code == codes.Internal && httpErr.Cause != nil {
+			logFailure(ctx, method, httpErr.Cause)
+		}
+		return status.Error(code, httpErr.Message)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	logFailure(ctx, method, err)
+	return status.Error(codes.Internal, "Internal Server Error")
+}
+
+func logFailure(ctx context.Context, method string, cause error) {
+	slog.Error("grpc call failed", "requestId", utils.RequestIdFromContext(ctx), "method", method, "error", cause.Error())
+}
+
+func unaryErrorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	return resp, toStatus(ctx, info.FullMethod, err)
+}
+
+func streamErrorInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatus(stream.Context(), info.FullMethod, handler(srv, stream))
+}
+
+// requestContext keeps the x-request-id metadata of the call, or assigns one, and sends it back in
+// the x-request-id header, as AccessLogMiddleware does for HTTP requests. The principal comes from
+// the authorization metadata.
+func requestContext(ctx context.Context, setHeader func(metadata.MD) error) (context.Context, *utils.RequestInfo) {
+	requestId, authorization := "", ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIdMetadata); len(values) > 0 {
+			requestId = values[0]
+		}
+		if values := md.Get("authorization"); len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	if !utils.IsValidRequestId(requestId) {
+		requestId = utils.NewRequestId()
+	}
+
+	info := &utils.RequestInfo{Id: requestId, Principal: utils.PrincipalFromAuthorization(authorization)}
+	_ = setHeader(metadata.Pairs(requestIdMetadata, requestId))
+	return utils.WithRequestInfo(ctx, info), info
+}
+
+// logCall writes the access log line of a call.
+func logCall(ctx context.Context, info *utils.RequestInfo, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("requestId", info.Id),
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Float64("durationMs", float64(time.Since(start).Microseconds())/1000),
+		slog.String("clientIp", clientIP(ctx)),
+		slog.String("principal", info.Principal),
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			attrs = append(attrs, slog.String("userAgent", values[0]))
+		}
+	}
+
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	slog.LogAttrs(context.Background(), level, "grpc call", attrs...)
+}
+
+func unaryLoggingInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx, requestInfo := requestContext(ctx, func(md metadata.MD) error {
+		return grpc.SetHeader(ctx, md)
+	})
+
+	resp, err := handler(ctx, req)
+
+	logCall(ctx, requestInfo, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamLoggingInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx, requestInfo := requestContext(stream.Context(), stream.SetHeader)
+
+	err := handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+
+	logCall(ctx, requestInfo, info.FullMethod, start, err)
+	return err
+}
+
+// traceContext continues the trace of the traceparent metadata, or starts a new one, and sends the
+// call's span back in the traceparent header, as TraceMiddleware does.
+func traceContext(ctx context.Context, setHeader func(metadata.MD) error) context.Context {
+	tc := tracing.NewTraceContext()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(tracing.TraceParentHeader); len(values) > 0 {
+			if parent, ok := tracing.ParseTraceParent(values[0]); ok {
+				tc = parent.NewChild()
+			}
+		}
+	}
+
+	_ = setHeader(metadata.Pairs(tracing.TraceParentHeader, tc.TraceParent()))
+	return tracing.WithTraceContext(ctx, tc)
+}
+
+func unaryTraceInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx = traceContext(ctx, func(md metadata.MD) error {
+		return grpc.SetHeader(ctx, md)
+	})
+	return handler(ctx, req)
+}
+
+func streamTraceInterceptor(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := traceContext(stream.Context(), stream.SetHeader)
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: ctx})
+}
+
+// rateLimiter shares the HTTP API's Redis counters, so a client has one budget across both APIs.
+type rateLimiter struct {
+	limiter *redis_rate.Limiter
+	limit   redis_rate.Limit
+}
+
+func (rl *rateLimiter) allow(ctx context.Context) error {
+	res, err := rl.limiter.Allow(ctx, "rate_limit:"+clientIP(ctx), rl.limit)
+	if err != nil {
+		return err
+	}
+	if res.Allowed == 0 {
+		seconds := int(res.RetryAfter / time.Second)
+		_ = grpc.SetTrailer(ctx, metadata.Pairs("ratelimit-retryafter", strconv.Itoa(seconds)))
+		return http_error.TooManyRequests()
+	}
+	return nil
+}
+
+func (rl *rateLimiter) unary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := rl.allow(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream counts each received message as a request, a WriteStream batch costs as much as a POST.
+func (rl *rateLimiter) stream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &wrappedStream{ServerStream: stream, ctx: stream.Context(), beforeRecv: rl.allow})
+}
+
+// wrappedStream replaces the context of a stream and optionally runs a check before each message is read.
+type wrappedStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	beforeRecv func(ctx context.Context) error
+}
+
+func (w *wrappedStream) Context() context.Context {
+	return w.ctx
+}
+
+func (w *wrappedStream) RecvMsg(m any) error {
+	if w.beforeRecv != nil {
+		if err := w.beforeRecv(w.ctx); err != nil {
+			return err
+		}
+	}
+	return w.ServerStream.RecvMsg(m)
+}
+
+// clientIP reads the forwarding metadata set by proxies, then falls back to the peer address, as
+// utils.GetClientIP does for HTTP requests.
+func clientIP(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, key := range []string{"x-forwarded-for", "x-real-ip"} {
+			if values := md.Get(key); len(values) > 0 && values[0] != "" {
+				return values[0]
+			}
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if ip, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return ip
+		}
+		return p.Addr.String()
+	}
+	return ""
+}
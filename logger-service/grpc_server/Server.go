@@ -0,0 +1,102 @@
+// Package grpc_server runs the gRPC LoggerService next to the HTTP API, with interceptors applying the
+// same rate limiting, tracing and logging as the HTTP middlewares.
+package grpc_server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"net"
+	"os"
+	loggerv1 "tikube-backend/proto/logger/v1"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// How long Close waits for the running calls before cancelling them, Tail calls only end when cancelled.
+const shutdownTimeout = 5 * time.Second
+
+type Config struct {
+	Addr string
+	// TLS is used when set, plaintext otherwise.
+	TLS *tls.Config
+}
+
+// ConfigFromEnv reads GRPC_ADDR, usually :9090, GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE. The server is
+// disabled when GRPC_ADDR is not set.
+func ConfigFromEnv() (Config, error) {
+	config := Config{Addr: os.Getenv("GRPC_ADDR")}
+
+	certFile, keyFile := os.Getenv("GRPC_TLS_CERT_FILE"), os.Getenv("GRPC_TLS_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return config, fmt.Errorf("loading gRPC TLS certificate: %w", err)
+		}
+		config.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	}
+	return config, nil
+}
+
+type Server struct {
+	config Config
+	server *grpc.Server
+}
+
+// NewServer serves loggerService. Every call, and every message received on a stream, counts against
+// the same per client limit as the HTTP requests.
+func NewServer(config Config, loggerService loggerv1.LoggerServiceServer, limiter *redis_rate.Limiter, limit redis_rate.Limit) *Server {
+	rateLimit := &rateLimiter{limiter: limiter, limit: limit}
+	options := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(int(utils.MaxPayloadSize)),
+		grpc.ChainUnaryInterceptor(unaryLoggingInterceptor, unaryErrorInterceptor, unaryTraceInterceptor, rateLimit.unary),
+		grpc.ChainStreamInterceptor(streamLoggingInterceptor, streamErrorInterceptor, streamTraceInterceptor, rateLimit.stream),
+	}
+	if config.TLS != nil {
+		options = append(options, grpc.Creds(credentials.NewTLS(config.TLS)))
+	}
+
+	server := grpc.NewServer(options...)
+	loggerv1.RegisterLoggerServiceServer(server, loggerService)
+	return &Server{config: config, server: server}
+}
+
+func (s *Server) Name() string {
+	return "grpc"
+}
+
+func (s *Server) Enabled() bool {
+	return s.config.Addr != ""
+}
+
+// ListenAndServe blocks until Close is called, after which it returns utils.ErrListenerClosed.
+func (s *Server) ListenAndServe() error {
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return err
+	}
+	// Serve returns nil once Stop or GracefulStop was called, or ErrServerStopped when it was called before
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return utils.ErrListenerClosed
+}
+
+// Close lets the running calls finish for a few seconds, then cancels them.
+func (s *Server) Close() error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		s.server.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/utils"
+)
+
+type AlertHandler struct {
+	alertService *service.AlertService
+}
+
+func NewAlertController(alertService *service.AlertService) *AlertHandler {
+	return &AlertHandler{alertService: alertService}
+}
+
+func (ac *AlertHandler) GetRules(w http.ResponseWriter, r *http.Request) error {
+	rules, err := ac.alertService.GetRules(r.Context())
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, rules)
+}
+
+func (ac *AlertHandler) GetRule(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	rule, err := ac.alertService.GetRule(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, rule)
+}
+
+func (ac *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) error {
+	payload := r.Context().Value(utils.PayloadKey{}).(model.AlertRuleSchema)
+
+	rule, err := ac.alertService.CreateRule(r.Context(), payload.ToAlertRule())
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusCreated, rule)
+}
+
+func (ac *AlertHandler) UpdateRule(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	payload := r.Context().Value(utils.PayloadKey{}).(model.AlertRuleSchema)
+
+	rule, err := ac.alertService.UpdateRule(r.Context(), id, payload.ToAlertRule())
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, rule)
+}
+
+func (ac *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	if err := ac.alertService.DeleteRule(r.Context(), id); err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusNoContent, nil)
+}
+
+func (ac *AlertHandler) GetHistory(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	events, err := ac.alertService.GetHistory(r.Context(), id, parsePagination(r.URL.Query()))
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, events)
+}
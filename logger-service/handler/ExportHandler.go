@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"tikube-backend/logger-service/export"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/http_error"
+	"time"
+)
+
+type ExportHandler struct {
+	exportService *service.ExportService
+}
+
+func NewExportController(exportService *service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// ExportLogs streams every log matching the filter as a file download. The response uses chunked
+// encoding and is gzip encoded when the client accepts it.
+func (ec *ExportHandler) ExportLogs(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+
+	format, err := export.ParseFormat(query.Get("format"))
+	if err != nil {
+		return http_error.BadRequest("format must be one of csv, ndjson, parquet")
+	}
+
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		return err
+	}
+
+	limit := ec.exportService.MaxRows()
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 {
+			return http_error.BadRequest("Invalid limit")
+		}
+		if parsedLimit > limit {
+			return http_error.BadRequest(fmt.Sprintf("limit must not exceed %d", limit))
+		}
+		limit = parsedLimit
+	}
+
+	filename := fmt.Sprintf("logs-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format.Extension())
+	h := w.Header()
+	h.Set("Content-Type", format.ContentType())
+	h.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	counter := &countingWriter{w: w}
+	rc := http.NewResponseController(w)
+	var out io.Writer = counter
+	flush := rc.Flush
+
+	var gz *gzip.Writer
+	h.Add("Vary", "Accept-Encoding")
+	if acceptsGzip(r.Header.Values("Accept-Encoding")) {
+		h.Set("Content-Encoding", "gzip")
+		gz = gzip.NewWriter(counter)
+		out = gz
+		flush = func() error {
+			if err := gz.Flush(); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+	}
+
+	_, err = ec.exportService.Export(r.Context(), filter, format, limit, out, flush)
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		if counter.written == 0 {
+			// Nothing was sent yet, so a regular error response is still possible
+			h.Del("Content-Encoding")
+			h.Del("Content-Disposition")
+			return http_error.InternalServerError().WithCause(fmt.Errorf("exporting logs: %w", err))
+		}
+		// The download is already under way, abort it so the client sees a truncated transfer
+		panic(http.ErrAbortHandler)
+	}
+
+	return nil
+}
+
+// countingWriter tracks whether any bytes reached the client.
+type countingWriter struct {
+	w       http.ResponseWriter
+	written int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
+
+// acceptsGzip reads the Accept-Encoding headers. gzip is used when it, or the * wildcard if gzip
+// is not listed, has a q-value above 0; "gzip;q=0" refuses it.
+func acceptsGzip(headers []string) bool {
+	gzipQ, wildcardQ := -1.0, -1.0
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			coding, params, _ := strings.Cut(element, ";")
+			q := 1.0
+			for _, param := range strings.Split(params, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+					continue
+				}
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					parsed = 0
+				}
+				q = parsed
+			}
+
+			switch strings.ToLower(strings.TrimSpace(coding)) {
+			case "gzip", "x-gzip":
+				gzipQ = math.Max(gzipQ, q)
+			case "*":
+				wildcardQ = math.Max(wildcardQ, q)
+			}
+		}
+	}
+
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return wildcardQ > 0
+}
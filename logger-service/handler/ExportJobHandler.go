@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"tikube-backend/logger-service/export"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/utils"
+)
+
+type ExportJobHandler struct {
+	exportJobService *service.ExportJobService
+}
+
+func NewExportJobController(exportJobService *service.ExportJobService) *ExportJobHandler {
+	return &ExportJobHandler{exportJobService: exportJobService}
+}
+
+// CreateJob queues an export. The response is the job itself, which the client polls until it completes.
+func (ec *ExportJobHandler) CreateJob(w http.ResponseWriter, r *http.Request) error {
+	payload := r.Context().Value(utils.PayloadKey{}).(model.ExportJobSchema)
+
+	job, err := ec.exportJobService.CreateJob(r.Context(), export.Format(payload.Format), payload.Filter, payload.Limit)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", "/logger/exports/"+job.Id)
+	return utils.JSONResponse(w, http.StatusAccepted, job)
+}
+
+func (ec *ExportJobHandler) GetJob(w http.ResponseWriter, r *http.Request) error {
+	job, err := ec.exportJobService.GetJob(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, job)
+}
+
+// DownloadJob serves the file of a completed job. Range requests are supported so large
+// downloads can be resumed.
+func (ec *ExportJobHandler) DownloadJob(w http.ResponseWriter, r *http.Request) error {
+	job, file, err := ec.exportJobService.OpenDownload(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	format := export.Format(job.Format)
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="logs-%s.%s"`, job.Id, format.Extension()))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	modified := job.UpdatedAt
+	if job.CompletedAt != nil {
+		modified = *job.CompletedAt
+	}
+	http.ServeContent(w, r, "", modified, file)
+	return nil
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/service"
+	loggerv1 "tikube-backend/proto/logger/v1"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// Largest backlog a tail can ask for, over gRPC or HTTP.
+const maxTailBacklog = 1000
+
+var (
+	levelsFromProto = map[loggerv1.LogLevel]utils.LogLevel{
+		loggerv1.LogLevel_LOG_LEVEL_TRACE: utils.TRACE,
+		loggerv1.LogLevel_LOG_LEVEL_DEBUG: utils.DEBUG,
+		loggerv1.LogLevel_LOG_LEVEL_INFO:  utils.INFO,
+		loggerv1.LogLevel_LOG_LEVEL_WARN:  utils.WARN,
+		loggerv1.LogLevel_LOG_LEVEL_ERROR: utils.ERROR,
+		loggerv1.LogLevel_LOG_LEVEL_FATAL: utils.FATAL,
+	}
+	levelsToProto = map[utils.LogLevel]loggerv1.LogLevel{}
+)
+
+func init() {
+	for protoLevel, level := range levelsFromProto {
+		levelsToProto[level] = protoLevel
+	}
+}
+
+// GrpcHandler implements the gRPC LoggerService. Requests are turned into the query parameters of the
+// HTTP API so that they are validated the same way, and errors are returned as http_error errors that
+// the server's interceptors translate into gRPC status codes.
+type GrpcHandler struct {
+	loggerv1.UnimplementedLoggerServiceServer
+	loggerService *service.LoggerService
+}
+
+func NewGrpcController(loggerService *service.LoggerService) *GrpcHandler {
+	return &GrpcHandler{loggerService: loggerService}
+}
+
+// Write queues the logs like IngestLogs, the call only fails when every log is rejected.
+func (gc *GrpcHandler) Write(_ context.Context, request *loggerv1.WriteRequest) (*loggerv1.WriteResponse, error) {
+	logs, err := fromLogEntries(request.GetLogs())
+	if err != nil {
+		return nil, err
+	}
+
+	rejected, reason := gc.loggerService.PublishLogs(logs)
+	if rejected == len(logs) {
+		return nil, rejectedBatchError(reason)
+	}
+	return &loggerv1.WriteResponse{Accepted: int64(len(logs) - rejected), Rejected: int64(rejected), Error: rejectionReason(reason)}, nil
+}
+
+// WriteStream queues each batch as it is received. Batches are not all-or-nothing here: the stream
+// only fails at the end when no log at all was accepted, or right away on a batch that is empty or
+// too large.
+func (gc *GrpcHandler) WriteStream(stream loggerv1.LoggerService_WriteStreamServer) error {
+	response := &loggerv1.WriteResponse{}
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		logs, err := fromLogEntries(request.GetLogs())
+		if err != nil {
+			return err
+		}
+		rejected, reason := gc.loggerService.PublishLogs(logs)
+		response.Accepted += int64(len(logs) - rejected)
+		response.Rejected += int64(rejected)
+		if response.Error == "" {
+			response.Error = rejectionReason(reason)
+		}
+	}
+
+	if response.Accepted == 0 && response.Rejected > 0 {
+		return http_error.BadRequest("Every log was rejected: " + response.Error)
+	}
+	return stream.SendAndClose(response)
+}
+
+// Query is GetLogs, newest first unless a sort order is given.
+func (gc *GrpcHandler) Query(ctx context.Context, request *loggerv1.QueryRequest) (*loggerv1.QueryResponse, error) {
+	query, err := filterQuery(request.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := "", ""
+	if request.GetFrom() != nil {
+		if from, err = formatTimestamp(request.GetFrom()); err != nil {
+			return nil, err
+		}
+	}
+	if request.GetTo() != nil {
+		if to, err = formatTimestamp(request.GetTo()); err != nil {
+			return nil, err
+		}
+	}
+	if from != "" || to != "" {
+		query.Set("date_filter", from+","+to)
+	}
+
+	query.Set("sort", "createdAt:desc,id:desc")
+	if request.GetSort() != "" {
+		query.Set("sort", request.GetSort())
+	}
+	if request.GetLimit() > 0 {
+		query.Set("limit", strconv.Itoa(int(request.GetLimit())))
+	}
+	query.Set("offset", strconv.Itoa(int(request.GetPage())))
+
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		return nil, err
+	}
+	view, err := parseLogView(query)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := gc.loggerService.GetLogs(ctx, filter, parsePagination(query), view)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &loggerv1.QueryResponse{}
+	if logs != nil {
+		if response.Logs, err = toProtoLogs(logs.Data); err != nil {
+			return nil, err
+		}
+		response.Total = int64(logs.Total)
+	}
+	return response, nil
+}
+
+// Tail streams the logs as they are stored until the client cancels the call.
+func (gc *GrpcHandler) Tail(request *loggerv1.TailRequest, stream loggerv1.LoggerService_TailServer) error {
+	if request.GetBacklog() < 0 || request.GetBacklog() > maxTailBacklog {
+		return http_error.BadRequest(fmt.Sprintf("backlog must be between 0 and %d", maxTailBacklog))
+	}
+	query, err := filterQuery(request.GetFilter())
+	if err != nil {
+		return err
+	}
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		return err
+	}
+
+	err = gc.loggerService.TailLogs(stream.Context(), filter, int(request.GetBacklog()), func(logs []utils.Log) error {
+		protoLogs, err := toProtoLogs(logs)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&loggerv1.TailResponse{Logs: protoLogs})
+	})
+	if stream.Context().Err() != nil {
+		// The client went away, there is no one left to report to
+		return nil
+	}
+	return err
+}
+
+// filterQuery turns a filter into the level_filter, min_level, source_filter and q parameters.
+func filterQuery(filter *loggerv1.LogFilter) (url.Values, error) {
+	query := url.Values{}
+
+	if len(filter.GetLevels()) > 0 {
+		levels := make([]string, 0, len(filter.GetLevels()))
+		for _, protoLevel := range filter.GetLevels() {
+			level, ok := levelsFromProto[protoLevel]
+			if !ok {
+				return nil, http_error.BadRequest("levels: unknown log level " + protoLevel.String())
+			}
+			levels = append(levels, string(level))
+		}
+		query.Set("level_filter", strings.Join(levels, ","))
+	}
+	if filter.GetMinLevel() != loggerv1.LogLevel_LOG_LEVEL_UNSPECIFIED {
+		level, ok := levelsFromProto[filter.GetMinLevel()]
+		if !ok {
+			return nil, http_error.BadRequest("min_level: unknown log level " + filter.GetMinLevel().String())
+		}
+		query.Set("min_level", string(level))
+	}
+	if len(filter.GetSources()) > 0 {
+		query.Set("source_filter", strings.Join(filter.GetSources(), ","))
+	}
+	if filter.GetQuery() != "" {
+		query.Set("q", filter.GetQuery())
+	}
+	return query, nil
+}
+
+func formatTimestamp(timestamp *timestamppb.Timestamp) (string, error) {
+	if err := timestamp.CheckValid(); err != nil {
+		return "", http_error.BadRequest("Invalid timestamp: " + err.Error())
+	}
+	return timestamp.AsTime().Format(time.RFC3339Nano), nil
+}
+
+func fromLogEntries(entries []*loggerv1.LogEntry) ([]model.CreateLogSchema, error) {
+	if len(entries) == 0 {
+		return nil, http_error.BadRequest("At least one log is required")
+	}
+	if len(entries) > maxIngestBatch {
+		return nil, http_error.PayloadTooLarge(fmt.Sprintf("At most %d logs are accepted per request", maxIngestBatch))
+	}
+
+	logs := make([]model.CreateLogSchema, 0, len(entries))
+	for _, entry := range entries {
+		// An unknown level is left empty so that validation rejects the log like any other invalid one
+		log := model.CreateLogSchema{
+			LogLevel: levelsFromProto[entry.GetLevel()],
+			Source:   entry.GetSource(),
+			Message:  entry.GetMessage(),
+			TraceId:  entry.GetTraceId(),
+			SpanId:   entry.GetSpanId(),
+		}
+		if attributes := entry.GetAttributes().AsMap(); len(attributes) > 0 {
+			log.Attributes = attributes
+		}
+		// Out of range timestamps are ignored, the log gets the time of ingestion as when it has none
+		if entry.GetTimestamp() != nil && entry.GetTimestamp().IsValid() {
+			timestamp := entry.GetTimestamp().AsTime()
+			log.Timestamp = &timestamp
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
+func toProtoLogs(logs []utils.Log) ([]*loggerv1.Log, error) {
+	protoLogs := make([]*loggerv1.Log, 0, len(logs))
+	for _, log := range logs {
+		protoLog := &loggerv1.Log{
+			Id:          log.Id,
+			Level:       levelsToProto[log.LogLevel],
+			Source:      log.Source,
+			Message:     log.Message,
+			Fingerprint: log.Fingerprint,
+			TraceId:     log.TraceId,
+			SpanId:      log.SpanId,
+			CreatedAt:   timestamppb.New(log.CreatedAt),
+		}
+		if len(log.Attributes) > 0 {
+			attributes, err := structpb.NewStruct(log.Attributes)
+			if err != nil {
+				return nil, http_error.InternalServerError().WithCause(fmt.Errorf("converting attributes of log %d: %w", log.Id, err))
+			}
+			protoLog.Attributes = attributes
+		}
+		protoLogs = append(protoLogs, protoLog)
+	}
+	return protoLogs, nil
+}
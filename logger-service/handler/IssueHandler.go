@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/utils"
+)
+
+type IssueHandler struct {
+	issueService *service.IssueService
+}
+
+func NewIssueController(issueService *service.IssueService) *IssueHandler {
+	return &IssueHandler{issueService: issueService}
+}
+
+func (ic *IssueHandler) GetIssues(w http.ResponseWriter, r *http.Request) error {
+	var filter utils.IssueFilter
+
+	query := r.URL.Query()
+	pagination := parsePagination(query)
+
+	if statusStr := query.Get("status_filter"); statusStr != "" {
+		filter.StatusFilter = strings.Split(statusStr, ",")
+	}
+	filter.Source = query.Get("source")
+
+	issues, err := ic.issueService.GetIssues(r.Context(), filter, pagination)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, issues)
+}
+
+func (ic *IssueHandler) GetIssue(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	issue, err := ic.issueService.GetIssue(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, issue)
+}
+
+func (ic *IssueHandler) GetIssueLogs(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	logs, err := ic.issueService.GetIssueLogs(r.Context(), id, parsePagination(r.URL.Query()))
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, logs)
+}
+
+func (ic *IssueHandler) UpdateIssue(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	payload := r.Context().Value(utils.PayloadKey{}).(model.UpdateIssueSchema)
+
+	issue, err := ic.issueService.UpdateIssueStatus(r.Context(), id, utils.IssueStatus(strings.ToLower(string(payload.Status))))
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, issue)
+}
@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/IBM/sarama"
+	"github.com/gorilla/mux"
 	"net/http"
 	"strconv"
 	"strings"
+	"tikube-backend/logger-service/model"
 	"tikube-backend/logger-service/service"
 	"tikube-backend/shared/http_error"
 	"tikube-backend/shared/utils"
+	"time"
 )
 
+// Largest number of logs accepted by a single IngestLogs request.
+const maxIngestBatch = 5000
+
+// Logs sent first by TailLogs when the request has no backlog parameter.
+const defaultTailBacklog = 10
+
 type LoggerHandler struct {
 	loggerService *service.LoggerService
 	producer      sarama.AsyncProducer
@@ -19,51 +31,290 @@ func NewLoggerController(loggerService *service.LoggerService, producer sarama.A
 	return &LoggerHandler{loggerService: loggerService}
 }
 
+// IngestLogs accepts a JSON array of logs, optionally gzip compressed, and queues the valid ones
+// for ingestion. The request only fails when every log is rejected.
+func (lc *LoggerHandler) IngestLogs(w http.ResponseWriter, r *http.Request) error {
+	body, err := readEncodedBody(w, r)
+	if err != nil {
+		return err
+	}
+
+	var logs []model.CreateLogSchema
+	if err := json.Unmarshal(body, &logs); err != nil {
+		return http_error.BadRequest("Invalid JSON payload, expected an array of logs")
+	}
+	if len(logs) == 0 {
+		return http_error.BadRequest("At least one log is required")
+	}
+	if len(logs) > maxIngestBatch {
+		return http_error.PayloadTooLarge(fmt.Sprintf("At most %d logs are accepted per request", maxIngestBatch))
+	}
+
+	rejected, reason := lc.loggerService.PublishLogs(logs)
+	if rejected == len(logs) {
+		return rejectedBatchError(reason)
+	}
+	return utils.JSONResponse(w, http.StatusAccepted, utils.IngestResult{Accepted: len(logs) - rejected, Rejected: rejected, Error: rejectionReason(reason)})
+}
+
+// rejectedBatchError is returned when every log of a batch was rejected. It lists the invalid fields
+// of the first log.
+func rejectedBatchError(reason error) error {
+	var httpErr *http_error.HTTPError
+	if errors.As(reason, &httpErr) {
+		return reason
+	}
+	var fieldErrors http_error.FieldErrors
+	errors.As(reason, &fieldErrors)
+	return http_error.ValidationFailed("Every log was rejected: "+rejectionReason(reason), fieldErrors)
+}
+
+// rejectionReason is the reason PublishLogs gave for the first rejected log, as sent to clients.
+func rejectionReason(reason error) string {
+	if reason == nil {
+		return ""
+	}
+	return reason.Error()
+}
+
 func (lc *LoggerHandler) GetLogs(w http.ResponseWriter, r *http.Request) error {
-	const defaultLimit int = 10
-	const defaultOffset int = 0
-	var filter utils.LogFilter
+	query := r.URL.Query()
+	pagination := parsePagination(query)
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		return err
+	}
+	view, err := parseLogView(query)
+	if err != nil {
+		return err
+	}
+
+	logs, err := lc.loggerService.GetLogs(r.Context(), filter, pagination, view)
+	if err != nil {
+		return err
+	}
+	//Return an empty json array instead of nil
+	if logs == nil {
+		logs = &utils.PaginationResult[utils.Log]{Data: []utils.Log{}, Total: 0}
+	}
+	if len(view.Fields) > 0 {
+		return utils.JSONResponse(w, http.StatusOK, projectLogs(logs, view.Fields))
+	}
+	return utils.JSONResponse(w, http.StatusOK, logs)
+}
+
+func (lc *LoggerHandler) GetLog(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	dLog, err := lc.loggerService.GetLog(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, dLog)
+}
+
+const (
+	defaultContextSize = 20
+	maxContextSize     = 200
+)
+
+// GetLogContext returns a log with its neighbors. before and after set how many logs to include on
+// each side and same_source limits them to the source of the log.
+func (lc *LoggerHandler) GetLogContext(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
 
 	query := r.URL.Query()
+	before, err := parseContextSize(query.Get("before"), "before")
+	if err != nil {
+		return err
+	}
+	after, err := parseContextSize(query.Get("after"), "after")
+	if err != nil {
+		return err
+	}
 
-	limit := defaultLimit
-	offset := defaultOffset
+	sameSource := false
+	if sameSourceStr := query.Get("same_source"); sameSourceStr != "" {
+		if sameSource, err = strconv.ParseBool(sameSourceStr); err != nil {
+			return http_error.BadRequest("same_source must be true or false")
+		}
+	}
 
-	if limitStr := query.Get("limit"); limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit >= 0 {
-			limit = parsedLimit
+	logContext, err := lc.loggerService.GetLogContext(r.Context(), id, before, after, sameSource)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, logContext)
+}
+
+// TailLogs streams the logs matching the filters as newline delimited JSON: the last backlog logs,
+// then the new ones as they are stored, until the client disconnects. Unlike GetLogs it is not cached.
+func (lc *LoggerHandler) TailLogs(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	backlog := defaultTailBacklog
+	if backlogStr := query.Get("backlog"); backlogStr != "" {
+		parsed, err := strconv.Atoi(backlogStr)
+		if err != nil || parsed < 0 || parsed > maxTailBacklog {
+			return http_error.BadRequest(fmt.Sprintf("backlog must be between 0 and %d", maxTailBacklog))
 		}
+		backlog = parsed
+	}
+
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		return err
+	}
+
+	// A tail has no end, the server wide write timeout would cut it off
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	// The headers are sent right away, clients wait for them before reading a stream that may stay quiet
+	h := w.Header()
+	h.Set("Content-Type", "application/x-ndjson")
+	h.Set("Cache-Control", "no-store")
+	h.Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return nil
 	}
 
-	if offsetStr := query.Get("offset"); offsetStr != "" {
-		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
-			offset = parsedOffset
+	encoder := json.NewEncoder(w)
+	err = lc.loggerService.TailLogs(r.Context(), filter, backlog, func(logs []utils.Log) error {
+		for _, log := range logs {
+			if err := encoder.Encode(log); err != nil {
+				return err
+			}
 		}
+		return rc.Flush()
+	})
+	if err != nil && r.Context().Err() == nil {
+		// The stream is under way, abort it so the client sees a truncated response and reconnects
+		panic(http.ErrAbortHandler)
+	}
+	return nil
+}
+
+func parseContextSize(value string, name string) (int, error) {
+	if value == "" {
+		return defaultContextSize, nil
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 || size > maxContextSize {
+		return 0, http_error.BadRequest(fmt.Sprintf("%s must be between 0 and %d", name, maxContextSize))
+	}
+	return size, nil
+}
+
+// GetSources returns the source hierarchy with log counts per node. It accepts the same filters as GetLogs.
+func (lc *LoggerHandler) GetSources(w http.ResponseWriter, r *http.Request) error {
+	filter, err := parseLogFilter(r.URL.Query())
+	if err != nil {
+		return err
 	}
-	offset = offset * limit
 
-	if filterStr := query.Get("level_filter"); filterStr != "" {
-		filter.LevelFilter = strings.Split(filterStr, ",")
+	tree, err := lc.loggerService.GetSources(r.Context(), filter)
+	if err != nil {
+		return err
 	}
+	return utils.JSONResponse(w, http.StatusOK, tree)
+}
 
-	//Date filter
-	if dateFilterStr := query.Get("date_filter"); dateFilterStr != "" {
-		dateFilterSlice := strings.Split(dateFilterStr, ",")
-		if len(dateFilterSlice) == 2 {
-			// Optionally add more validation for date format
-			filter.DateFilter = &utils.DateFilterRange{From: dateFilterSlice[0], To: dateFilterSlice[1]}
-		} else {
-			filter.DateFilter = nil
+// GetTrace returns every log of a distributed trace across services.
+func (lc *LoggerHandler) GetTrace(w http.ResponseWriter, r *http.Request) error {
+	trace, err := lc.loggerService.GetTrace(r.Context(), mux.Vars(r)["traceId"])
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, trace)
+}
+
+// projectLogs keeps only the requested fields so that unselected ones are left out of the response
+// instead of being sent as zero values.
+func projectLogs(logs *utils.PaginationResult[utils.Log], fields []string) utils.PaginationResult[map[string]any] {
+	projected := utils.PaginationResult[map[string]any]{Data: make([]map[string]any, len(logs.Data)), Total: logs.Total}
+	for i, log := range logs.Data {
+		row := make(map[string]any, len(fields))
+		for _, field := range fields {
+			switch field {
+			case "id":
+				row[field] = log.Id
+			case "logLevel":
+				row[field] = log.LogLevel
+			case "source":
+				row[field] = log.Source
+			case "message":
+				row[field] = log.Message
+			case "fingerprint":
+				row[field] = log.Fingerprint
+			case "traceId":
+				row[field] = log.TraceId
+			case "spanId":
+				row[field] = log.SpanId
+			case "attributes":
+				row[field] = log.Attributes
+			case "createdAt":
+				row[field] = log.CreatedAt
+			case "updatedAt":
+				row[field] = log.UpdatedAt
+			}
 		}
+		projected.Data[i] = row
 	}
+	return projected
+}
 
-	logs, err := lc.loggerService.GetLogs(r.Context(), filter, utils.Pagination{Limit: limit, Offset: offset})
+// GetHistogram returns log counts per time bucket, split by level or source.
+func (lc *LoggerHandler) GetHistogram(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	filter, err := parseLogFilter(query)
 	if err != nil {
-		return http_error.InternalServerError()
+		return err
 	}
-	//Return an empty json array instead of nil
-	if logs == nil {
-		logs = &utils.PaginationResult[utils.Log]{Data: []utils.Log{}, Total: 0}
+
+	groupBy := query.Get("group_by")
+	if groupBy == "" {
+		groupBy = "level"
 	}
-	return utils.JSONResponse(w, http.StatusOK, logs)
+
+	histogram, err := lc.loggerService.GetHistogram(r.Context(), filter, query.Get("interval"), groupBy)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, histogram)
+}
+
+// GetFacets returns the page of logs for the filter along with level, source and attribute counts.
+func (lc *LoggerHandler) GetFacets(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+	pagination := parsePagination(query)
+	filter, err := parseLogFilter(query)
+	if err != nil {
+		return err
+	}
+
+	var attributeKeys []string
+	if attributesStr := query.Get("facet_attributes"); attributesStr != "" {
+		attributeKeys = strings.Split(attributesStr, ",")
+	}
+	approximate := false
+	if approximateStr := query.Get("approximate"); approximateStr != "" {
+		if approximate, err = strconv.ParseBool(approximateStr); err != nil {
+			return http_error.BadRequest("approximate must be true or false")
+		}
+	}
+
+	facets, err := lc.loggerService.GetFacets(r.Context(), filter, pagination, attributeKeys, approximate)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, facets)
 }
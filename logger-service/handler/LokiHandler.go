@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"mime"
+	"net/http"
+	"strconv"
+	"tikube-backend/logger-service/loki"
+	"tikube-backend/logger-service/lql"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+const (
+	defaultLokiLimit = 100
+	// Same as Loki's default max_entries_limit_per_query.
+	maxLokiLimit = 5000
+	// Range queried when the request has no start.
+	defaultLokiSince = time.Hour
+)
+
+type LokiHandler struct {
+	loggerService *service.LoggerService
+}
+
+func NewLokiController(loggerService *service.LoggerService) *LokiHandler {
+	return &LokiHandler{loggerService: loggerService}
+}
+
+// Push accepts the snappy compressed protobuf sent by promtail and the JSON format. Entries that
+// fail validation are dropped and reported with a 400 once the valid ones are published, which
+// promtail does not retry.
+func (lc *LokiHandler) Push(w http.ResponseWriter, r *http.Request) error {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		// promtail always sends protobuf
+		contentType = protobufContentType
+	}
+	if contentType != protobufContentType && contentType != jsonContentType {
+		return http_error.UnsupportedMediaType("Content-Type must be application/x-protobuf or application/json")
+	}
+
+	body, err := readEncodedBody(w, r)
+	if err != nil {
+		return err
+	}
+
+	var streams []loki.PushStream
+	if contentType == protobufContentType {
+		streams, err = loki.DecodeProtobuf(body, utils.MaxPayloadSize)
+	} else {
+		streams, err = loki.DecodeJSON(body)
+	}
+	if errors.Is(err, loki.ErrTooLarge) {
+		return http_error.PayloadTooLarge()
+	}
+	if err != nil {
+		return http_error.BadRequest("Invalid push request: " + err.Error())
+	}
+
+	logs := loki.ToLogs(streams)
+	if rejected, reason := lc.loggerService.PublishLogs(logs); rejected > 0 {
+		return http_error.BadRequest(fmt.Sprintf("%d of %d entries were rejected: %s", rejected, len(logs), rejectionReason(reason)))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// QueryRange runs a LogQL log query. Only stream selectors and line filters are supported, see loki.Query.
+func (lc *LokiHandler) QueryRange(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+
+	query, err := loki.ParseQuery(params.Get("query"))
+	if err != nil {
+		return http_error.BadRequest(err.Error())
+	}
+	lqlQuery, err := query.ToLQL()
+	if err != nil {
+		return http_error.BadRequest(err.Error())
+	}
+	if lqlQuery != "" {
+		if _, _, err := lql.CompileQuery(lqlQuery); err != nil {
+			return http_error.BadRequest(err.Error())
+		}
+	}
+
+	dateFilter, err := parseLokiRange(params.Get("start"), params.Get("end"), params.Get("since"))
+	if err != nil {
+		return err
+	}
+
+	limit := defaultLokiLimit
+	if limitStr := params.Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return http_error.BadRequest("limit must be a positive number")
+		}
+		if limit > maxLokiLimit {
+			return http_error.BadRequest(fmt.Sprintf("max entries limit per query exceeded, limit > %d", maxLokiLimit))
+		}
+	}
+
+	var desc bool
+	switch params.Get("direction") {
+	case "", "backward":
+		desc = true
+	case "forward":
+	default:
+		return http_error.BadRequest("direction must be forward or backward")
+	}
+
+	filter := utils.LogFilter{DateFilter: dateFilter, Query: lqlQuery}
+	view := utils.LogView{Sort: []utils.SortField{{Field: "createdAt", Desc: desc}}}
+	logs, err := lc.loggerService.GetLogs(r.Context(), filter, utils.Pagination{Limit: limit}, view)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, loki.NewStreamsResponse(logs.Data))
+}
+
+// Labels lists the labels of the streams returned by QueryRange.
+func (lc *LokiHandler) Labels(w http.ResponseWriter, r *http.Request) error {
+	return utils.JSONResponse(w, http.StatusOK, loki.Response{Status: "success", Data: loki.LabelNames})
+}
+
+// LabelValues lists the values of a label over the requested range. Sources come from the source
+// hierarchy so parents such as PAYMENTS are listed next to PAYMENTS:API.
+func (lc *LokiHandler) LabelValues(w http.ResponseWriter, r *http.Request) error {
+	values := []string{}
+
+	switch mux.Vars(r)["name"] {
+	case "level":
+		for _, level := range utils.LogLevels {
+			values = append(values, string(level))
+		}
+	case "source":
+		params := r.URL.Query()
+		dateFilter, err := parseLokiRange(params.Get("start"), params.Get("end"), params.Get("since"))
+		if err != nil {
+			return err
+		}
+		tree, err := lc.loggerService.GetSources(r.Context(), utils.LogFilter{DateFilter: dateFilter})
+		if err != nil {
+			return err
+		}
+		values = appendSourcePaths(values, tree)
+	}
+
+	return utils.JSONResponse(w, http.StatusOK, loki.Response{Status: "success", Data: values})
+}
+
+func appendSourcePaths(paths []string, node *utils.SourceNode) []string {
+	for _, child := range node.Children {
+		paths = append(paths, child.Path)
+		paths = appendSourcePaths(paths, child)
+	}
+	return paths
+}
+
+// parseLokiRange reads start and end, defaulting to the last hour. since sets start relative to end.
+func parseLokiRange(startStr, endStr, sinceStr string) (*utils.DateFilterRange, error) {
+	end := time.Now().UTC()
+	if endStr != "" {
+		t, err := loki.ParseTime(endStr)
+		if err != nil {
+			return nil, http_error.BadRequest("end: " + err.Error())
+		}
+		end = t
+	}
+
+	since := defaultLokiSince
+	if sinceStr != "" {
+		d, err := time.ParseDuration(sinceStr)
+		if err != nil || d <= 0 {
+			return nil, http_error.BadRequest("since must be a positive duration such as 1h")
+		}
+		since = d
+	}
+
+	start := end.Add(-since)
+	if startStr != "" {
+		t, err := loki.ParseTime(startStr)
+		if err != nil {
+			return nil, http_error.BadRequest("start: " + err.Error())
+		}
+		start = t
+	}
+
+	if end.Before(start) {
+		return nil, http_error.BadRequest("end timestamp must not be before start time")
+	}
+	return &utils.DateFilterRange{From: &start, To: &end}, nil
+}
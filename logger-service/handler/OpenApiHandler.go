@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"tikube-backend/logger-service/openapi"
+)
+
+type OpenApiHandler struct{}
+
+func NewOpenApiController() *OpenApiHandler {
+	return &OpenApiHandler{}
+}
+
+// GetSpec returns the OpenAPI document of the HTTP API.
+func (oc *OpenApiHandler) GetSpec(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(openapi.Spec)
+	return err
+}
+
+// GetDocs returns a page listing the operations of the OpenAPI document.
+func (oc *OpenApiHandler) GetDocs(w http.ResponseWriter, r *http.Request) error {
+	var page bytes.Buffer
+	if err := openapi.RenderDocs(&page); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// The page is self-contained, nothing but its inline styles may load
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	w.WriteHeader(http.StatusOK)
+	_, err := page.WriteTo(w)
+	return err
+}
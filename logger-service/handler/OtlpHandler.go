@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime"
+	"net/http"
+	"tikube-backend/logger-service/otlp"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/utils"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+	// Seconds an exporter waits before retrying a batch that could not be stored
+	otlpRetryAfter = "5"
+)
+
+type OtlpHandler struct {
+	loggerService *service.LoggerService
+}
+
+func NewOtlpController(loggerService *service.LoggerService) *OtlpHandler {
+	return &OtlpHandler{loggerService: loggerService}
+}
+
+// ExportLogs implements the OTLP/HTTP logs endpoint for both the protobuf and the JSON encoding.
+// Records that fail validation are reported through partial success instead of failing the request,
+// so exporters don't retry the valid ones. The response is only sent once Kafka has the valid
+// records, when it fails the exporter is told to retry with a 503.
+func (oc *OtlpHandler) ExportLogs(w http.ResponseWriter, r *http.Request) error {
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (contentType != protobufContentType && contentType != jsonContentType) {
+		return http_error.UnsupportedMediaType("Content-Type must be application/x-protobuf or application/json")
+	}
+
+	body, err := readEncodedBody(w, r)
+	if err != nil {
+		return err
+	}
+
+	request := &collogspb.ExportLogsServiceRequest{}
+	if contentType == protobufContentType {
+		err = proto.Unmarshal(body, request)
+	} else {
+		request, err = otlp.DecodeJSON(body)
+	}
+	if err != nil {
+		return http_error.BadRequest("Invalid OTLP request: " + err.Error())
+	}
+
+	response := &collogspb.ExportLogsServiceResponse{}
+	rejected, reason, err := oc.loggerService.PublishLogsConfirmed(r.Context(), otlp.ToLogs(request))
+	if err != nil {
+		w.Header().Set("Retry-After", otlpRetryAfter)
+		return http_error.ServiceUnavailable("The logs could not be stored, retry later").WithCause(err)
+	}
+	if rejected > 0 {
+		response.PartialSuccess = &collogspb.ExportLogsPartialSuccess{RejectedLogRecords: int64(rejected), ErrorMessage: rejectionReason(reason)}
+	}
+
+	var data []byte
+	if contentType == protobufContentType {
+		data, err = proto.Marshal(response)
+	} else {
+		data, err = protojson.Marshal(response)
+	}
+	if err != nil {
+		return http_error.InternalServerError().WithCause(fmt.Errorf("encoding OTLP response: %w", err))
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(data)
+	return err
+}
+
+// readEncodedBody reads the request body, gunzipping it when the client compressed it. The
+// decompressed size is limited as well so a small gzip body can't expand without bound.
+func readEncodedBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	var body io.Reader = http.MaxBytesReader(w, r.Body, utils.MaxPayloadSize)
+
+	switch r.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, http_error.BadRequest("Invalid gzip body")
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		body = gz
+	default:
+		return nil, http_error.UnsupportedMediaType("Content-Encoding must be gzip or identity")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, utils.MaxPayloadSize+1))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || int64(len(data)) > utils.MaxPayloadSize {
+		return nil, http_error.PayloadTooLarge()
+	}
+	if err != nil {
+		return nil, http_error.BadRequest("Could not read the request body")
+	}
+	return data, nil
+}
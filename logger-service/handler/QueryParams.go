@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"tikube-backend/logger-service/lql"
+	"tikube-backend/logger-service/timerange"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// parsePagination reads the limit and offset query parameters. Offset is a page number,
+// so it is multiplied by the limit to get the row offset.
+func parsePagination(query url.Values) utils.Pagination {
+	const defaultLimit int = 10
+	const defaultOffset int = 0
+
+	limit := defaultLimit
+	offset := defaultOffset
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit >= 0 {
+			limit = parsedLimit
+		}
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if parsedOffset, err := strconv.Atoi(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+
+	return utils.Pagination{Limit: limit, Offset: offset * limit}
+}
+
+// parseIdParam reads a numeric path variable.
+func parseIdParam(vars map[string]string, name string) (int64, error) {
+	id, err := strconv.ParseInt(vars[name], 10, 64)
+	if err != nil || id <= 0 {
+		return 0, http_error.BadRequest("Invalid " + name)
+	}
+	return id, nil
+}
+
+// parseLogFilter reads the filter query parameters shared by every endpoint that lists or aggregates logs.
+func parseLogFilter(query url.Values) (utils.LogFilter, error) {
+	var filter utils.LogFilter
+
+	if filterStr := query.Get("level_filter"); filterStr != "" {
+		filter.LevelFilter = strings.Split(filterStr, ",")
+		for i, level := range filter.LevelFilter {
+			if parsed, ok := utils.ParseLogLevel(level); ok {
+				filter.LevelFilter[i] = string(parsed)
+			}
+		}
+	}
+
+	if sourceFilterStr := query.Get("source_filter"); sourceFilterStr != "" {
+		for _, pattern := range strings.Split(sourceFilterStr, ",") {
+			source, isPrefix, err := utils.ParseSourcePattern(pattern)
+			if err != nil {
+				return filter, http_error.BadRequest("source_filter: " + err.Error())
+			}
+			if isPrefix {
+				source += utils.SourceSeparator + "*"
+			}
+			filter.SourceFilter = append(filter.SourceFilter, source)
+		}
+	}
+
+	if minLevelStr := query.Get("min_level"); minLevelStr != "" {
+		minLevel, ok := utils.ParseLogLevel(minLevelStr)
+		if !ok {
+			return filter, http_error.BadRequest("min_level: unknown log level " + minLevelStr)
+		}
+		filter.MinLevel = minLevel
+	}
+
+	//Date filter, zone-less times are read in the tz time zone
+	if dateFilterStr := query.Get("date_filter"); dateFilterStr != "" {
+		loc, err := timerange.LoadLocation(query.Get("tz"))
+		if err != nil {
+			return filter, http_error.BadRequest(err.Error())
+		}
+		dateFilter, err := timerange.Parse(dateFilterStr, time.Now(), loc)
+		if err != nil {
+			return filter, http_error.BadRequest("date_filter: " + err.Error())
+		}
+		filter.DateFilter = dateFilter
+	}
+
+	//LQL query, compiled here so that syntax errors and invalid values are reported as a bad request
+	if q := query.Get("q"); q != "" {
+		if _, _, err := lql.CompileQuery(q); err != nil {
+			return filter, http_error.BadRequest(err.Error())
+		}
+		filter.Query = q
+	}
+
+	return filter, nil
+}
+
+// sortableLogFields can be used in the sort parameter.
+var sortableLogFields = []string{"id", "createdAt", "logLevel", "source"}
+
+const maxSortFields = 4
+
+// parseLogView reads sort (e.g. createdAt:desc,level:asc), fields (e.g. id,level,message) and
+// message_length. "level" is accepted as a shorthand for logLevel.
+func parseLogView(query url.Values) (utils.LogView, error) {
+	var view utils.LogView
+
+	if sortStr := query.Get("sort"); sortStr != "" {
+		seen := map[string]bool{}
+		for _, term := range strings.Split(sortStr, ",") {
+			field, direction, _ := strings.Cut(strings.TrimSpace(term), ":")
+			field = canonicalLogField(field)
+			if !slices.Contains(sortableLogFields, field) {
+				return view, http_error.BadRequest("sort: logs can't be sorted by " + field + ", use one of id, createdAt, level, source")
+			}
+			if seen[field] {
+				return view, http_error.BadRequest("sort: " + field + " is listed twice")
+			}
+			seen[field] = true
+
+			switch strings.ToLower(direction) {
+			case "", "asc":
+				view.Sort = append(view.Sort, utils.SortField{Field: field})
+			case "desc":
+				view.Sort = append(view.Sort, utils.SortField{Field: field, Desc: true})
+			default:
+				return view, http_error.BadRequest("sort: direction must be asc or desc")
+			}
+		}
+		if len(view.Sort) > maxSortFields {
+			return view, http_error.BadRequest(fmt.Sprintf("sort: at most %d fields are allowed", maxSortFields))
+		}
+	}
+
+	if fieldsStr := query.Get("fields"); fieldsStr != "" {
+		for _, field := range strings.Split(fieldsStr, ",") {
+			field = canonicalLogField(strings.TrimSpace(field))
+			if !utils.IsLogColumn(field) {
+				return view, http_error.BadRequest("fields: unknown field " + field)
+			}
+			if !slices.Contains(view.Fields, field) {
+				view.Fields = append(view.Fields, field)
+			}
+		}
+	}
+
+	if lengthStr := query.Get("message_length"); lengthStr != "" {
+		length, err := strconv.Atoi(lengthStr)
+		if err != nil || length <= 0 || length > 65535 {
+			return view, http_error.BadRequest("message_length must be between 1 and 65535")
+		}
+		view.MessageLength = length
+	}
+
+	return view, nil
+}
+
+func canonicalLogField(field string) string {
+	if field == "level" {
+		return "logLevel"
+	}
+	return field
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/service"
+	"tikube-backend/shared/utils"
+)
+
+type SavedSearchHandler struct {
+	savedSearchService *service.SavedSearchService
+}
+
+func NewSavedSearchController(savedSearchService *service.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{savedSearchService: savedSearchService}
+}
+
+func (sc *SavedSearchHandler) GetSearches(w http.ResponseWriter, r *http.Request) error {
+	query := r.URL.Query()
+
+	searches, err := sc.savedSearchService.GetSearches(r.Context(), query.Get("owner"), parsePagination(query))
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, searches)
+}
+
+func (sc *SavedSearchHandler) GetSearch(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	search, err := sc.savedSearchService.GetSearch(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, search)
+}
+
+func (sc *SavedSearchHandler) CreateSearch(w http.ResponseWriter, r *http.Request) error {
+	payload := r.Context().Value(utils.PayloadKey{}).(model.SavedSearchSchema)
+
+	search, err := sc.savedSearchService.CreateSearch(r.Context(), toSavedSearch(payload))
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusCreated, search)
+}
+
+func (sc *SavedSearchHandler) UpdateSearch(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	payload := r.Context().Value(utils.PayloadKey{}).(model.SavedSearchSchema)
+
+	search, err := sc.savedSearchService.UpdateSearch(r.Context(), id, toSavedSearch(payload))
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, search)
+}
+
+func (sc *SavedSearchHandler) DeleteSearch(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	if err := sc.savedSearchService.DeleteSearch(r.Context(), id); err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusNoContent, nil)
+}
+
+func (sc *SavedSearchHandler) RunSearch(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	result, err := sc.savedSearchService.RunSearch(r.Context(), id, parsePagination(r.URL.Query()))
+	if err != nil {
+		return err
+	}
+	// Only the columns of the search are fetched, the others are left out as GetLogs does with fields
+	if len(result.Search.Columns) > 0 {
+		return utils.JSONResponse(w, http.StatusOK, struct {
+			Search *utils.SavedSearch                     `json:"search"`
+			Logs   utils.PaginationResult[map[string]any] `json:"logs"`
+		}{result.Search, projectLogs(result.Logs, result.Search.Columns)})
+	}
+	return utils.JSONResponse(w, http.StatusOK, result)
+}
+
+func (sc *SavedSearchHandler) ShareSearch(w http.ResponseWriter, r *http.Request) error {
+	id, err := parseIdParam(mux.Vars(r), "id")
+	if err != nil {
+		return err
+	}
+
+	search, err := sc.savedSearchService.ShareSearch(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, map[string]string{
+		"token": search.ShareToken,
+		"path":  "/logger/searches/shared/" + search.ShareToken,
+	})
+}
+
+func (sc *SavedSearchHandler) ResolveShareToken(w http.ResponseWriter, r *http.Request) error {
+	search, err := sc.savedSearchService.ResolveShareToken(r.Context(), mux.Vars(r)["token"])
+	if err != nil {
+		return err
+	}
+	return utils.JSONResponse(w, http.StatusOK, search)
+}
+
+func toSavedSearch(payload model.SavedSearchSchema) utils.SavedSearch {
+	return utils.SavedSearch{
+		Name:      strings.TrimSpace(payload.Name),
+		Owner:     strings.TrimSpace(payload.Owner),
+		Filter:    payload.Filter,
+		Columns:   payload.Columns,
+		TimeRange: payload.TimeRange,
+	}
+}
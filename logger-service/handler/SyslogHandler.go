@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"net/http"
+	"tikube-backend/logger-service/syslog"
+	"tikube-backend/shared/utils"
+)
+
+type SyslogHandler struct {
+	server *syslog.Server
+}
+
+func NewSyslogController(server *syslog.Server) *SyslogHandler {
+	return &SyslogHandler{server: server}
+}
+
+// GetStats returns the counters of the syslog listener, including messages that could not be parsed.
+func (sc *SyslogHandler) GetStats(w http.ResponseWriter, r *http.Request) error {
+	return utils.JSONResponse(w, http.StatusOK, sc.server.Stats())
+}
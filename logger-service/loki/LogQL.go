@@ -0,0 +1,402 @@
+package loki
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"slices"
+	"strconv"
+	"strings"
+	"tikube-backend/shared/utils"
+	"unicode"
+)
+
+// Labels that map onto the source and level columns, every other label is an attribute.
+var (
+	sourceLabels = []string{"source", "service_name", "app", "job", "service"}
+	levelLabels  = []string{"level", "detected_level", "severity", "log_level"}
+)
+
+// Matcher is a label matcher of a stream selector, Op is one of =, !=, =~ and !~.
+type Matcher struct {
+	Name  string
+	Op    string
+	Value string
+}
+
+// LineFilter is a line filter expression, Op is one of |=, !=, |~ and !~.
+type LineFilter struct {
+	Op    string
+	Value string
+}
+
+// Query is a LogQL log query limited to a stream selector followed by line filters, e.g.
+// {app="payments", level=~"error|warn"} |= "timeout" != "retry". Parsers, formatters and metric
+// queries are not supported.
+type Query struct {
+	Matchers []Matcher
+	Filters  []LineFilter
+}
+
+type scanner struct {
+	input string
+	pos   int
+}
+
+func (s *scanner) errorf(format string, args ...any) error {
+	return fmt.Errorf("parse error at line 1, col %d: %s", s.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.input) && unicode.IsSpace(rune(s.input[s.pos])) {
+		s.pos++
+	}
+}
+
+func (s *scanner) done() bool {
+	s.skipSpace()
+	return s.pos >= len(s.input)
+}
+
+// consume skips the given token when the input continues with it.
+func (s *scanner) consume(token string) bool {
+	s.skipSpace()
+	if strings.HasPrefix(s.input[s.pos:], token) {
+		s.pos += len(token)
+		return true
+	}
+	return false
+}
+
+func (s *scanner) identifier() (string, error) {
+	s.skipSpace()
+	start := s.pos
+	for s.pos < len(s.input) {
+		c := s.input[s.pos]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || s.pos > start && c >= '0' && c <= '9' {
+			s.pos++
+			continue
+		}
+		break
+	}
+	if s.pos == start {
+		return "", s.errorf("expected a label name")
+	}
+	return s.input[start:s.pos], nil
+}
+
+// str reads a double quoted string with Go escapes or a raw string in backticks.
+func (s *scanner) str() (string, error) {
+	s.skipSpace()
+	if s.pos >= len(s.input) || (s.input[s.pos] != '"' && s.input[s.pos] != '`') {
+		return "", s.errorf("expected a string")
+	}
+
+	quote := s.input[s.pos]
+	start := s.pos
+	for i := s.pos + 1; i < len(s.input); i++ {
+		if quote == '"' && s.input[i] == '\\' {
+			i++
+			continue
+		}
+		if s.input[i] == quote {
+			s.pos = i + 1
+			value, err := strconv.Unquote(s.input[start:s.pos])
+			if err != nil {
+				s.pos = start
+				return "", s.errorf("invalid string")
+			}
+			return value, nil
+		}
+	}
+	return "", s.errorf("unterminated string")
+}
+
+// selector reads {name op "value", ...}.
+func (s *scanner) selector() ([]Matcher, error) {
+	if !s.consume("{") {
+		return nil, s.errorf("expected a stream selector such as {app=\"payments\"}, metric queries are not supported")
+	}
+	if s.consume("}") {
+		return nil, nil
+	}
+
+	var matchers []Matcher
+	for {
+		name, err := s.identifier()
+		if err != nil {
+			return nil, err
+		}
+
+		var op string
+		for _, candidate := range []string{"=~", "!~", "!=", "="} {
+			if s.consume(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return nil, s.errorf("expected =, !=, =~ or !~ after %s", name)
+		}
+
+		value, err := s.str()
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, Matcher{Name: name, Op: op, Value: value})
+
+		if s.consume("}") {
+			return matchers, nil
+		}
+		if !s.consume(",") {
+			return nil, s.errorf("expected , or }")
+		}
+	}
+}
+
+// ParseLabels parses a label set such as {app="payments", env="prod"}, the form used by push requests.
+func ParseLabels(labels string) (map[string]string, error) {
+	s := &scanner{input: labels}
+	matchers, err := s.selector()
+	if err != nil {
+		return nil, err
+	}
+	if !s.done() {
+		return nil, s.errorf("unexpected input after the labels")
+	}
+
+	result := make(map[string]string, len(matchers))
+	for _, m := range matchers {
+		if m.Op != "=" {
+			return nil, fmt.Errorf("label %s must use =", m.Name)
+		}
+		result[m.Name] = m.Value
+	}
+	return result, nil
+}
+
+// ParseQuery parses a log query made of a stream selector and line filters.
+func ParseQuery(query string) (*Query, error) {
+	s := &scanner{input: query}
+	matchers, err := s.selector()
+	if err != nil {
+		return nil, err
+	}
+	if len(matchers) == 0 {
+		return nil, s.errorf("queries require at least one label matcher")
+	}
+
+	q := &Query{Matchers: matchers}
+	for !s.done() {
+		var op string
+		for _, candidate := range []string{"|=", "|~", "!=", "!~"} {
+			if s.consume(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return nil, s.errorf("only line filters (|=, !=, |~, !~) are supported after the stream selector")
+		}
+
+		value, err := s.str()
+		if err != nil {
+			return nil, err
+		}
+		q.Filters = append(q.Filters, LineFilter{Op: op, Value: value})
+	}
+	return q, nil
+}
+
+// ToLQL translates the query into an LQL query. Regular expressions are only supported when they are
+// an alternation of literals, optionally followed by .* or .+, e.g. "payments|billing.*".
+func (q *Query) ToLQL() (string, error) {
+	var terms []string
+
+	for _, m := range q.Matchers {
+		field := labelField(m.Name)
+
+		alternatives := []alternative{{value: m.Value}}
+		if m.Op == "=~" || m.Op == "!~" {
+			var err error
+			if alternatives, err = parseAlternatives(m.Value); err != nil {
+				return "", fmt.Errorf("unsupported regular expression for %s: %w", m.Name, err)
+			}
+		}
+		negate := m.Op == "!=" || m.Op == "!~"
+
+		var matches []string
+		matchesAll := false
+		for _, alt := range alternatives {
+			if alt.value == "" && alt.suffix == "*" {
+				matchesAll = true
+				break
+			}
+			matches = append(matches, matchTerm(field, alt))
+		}
+
+		if matchesAll {
+			if negate {
+				return "", fmt.Errorf("the matcher on %s never matches", m.Name)
+			}
+			continue
+		}
+
+		term := strings.Join(matches, " OR ")
+		if len(matches) > 1 {
+			term = "(" + term + ")"
+		}
+		if negate {
+			// label!="" only checks that the label is present
+			if absent, ok := strings.CutPrefix(term, "NOT "); ok {
+				term = absent
+			} else {
+				term = "NOT " + term
+			}
+		}
+		terms = append(terms, term)
+	}
+
+	for _, f := range q.Filters {
+		value := f.Value
+		if f.Op == "|~" || f.Op == "!~" {
+			re, err := syntax.Parse(value, syntax.Perl)
+			if err != nil || (re.Op != syntax.OpLiteral && re.Op != syntax.OpEmptyMatch) || re.Flags&syntax.FoldCase != 0 {
+				return "", fmt.Errorf("regular expression line filters are only supported for plain text, got %q", value)
+			}
+			value = string(re.Rune)
+		}
+		if value == "" {
+			// An empty filter matches every line, Grafana adds one to new queries
+			continue
+		}
+
+		term := "message:" + quote(value)
+		if f.Op == "!=" || f.Op == "!~" {
+			term = "NOT " + term
+		}
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, " AND "), nil
+}
+
+func labelField(name string) string {
+	if slices.Contains(sourceLabels, name) {
+		return "source"
+	}
+	if slices.Contains(levelLabels, name) {
+		return "level"
+	}
+	return "attributes." + name
+}
+
+// alternative is one branch of a regular expression: a literal value followed by * (.*), ?* (.+) or nothing.
+type alternative struct {
+	value  string
+	suffix string
+}
+
+func parseAlternatives(expr string) ([]alternative, error) {
+	var alternatives []alternative
+	for _, part := range splitAlternation(expr) {
+		re, err := syntax.Parse(part, syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		alt, ok := toAlternative(re)
+		if !ok {
+			return nil, fmt.Errorf("%q is not an alternation of literals", expr)
+		}
+		alternatives = append(alternatives, alt)
+	}
+	return alternatives, nil
+}
+
+// splitAlternation splits on the | that are neither escaped nor inside a group.
+func splitAlternation(expr string) []string {
+	var parts []string
+	start, depth := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '|':
+			if depth == 0 {
+				parts = append(parts, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+func toAlternative(re *syntax.Regexp) (alternative, bool) {
+	if re.Flags&syntax.FoldCase != 0 {
+		return alternative{}, false
+	}
+
+	switch re.Op {
+	case syntax.OpEmptyMatch:
+		return alternative{}, true
+	case syntax.OpLiteral:
+		return alternative{value: string(re.Rune)}, true
+	case syntax.OpStar, syntax.OpPlus:
+		return wildcardSuffix(re)
+	case syntax.OpConcat:
+		if len(re.Sub) != 2 || re.Sub[0].Op != syntax.OpLiteral || re.Sub[0].Flags&syntax.FoldCase != 0 {
+			return alternative{}, false
+		}
+		alt, ok := wildcardSuffix(re.Sub[1])
+		alt.value = string(re.Sub[0].Rune)
+		return alt, ok
+	}
+	return alternative{}, false
+}
+
+func wildcardSuffix(re *syntax.Regexp) (alternative, bool) {
+	if (re.Op != syntax.OpStar && re.Op != syntax.OpPlus) || len(re.Sub) != 1 ||
+		(re.Sub[0].Op != syntax.OpAnyCharNotNL && re.Sub[0].Op != syntax.OpAnyChar) {
+		return alternative{}, false
+	}
+	if re.Op == syntax.OpPlus {
+		return alternative{suffix: "?*"}, true
+	}
+	return alternative{suffix: "*"}, true
+}
+
+// matchTerm matches a field against one alternative. Loki treats an empty value as a missing label.
+func matchTerm(field string, alt alternative) string {
+	value := alt.value
+	if field == "source" {
+		value = utils.NormalizeSource(value)
+	}
+
+	if alt.suffix == "" {
+		if value == "" {
+			return "NOT " + field + ":*"
+		}
+		return field + ":" + quote(value)
+	}
+	return field + ":" + escapeWord(value) + alt.suffix
+}
+
+func quote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// escapeWord escapes every character of an unquoted LQL word so that only the wildcards added
+// after it keep their meaning.
+func escapeWord(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,101 @@
+package loki
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/utils"
+)
+
+// Used when a stream has none of the source labels.
+const unknownSource = "unknown_service"
+
+// Structured metadata keys carrying the trace context, as written by the OpenTelemetry collector and Alloy.
+var (
+	traceIdKeys = []string{"trace_id", "traceID", "traceId"}
+	spanIdKeys  = []string{"span_id", "spanID", "spanId"}
+)
+
+// ToLogs flattens pushed streams into logs. The first source label found is the source and the
+// level comes from a level label or structured metadata, defaulting to INFO. Every other label and
+// structured metadata entry becomes an attribute.
+func ToLogs(streams []PushStream) []model.CreateLogSchema {
+	var logs []model.CreateLogSchema
+	for _, stream := range streams {
+		source, sourceLabel := firstLabel(stream.Labels, sourceLabels)
+		if source == "" {
+			source = unknownSource
+		}
+		level, levelLabel := firstLabel(stream.Labels, levelLabels)
+
+		for _, entry := range stream.Entries {
+			log := model.CreateLogSchema{
+				LogLevel: levelOf(level, entry.Metadata),
+				Source:   source,
+				Message:  entry.Line,
+			}
+
+			if traceId, key := firstLabel(entry.Metadata, traceIdKeys); traceId != "" {
+				log.TraceId = strings.ToLower(traceId)
+				delete(entry.Metadata, key)
+				if spanId, key := firstLabel(entry.Metadata, spanIdKeys); spanId != "" {
+					log.SpanId = strings.ToLower(spanId)
+					delete(entry.Metadata, key)
+				}
+			}
+
+			attributes := map[string]any{}
+			addAttributes(attributes, entry.Metadata)
+			addAttributes(attributes, stream.Labels, sourceLabel, levelLabel)
+			if len(attributes) > 0 {
+				log.Attributes = attributes
+			}
+
+			if !entry.Timestamp.IsZero() && entry.Timestamp.Unix() > 0 {
+				timestamp := entry.Timestamp
+				log.Timestamp = &timestamp
+			}
+
+			logs = append(logs, log)
+		}
+	}
+	return logs
+}
+
+// firstLabel returns the value and name of the first of names present in labels.
+func firstLabel(labels map[string]string, names []string) (string, string) {
+	for _, name := range names {
+		if value := labels[name]; value != "" {
+			return value, name
+		}
+	}
+	return "", ""
+}
+
+func levelOf(streamLevel string, metadata map[string]string) utils.LogLevel {
+	if level, ok := utils.ParseLogLevel(streamLevel); ok {
+		return level
+	}
+	if value, _ := firstLabel(metadata, levelLabels); value != "" {
+		if level, ok := utils.ParseLogLevel(value); ok {
+			return level
+		}
+	}
+	return utils.INFO
+}
+
+// addAttributes adds the labels in name order so that the same ones are kept when there are too many.
+func addAttributes(attributes map[string]any, labels map[string]string, skip ...string) {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !slices.Contains(skip, name) {
+			model.AddAttribute(attributes, name, labels[name])
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// Package loki implements the subset of the Loki HTTP API used by promtail and Grafana.
+package loki
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+	"strconv"
+	"time"
+)
+
+var ErrTooLarge = errors.New("push request is too large")
+
+// PushStream is a stream of a push request: a label set and its entries.
+type PushStream struct {
+	Labels  map[string]string
+	Entries []Entry
+}
+
+type Entry struct {
+	Timestamp time.Time
+	Line      string
+	Metadata  map[string]string // structured metadata
+}
+
+// DecodeProtobuf decodes a snappy compressed logproto.PushRequest, the format promtail sends.
+// The decompressed size is checked before decompressing so a small body can't expand without bound.
+func DecodeProtobuf(data []byte, maxSize int64) ([]PushStream, error) {
+	size, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy body: %w", err)
+	}
+	if int64(size) > maxSize {
+		return nil, ErrTooLarge
+	}
+	data, err = snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snappy body: %w", err)
+	}
+
+	var streams []PushStream
+	err = forEachField(data, func(num protowire.Number, value []byte) error {
+		if num != 1 {
+			return nil
+		}
+		stream, err := decodeStream(value)
+		if err != nil {
+			return err
+		}
+		streams = append(streams, stream)
+		return nil
+	})
+	return streams, err
+}
+
+// decodeStream decodes a StreamAdapter: labels = 1, entries = 2. The hash (3) is not needed.
+func decodeStream(data []byte) (PushStream, error) {
+	var stream PushStream
+	var labels string
+	err := forEachField(data, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			labels = string(value)
+		case 2:
+			entry, err := decodeEntry(value)
+			if err != nil {
+				return err
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return stream, err
+	}
+
+	stream.Labels, err = ParseLabels(labels)
+	return stream, err
+}
+
+// decodeEntry decodes an EntryAdapter: timestamp = 1, line = 2, structured metadata = 3.
+func decodeEntry(data []byte) (Entry, error) {
+	var entry Entry
+	err := forEachField(data, func(num protowire.Number, value []byte) error {
+		switch num {
+		case 1:
+			var seconds, nanos int64
+			err := forEachVarint(value, func(num protowire.Number, v uint64) {
+				switch num {
+				case 1:
+					seconds = int64(v)
+				case 2:
+					nanos = int64(int32(v))
+				}
+			})
+			if err != nil {
+				return err
+			}
+			entry.Timestamp = time.Unix(seconds, nanos).UTC()
+		case 2:
+			entry.Line = string(value)
+		case 3:
+			var name, labelValue string
+			err := forEachField(value, func(num protowire.Number, v []byte) error {
+				switch num {
+				case 1:
+					name = string(v)
+				case 2:
+					labelValue = string(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if entry.Metadata == nil {
+				entry.Metadata = map[string]string{}
+			}
+			entry.Metadata[name] = labelValue
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// forEachField calls fn with every length delimited field of a message and skips the others.
+func forEachField(data []byte, fn func(num protowire.Number, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err := fn(num, value); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// forEachVarint calls fn with every varint field of a message and skips the others.
+func forEachVarint(data []byte, fn func(num protowire.Number, value uint64)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if typ != protowire.VarintType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			data = data[n:]
+			continue
+		}
+
+		value, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		fn(num, value)
+		data = data[n:]
+	}
+	return nil
+}
+
+type jsonPushRequest struct {
+	Streams []struct {
+		Stream map[string]string   `json:"stream"`
+		Values [][]json.RawMessage `json:"values"`
+	} `json:"streams"`
+}
+
+// DecodeJSON decodes the JSON push format. Each value is [timestamp in nanoseconds, line] with
+// an optional structured metadata object as third element.
+func DecodeJSON(data []byte) ([]PushStream, error) {
+	var request jsonPushRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+
+	streams := make([]PushStream, 0, len(request.Streams))
+	for _, s := range request.Streams {
+		stream := PushStream{Labels: s.Stream}
+		for _, value := range s.Values {
+			if len(value) < 2 || len(value) > 3 {
+				return nil, errors.New("values must be [timestamp, line] or [timestamp, line, metadata]")
+			}
+
+			var timestamp, line string
+			if err := json.Unmarshal(value[0], &timestamp); err != nil {
+				return nil, errors.New("timestamp must be a string of nanoseconds")
+			}
+			nanos, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid timestamp %q", timestamp)
+			}
+			if err := json.Unmarshal(value[1], &line); err != nil {
+				return nil, errors.New("line must be a string")
+			}
+
+			entry := Entry{Timestamp: time.Unix(0, nanos).UTC(), Line: line}
+			if len(value) == 3 {
+				if err := json.Unmarshal(value[2], &entry.Metadata); err != nil {
+					return nil, errors.New("structured metadata must be an object of strings")
+				}
+			}
+			stream.Entries = append(stream.Entries, entry)
+		}
+		streams = append(streams, stream)
+	}
+	return streams, nil
+}
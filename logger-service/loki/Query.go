@@ -0,0 +1,67 @@
+package loki
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// Response is the envelope of every Loki query API response.
+type Response struct {
+	Status string `json:"status"`
+	Data   any    `json:"data"`
+}
+
+type StreamsData struct {
+	ResultType string         `json:"resultType"`
+	Result     []ResultStream `json:"result"`
+	Stats      map[string]any `json:"stats"`
+}
+
+// ResultStream is a stream of a query result, values are [timestamp in nanoseconds, line] pairs.
+type ResultStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// NewStreamsResponse groups logs into one stream per source and level, keeping their order.
+func NewStreamsResponse(logs []utils.Log) Response {
+	result := []ResultStream{}
+	index := map[[2]string]int{}
+	for _, log := range logs {
+		key := [2]string{log.Source, string(log.LogLevel)}
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, ResultStream{Stream: map[string]string{"source": log.Source, "level": string(log.LogLevel)}})
+		}
+		result[i].Values = append(result[i].Values, [2]string{strconv.FormatInt(log.CreatedAt.UnixNano(), 10), log.Message})
+	}
+
+	return Response{Status: "success", Data: StreamsData{ResultType: "streams", Result: result, Stats: map[string]any{}}}
+}
+
+// LabelNames are the labels of the streams returned by queries.
+var LabelNames = []string{"level", "source"}
+
+// ParseTime reads the start and end parameters: nanoseconds since the epoch, seconds with a
+// fractional part, or RFC3339.
+func ParseTime(value string) (time.Time, error) {
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(0, nanos).UTC(), nil
+	}
+	if strings.Contains(value, ".") {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(seconds, 0) && !math.IsNaN(seconds) {
+			whole, fraction := math.Modf(seconds)
+			return time.Unix(int64(whole), int64(fraction*1e9)).UTC(), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected nanoseconds since the epoch or RFC3339", value)
+}
@@ -0,0 +1,292 @@
+package loki
+
+import (
+	"errors"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+	"reflect"
+	"testing"
+	"tikube-backend/logger-service/lql"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+func TestToLQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`{app="payments"}`, `source:"PAYMENTS"`},
+		{`{service_name="payments:api"}`, `source:"PAYMENTS:API"`},
+		{`{level="error"}`, `level:"error"`},
+		{`{env="prod"}`, `attributes.env:"prod"`},
+		{`{env!="prod"}`, `NOT attributes.env:"prod"`},
+		{`{app="payments", env="prod"}`, `source:"PAYMENTS" AND attributes.env:"prod"`},
+
+		// Loki treats an empty value as a missing label
+		{`{app="payments", env!=""}`, `source:"PAYMENTS" AND attributes.env:*`},
+		{`{env=""}`, `NOT attributes.env:*`},
+
+		// Regular expressions made of literals and trailing wildcards
+		{`{app=~"payments|billing"}`, `(source:"PAYMENTS" OR source:"BILLING")`},
+		{`{level=~"error|warn"}`, `(level:"error" OR level:"warn")`},
+		{`{env!~"prod|staging"}`, `NOT (attributes.env:"prod" OR attributes.env:"staging")`},
+		{`{env=~"prod|"}`, `(attributes.env:"prod" OR NOT attributes.env:*)`},
+		{`{app=~"pay.*"}`, `source:PAY*`},
+		{`{host=~"web-.+"}`, `attributes.host:web\-?*`},
+		{`{host=~"web\\.1"}`, `attributes.host:"web.1"`},
+		{`{app="payments", env=~".*"}`, `source:"PAYMENTS"`},
+
+		// Line filters
+		{`{app="payments"} |= "timeout" != "retry"`, `source:"PAYMENTS" AND message:"timeout" AND NOT message:"retry"`},
+		{`{app="payments"} |~ "connection refused"`, `source:"PAYMENTS" AND message:"connection refused"`},
+		{`{app="payments"} !~ "a\\.b"`, `source:"PAYMENTS" AND NOT message:"a.b"`},
+		{`{app="payments"} |= "say \"hi\""`, `source:"PAYMENTS" AND message:"say \"hi\""`},
+		{"{app=\"payments\"} |= `C:\\temp`", `source:"PAYMENTS" AND message:"C:\\temp"`},
+		{`{app="payments"} |= ""`, `source:"PAYMENTS"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			query, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned %v", tt.query, err)
+			}
+			got, err := query.ToLQL()
+			if err != nil {
+				t.Fatalf("ToLQL(%q) returned %v", tt.query, err)
+			}
+			if got != tt.want {
+				t.Errorf("ToLQL(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+			if _, _, err := lql.CompileQuery(got); err != nil {
+				t.Errorf("ToLQL(%q) = %s, which does not compile: %v", tt.query, got, err)
+			}
+		})
+	}
+}
+
+func TestToLQLErrors(t *testing.T) {
+	tests := []string{
+		`{app=~"pay(ments|ing)"}`,
+		`{app=~"[a-z]+"}`,
+		`{app=~"(?i)payments"}`,
+		`{app!~".*"}`,
+		`{app="payments"} |~ "time.*out"`,
+		`{app="payments"} |~ "(?i)error"`,
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			parsed, err := ParseQuery(query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) returned %v", query, err)
+			}
+			if got, err := parsed.ToLQL(); err == nil {
+				t.Errorf("ToLQL(%q) = %s, want an error", query, got)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []string{
+		`sum(rate({app="payments"}[1m]))`,
+		`{}`,
+		`{app="payments"} | json`,
+		`{app="payments" env="prod"}`,
+		`{app="payments}`,
+		`{app~"payments"}`,
+		`{app="\q"}`,
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			if got, err := ParseQuery(query); err == nil {
+				t.Errorf("ParseQuery(%q) = %+v, want an error", query, got)
+			}
+		})
+	}
+}
+
+// pushRequest encodes a logproto.PushRequest field by field, the way promtail's client builds its
+// body: one StreamAdapter per label set, each EntryAdapter with a google.protobuf.Timestamp.
+func pushRequest(labels string, entries ...[]byte) []byte {
+	var stream []byte
+	stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+	stream = protowire.AppendString(stream, labels)
+	for _, entry := range entries {
+		stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+		stream = protowire.AppendBytes(stream, entry)
+	}
+
+	var request []byte
+	request = protowire.AppendTag(request, 1, protowire.BytesType)
+	return protowire.AppendBytes(request, stream)
+}
+
+func pushEntry(timestamp time.Time, line string, metadata ...string) []byte {
+	var ts []byte
+	ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(timestamp.Unix()))
+	ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+	ts = protowire.AppendVarint(ts, uint64(timestamp.Nanosecond()))
+
+	var entry []byte
+	entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+	entry = protowire.AppendBytes(entry, ts)
+	entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+	entry = protowire.AppendString(entry, line)
+	for i := 0; i+1 < len(metadata); i += 2 {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, metadata[i])
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, metadata[i+1])
+		entry = protowire.AppendTag(entry, 3, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, label)
+	}
+	return entry
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+	first := time.Date(2024, 5, 15, 13, 47, 21, 123_456_789, time.UTC)
+	second := first.Add(time.Second)
+
+	request := pushRequest(`{filename="/var/log/payments.log", job="payments", level="error"}`,
+		pushEntry(first, `level=error msg="charge failed" err="timeout"`),
+		pushEntry(second, "retrying", "trace_id", "4BF92F3577B34DA6A3CE929D0E0E4736"),
+	)
+	// Fields promtail doesn't send, such as the stream hash, are skipped
+	request = protowire.AppendTag(request, 3, protowire.VarintType)
+	request = protowire.AppendVarint(request, 42)
+
+	got, err := DecodeProtobuf(snappy.Encode(nil, request), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PushStream{{
+		Labels: map[string]string{"filename": "/var/log/payments.log", "job": "payments", "level": "error"},
+		Entries: []Entry{
+			{Timestamp: first, Line: `level=error msg="charge failed" err="timeout"`},
+			{Timestamp: second, Line: "retrying", Metadata: map[string]string{"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736"}},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeProtobuf = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeProtobufErrors(t *testing.T) {
+	request := pushRequest(`{job="payments"}`, pushEntry(time.Unix(1715780841, 0), "hello"))
+
+	tests := []struct {
+		name string
+		body []byte
+		want error
+	}{
+		{"too large", snappy.Encode(nil, request), ErrTooLarge},
+		{"not snappy", request, nil},
+		{"truncated protobuf", snappy.Encode(nil, request[:len(request)-3]), nil},
+		{"invalid labels", snappy.Encode(nil, pushRequest(`job="payments"`)), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maxSize := int64(1 << 20)
+			if tt.want == ErrTooLarge {
+				maxSize = int64(len(request) - 1)
+			}
+			got, err := DecodeProtobuf(tt.body, maxSize)
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("DecodeProtobuf = %+v, %v, want an error", got, err)
+			}
+		})
+	}
+}
+
+func TestDecodeJSON(t *testing.T) {
+	body := `{"streams": [{
+		"stream": {"app": "payments", "level": "warn"},
+		"values": [
+			["1715780841123456789", "retrying charge"],
+			["1715780842000000000", "charged", {"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736"}]
+		]
+	}]}`
+
+	got, err := DecodeJSON([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PushStream{{
+		Labels: map[string]string{"app": "payments", "level": "warn"},
+		Entries: []Entry{
+			{Timestamp: time.Date(2024, 5, 15, 13, 47, 21, 123_456_789, time.UTC), Line: "retrying charge"},
+			{Timestamp: time.Date(2024, 5, 15, 13, 47, 22, 0, time.UTC), Line: "charged", Metadata: map[string]string{"trace_id": "4BF92F3577B34DA6A3CE929D0E0E4736"}},
+		},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeJSON = %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"not json", `streams`},
+		{"numeric timestamp", `{"streams": [{"stream": {"app": "a"}, "values": [[1715780841123456789, "hello"]]}]}`},
+		{"invalid timestamp", `{"streams": [{"stream": {"app": "a"}, "values": [["yesterday", "hello"]]}]}`},
+		{"missing line", `{"streams": [{"stream": {"app": "a"}, "values": [["1715780841123456789"]]}]}`},
+		{"line not a string", `{"streams": [{"stream": {"app": "a"}, "values": [["1715780841123456789", 42]]}]}`},
+		{"metadata not strings", `{"streams": [{"stream": {"app": "a"}, "values": [["1715780841123456789", "hello", {"retries": 3}]]}]}`},
+		{"too many elements", `{"streams": [{"stream": {"app": "a"}, "values": [["1715780841123456789", "hello", {}, {}]]}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := DecodeJSON([]byte(tt.body)); err == nil {
+				t.Errorf("DecodeJSON(%s) = %+v, want an error", tt.body, got)
+			}
+		})
+	}
+}
+
+func TestToLogs(t *testing.T) {
+	seen := time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)
+	streams := []PushStream{
+		{
+			Labels: map[string]string{"job": "payments", "level": "error", "env": "prod"},
+			Entries: []Entry{{Timestamp: seen, Line: "charge failed", Metadata: map[string]string{
+				"traceID": "4BF92F3577B34DA6A3CE929D0E0E4736", "spanID": "00F067AA0BA902B7", "region": "eu",
+			}}},
+		},
+		{
+			Labels:  map[string]string{"filename": "/var/log/app.log"},
+			Entries: []Entry{{Line: "started", Metadata: map[string]string{"detected_level": "debug"}}},
+		},
+	}
+
+	got := ToLogs(streams)
+	if len(got) != 2 {
+		t.Fatalf("ToLogs returned %d logs, want 2", len(got))
+	}
+
+	if got[0].Source != "payments" || got[0].LogLevel != utils.ERROR || got[0].Message != "charge failed" ||
+		got[0].TraceId != "4bf92f3577b34da6a3ce929d0e0e4736" || got[0].SpanId != "00f067aa0ba902b7" ||
+		got[0].Timestamp == nil || !got[0].Timestamp.Equal(seen) {
+		t.Errorf("ToLogs[0] = %+v", got[0])
+	}
+	if want := map[string]any{"env": "prod", "region": "eu"}; !reflect.DeepEqual(got[0].Attributes, want) {
+		t.Errorf("ToLogs[0] attributes = %v, want %v", got[0].Attributes, want)
+	}
+
+	// Streams without a source label get a default one, entries without a timestamp are stamped on receipt
+	if got[1].Source != unknownSource || got[1].LogLevel != utils.DEBUG || got[1].Timestamp != nil {
+		t.Errorf("ToLogs[1] = %+v", got[1])
+	}
+	if want := map[string]any{"detected_level": "debug", "filename": "/var/log/app.log"}; !reflect.DeepEqual(got[1].Attributes, want) {
+		t.Errorf("ToLogs[1] attributes = %v, want %v", got[1].Attributes, want)
+	}
+}
@@ -0,0 +1,55 @@
+package lql
+
+// Node is an expression in the query AST.
+type Node interface {
+	Position() int
+}
+
+// Field is the left hand side of a term. Path is only set for attribute fields.
+type Field struct {
+	Name string
+	Path []string
+	Pos  int
+}
+
+type BinaryExpr struct {
+	Op    TokenKind // And or Or
+	Left  Node
+	Right Node
+	Pos   int
+}
+
+type NotExpr struct {
+	Expr Node
+	Pos  int
+}
+
+// MatchExpr matches a field against a value. Unquoted values may contain the * and ? wildcards.
+type MatchExpr struct {
+	Field  Field
+	Value  string
+	Quoted bool
+	Pos    int
+}
+
+// CompareExpr is a one sided range such as time:>2024-01-01.
+type CompareExpr struct {
+	Field Field
+	Op    TokenKind // GT, GTE, LT or LTE
+	Value string
+	Pos   int
+}
+
+// RangeExpr is an inclusive range, From or To is "*" for an open end.
+type RangeExpr struct {
+	Field Field
+	From  string
+	To    string
+	Pos   int
+}
+
+func (e *BinaryExpr) Position() int  { return e.Pos }
+func (e *NotExpr) Position() int     { return e.Pos }
+func (e *MatchExpr) Position() int   { return e.Pos }
+func (e *CompareExpr) Position() int { return e.Pos }
+func (e *RangeExpr) Position() int   { return e.Pos }
@@ -0,0 +1,283 @@
+package lql
+
+import (
+	"strconv"
+	"strings"
+	"tikube-backend/logger-service/timerange"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// columns maps the searchable fields to their columns. Only these names are ever written into the
+// SQL text, every value coming from the query is bound as a parameter.
+var columns = map[string]string{
+	"level":       "logLevel",
+	"source":      "source",
+	"message":     "message",
+	"fingerprint": "fingerprint",
+	"traceId":     "traceId",
+	"spanId":      "spanId",
+	"time":        "createdAt",
+}
+
+// likeEscape is used instead of the backslash so the generated SQL does not depend on the sql_mode.
+const likeEscape = '!'
+
+type compiler struct {
+	sql  strings.Builder
+	args []any
+}
+
+// Compile turns an AST into a SQL condition with ? placeholders and the arguments to bind.
+func Compile(node Node) (string, []any, error) {
+	c := &compiler{}
+	if err := c.compile(node); err != nil {
+		return "", nil, err
+	}
+	return c.sql.String(), c.args, nil
+}
+
+// CompileQuery parses and compiles a query in one step.
+func CompileQuery(query string) (string, []any, error) {
+	node, err := Parse(query)
+	if err != nil {
+		return "", nil, err
+	}
+	return Compile(node)
+}
+
+func (c *compiler) compile(node Node) error {
+	switch n := node.(type) {
+	case *BinaryExpr:
+		op := " AND "
+		if n.Op == Or {
+			op = " OR "
+		}
+		c.sql.WriteString("(")
+		if err := c.compile(n.Left); err != nil {
+			return err
+		}
+		c.sql.WriteString(op)
+		if err := c.compile(n.Right); err != nil {
+			return err
+		}
+		c.sql.WriteString(")")
+		return nil
+	case *NotExpr:
+		c.sql.WriteString("NOT (")
+		if err := c.compile(n.Expr); err != nil {
+			return err
+		}
+		c.sql.WriteString(")")
+		return nil
+	case *MatchExpr:
+		return c.compileMatch(n)
+	case *CompareExpr:
+		return c.compileCompare(n.Field, n.Op, n.Value, n.Pos)
+	case *RangeExpr:
+		return c.compileRange(n)
+	default:
+		return errorAt(node.Position(), "unsupported expression")
+	}
+}
+
+func (c *compiler) compileMatch(n *MatchExpr) error {
+	if n.Field.Name == "time" {
+		return errorAt(n.Pos, "time needs a range or a comparison, e.g. time:[2024-01-01 TO *] or time:>2024-01-01")
+	}
+
+	// field:* only checks that the field is present. The columns are NOT NULL, an absent value is ''
+	if !n.Quoted && n.Value == "*" {
+		if n.Field.Name == "attributes" {
+			c.sql.WriteString("JSON_EXTRACT(attributes, ?) IS NOT NULL")
+			c.args = append(c.args, jsonPath(n.Field.Path))
+		} else {
+			c.sql.WriteString(columns[n.Field.Name] + " <> ''")
+		}
+		return nil
+	}
+
+	value := n.Value
+	wildcard := !n.Quoted && hasWildcard(value)
+	if !n.Quoted && !wildcard {
+		value = unescape(value)
+	}
+	if n.Field.Name == "traceId" || n.Field.Name == "spanId" {
+		value = strings.ToLower(value)
+	}
+	if n.Field.Name == "level" {
+		value = strings.ToUpper(value)
+		if level, ok := utils.ParseLogLevel(value); ok && !wildcard {
+			value = string(level)
+		}
+	}
+
+	c.writeColumn(n.Field)
+
+	// Messages are searched for the value anywhere in the text
+	if n.Field.Name == "message" {
+		pattern := "%" + escapeLike(value) + "%"
+		if wildcard {
+			pattern = "%" + wildcardToLike(value) + "%"
+		}
+		c.sql.WriteString(" LIKE ? ESCAPE '!'")
+		c.args = append(c.args, pattern)
+		return nil
+	}
+
+	if wildcard {
+		c.sql.WriteString(" LIKE ? ESCAPE '!'")
+		c.args = append(c.args, wildcardToLike(value))
+		return nil
+	}
+
+	c.sql.WriteString(" = ?")
+	c.args = append(c.args, value)
+	return nil
+}
+
+func (c *compiler) compileCompare(field Field, op TokenKind, value string, pos int) error {
+	arg, err := comparisonArg(field, value, pos)
+	if err != nil {
+		return err
+	}
+
+	c.writeComparable(field, arg)
+	switch op {
+	case GT:
+		c.sql.WriteString(" > ?")
+	case GTE:
+		c.sql.WriteString(" >= ?")
+	case LT:
+		c.sql.WriteString(" < ?")
+	case LTE:
+		c.sql.WriteString(" <= ?")
+	}
+	c.args = append(c.args, arg)
+	return nil
+}
+
+func (c *compiler) compileRange(n *RangeExpr) error {
+	if n.From == "*" && n.To == "*" {
+		return errorAt(n.Pos, "a range needs at least one bound")
+	}
+	if n.From == "*" {
+		return c.compileCompare(n.Field, LTE, n.To, n.Pos)
+	}
+	if n.To == "*" {
+		return c.compileCompare(n.Field, GTE, n.From, n.Pos)
+	}
+
+	c.sql.WriteString("(")
+	if err := c.compileCompare(n.Field, GTE, n.From, n.Pos); err != nil {
+		return err
+	}
+	c.sql.WriteString(" AND ")
+	if err := c.compileCompare(n.Field, LTE, n.To, n.Pos); err != nil {
+		return err
+	}
+	c.sql.WriteString(")")
+	return nil
+}
+
+func (c *compiler) writeColumn(field Field) {
+	if field.Name == "attributes" {
+		c.sql.WriteString("JSON_UNQUOTE(JSON_EXTRACT(attributes, ?))")
+		c.args = append(c.args, jsonPath(field.Path))
+		return
+	}
+	c.sql.WriteString(columns[field.Name])
+}
+
+// writeComparable compares numeric attribute values as numbers rather than strings.
+func (c *compiler) writeComparable(field Field, arg any) {
+	if _, numeric := arg.(float64); numeric && field.Name == "attributes" {
+		c.sql.WriteString("CAST(JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) AS DECIMAL(30,10))")
+		c.args = append(c.args, jsonPath(field.Path))
+		return
+	}
+	c.writeColumn(field)
+}
+
+func comparisonArg(field Field, value string, pos int) (any, error) {
+	switch field.Name {
+	case "time":
+		t, err := timerange.ParseTime(value, time.Now(), time.UTC)
+		if err != nil {
+			return nil, errorAt(pos, "%s", err.Error())
+		}
+		return t, nil
+	case "attributes":
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number, nil
+		}
+		return value, nil
+	default:
+		return nil, errorAt(pos, "field %s does not support ranges", field.Name)
+	}
+}
+
+// jsonPath quotes every segment. Segments were validated by the parser and can't contain quotes.
+func jsonPath(path []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range path {
+		sb.WriteString(`."` + segment + `"`)
+	}
+	return sb.String()
+}
+
+func hasWildcard(value string) bool {
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '*', '?':
+			return true
+		}
+	}
+	return false
+}
+
+func unescape(value string) string {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+		sb.WriteByte(value[i])
+	}
+	return sb.String()
+}
+
+func escapeLike(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		if r == '%' || r == '_' || r == likeEscape {
+			sb.WriteRune(likeEscape)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// wildcardToLike converts * and ? into LIKE wildcards, escaping everything else.
+func wildcardToLike(value string) string {
+	var sb strings.Builder
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			sb.WriteString(escapeLike(string(runes[i])))
+		case r == '*':
+			sb.WriteRune('%')
+		case r == '?':
+			sb.WriteRune('_')
+		default:
+			sb.WriteString(escapeLike(string(r)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,205 @@
+package lql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type TokenKind int
+
+const (
+	EOF TokenKind = iota
+	Word
+	Quoted
+	Colon
+	LParen
+	RParen
+	LBracket
+	RBracket
+	Minus
+	GT
+	GTE
+	LT
+	LTE
+	And
+	Or
+	Not
+	To
+)
+
+var tokenNames = map[TokenKind]string{
+	EOF:      "end of query",
+	Word:     "word",
+	Quoted:   "quoted string",
+	Colon:    "':'",
+	LParen:   "'('",
+	RParen:   "')'",
+	LBracket: "'['",
+	RBracket: "']'",
+	Minus:    "'-'",
+	GT:       "'>'",
+	GTE:      "'>='",
+	LT:       "'<'",
+	LTE:      "'<='",
+	And:      "AND",
+	Or:       "OR",
+	Not:      "NOT",
+	To:       "TO",
+}
+
+func (k TokenKind) String() string {
+	return tokenNames[k]
+}
+
+// Token is a lexical token. Pos is the 1-based column of its first character.
+type Token struct {
+	Kind  TokenKind
+	Value string
+	Pos   int
+}
+
+// Error is a syntax or semantic error in a query. Column is 1-based.
+type Error struct {
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("query error at column %d: %s", e.Column, e.Message)
+}
+
+func errorAt(column int, format string, args ...any) *Error {
+	return &Error{Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+// MaxQueryLength bounds the work done for a single query.
+const MaxQueryLength = 2048
+
+// Lex splits a query into tokens. Keywords are case-sensitive (AND, OR, NOT, TO) so that lowercase
+// words can still be searched for.
+func Lex(query string) ([]Token, error) {
+	if len(query) > MaxQueryLength {
+		return nil, errorAt(MaxQueryLength+1, "query is longer than %d characters", MaxQueryLength)
+	}
+
+	var tokens []Token
+	runes := []rune(query)
+	i := 0
+	for i < len(runes) {
+		c := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == ':':
+			tokens = append(tokens, Token{Colon, ":", pos})
+			i++
+		case c == '(':
+			tokens = append(tokens, Token{LParen, "(", pos})
+			i++
+		case c == ')':
+			tokens = append(tokens, Token{RParen, ")", pos})
+			i++
+		case c == '[':
+			tokens = append(tokens, Token{LBracket, "[", pos})
+			i++
+		case c == ']':
+			tokens = append(tokens, Token{RBracket, "]", pos})
+			i++
+		case c == '-' && (i == 0 || isBoundary(runes[i-1])):
+			// A leading minus negates the next term, inside a word it is a literal (e.g. 2024-01-01)
+			tokens = append(tokens, Token{Minus, "-", pos})
+			i++
+		case c == '>' || c == '<':
+			kind := GT
+			if c == '<' {
+				kind = LT
+			}
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				tokens = append(tokens, Token{kind + 1, string(c) + "=", pos})
+				i += 2
+			} else {
+				tokens = append(tokens, Token{kind, string(c), pos})
+				i++
+			}
+		case c == '"':
+			value, next, err := lexQuoted(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Quoted, value, pos})
+			i = next
+		default:
+			start := i
+			for i < len(runes) {
+				// A quoted segment of an attribute path is part of the word, e.g. attributes."service.name"
+				if runes[i] == '"' && i > start && runes[i-1] == '.' {
+					_, next, err := lexQuoted(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					i = next
+					continue
+				}
+				if isDelimiter(runes[i]) {
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				i++
+			}
+			word := string(runes[start:i])
+			tokens = append(tokens, Token{keywordKind(word), word, pos})
+		}
+	}
+
+	tokens = append(tokens, Token{EOF, "", len(runes) + 1})
+	return tokens, nil
+}
+
+func lexQuoted(runes []rune, start int) (string, int, error) {
+	var sb strings.Builder
+	i := start + 1
+	for i < len(runes) {
+		switch runes[i] {
+		case '\\':
+			if i+1 >= len(runes) {
+				return "", 0, errorAt(i+1, "unfinished escape sequence")
+			}
+			sb.WriteRune(runes[i+1])
+			i += 2
+		case '"':
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteRune(runes[i])
+			i++
+		}
+	}
+	return "", 0, errorAt(start+1, "unterminated quoted string")
+}
+
+func keywordKind(word string) TokenKind {
+	switch word {
+	case "AND", "&&":
+		return And
+	case "OR", "||":
+		return Or
+	case "NOT":
+		return Not
+	case "TO":
+		return To
+	}
+	return Word
+}
+
+// isDelimiter ends an unquoted word. '-', '>' and '<' only have a meaning at the start of a word.
+func isDelimiter(c rune) bool {
+	return unicode.IsSpace(c) || strings.ContainsRune(`:()[]"`, c)
+}
+
+func isBoundary(c rune) bool {
+	return unicode.IsSpace(c) || c == '('
+}
@@ -0,0 +1,288 @@
+package lql
+
+import (
+	"regexp"
+	"strings"
+	"tikube-backend/shared/utils"
+)
+
+// Limits that keep a single query cheap to parse and to execute.
+const (
+	maxDepth = 32
+	maxTerms = 100
+)
+
+// Fields that can be searched. Keys are lowercase, "attributes" (or "attr") takes a dotted path whose
+// segments can be quoted to contain dots, e.g. attributes."service.name".
+var fieldAliases = map[string]string{
+	"level":       "level",
+	"loglevel":    "level",
+	"source":      "source",
+	"message":     "message",
+	"msg":         "message",
+	"time":        "time",
+	"createdat":   "time",
+	"fingerprint": "fingerprint",
+	"traceid":     "traceId",
+	"trace_id":    "traceId",
+	"spanid":      "spanId",
+	"span_id":     "spanId",
+	"attributes":  "attributes",
+	"attr":        "attributes",
+}
+
+var attributePathSegment = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type parser struct {
+	tokens []Token
+	pos    int
+	depth  int
+	terms  int
+}
+
+// Parse turns a query into an AST.
+func Parse(query string) (Node, error) {
+	tokens, err := Lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().Kind == EOF {
+		return nil, errorAt(1, "query is empty")
+	}
+
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != EOF {
+		return nil, errorAt(tok.Pos, "unexpected %s", describe(tok))
+	}
+	return node, nil
+}
+
+func (p *parser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() Token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != EOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) expect(kind TokenKind) (Token, error) {
+	tok := p.next()
+	if tok.Kind != kind {
+		return tok, errorAt(tok.Pos, "expected %s but found %s", kind, describe(tok))
+	}
+	return tok, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().Kind == Or {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: Or, Left: left, Right: right, Pos: op.Pos}
+	}
+	return left, nil
+}
+
+// parseAnd also handles implicit AND between terms written next to each other.
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.Kind == And {
+			p.next()
+		} else if !startsTerm(tok.Kind) {
+			return left, nil
+		}
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: And, Left: left, Right: right, Pos: tok.Pos}
+	}
+}
+
+func (p *parser) parseNot() (Node, error) {
+	tok := p.peek()
+	if tok.Kind == Not || tok.Kind == Minus {
+		p.next()
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseNot()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: expr, Pos: tok.Pos}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.peek()
+
+	switch tok.Kind {
+	case LParen:
+		p.next()
+		if err := p.enter(tok); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		p.depth--
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RParen); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	case Word, Quoted:
+		return p.parseTerm()
+	default:
+		return nil, errorAt(tok.Pos, "unexpected %s", describe(tok))
+	}
+}
+
+func (p *parser) parseTerm() (Node, error) {
+	p.terms++
+	first := p.next()
+	if p.terms > maxTerms {
+		return nil, errorAt(first.Pos, "query has more than %d terms", maxTerms)
+	}
+
+	// A term without a field searches the message
+	if first.Kind == Quoted || p.peek().Kind != Colon {
+		field := Field{Name: "message", Pos: first.Pos}
+		return &MatchExpr{Field: field, Value: first.Value, Quoted: first.Kind == Quoted, Pos: first.Pos}, nil
+	}
+	p.next() // colon
+
+	field, err := resolveField(first)
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.next()
+	switch tok.Kind {
+	case Word, Quoted:
+		return &MatchExpr{Field: field, Value: tok.Value, Quoted: tok.Kind == Quoted, Pos: first.Pos}, nil
+	case GT, GTE, LT, LTE:
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		return &CompareExpr{Field: field, Op: tok.Kind, Value: value.Value, Pos: first.Pos}, nil
+	case LBracket:
+		from, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(To); err != nil {
+			return nil, err
+		}
+		to, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(RBracket); err != nil {
+			return nil, err
+		}
+		return &RangeExpr{Field: field, From: from.Value, To: to.Value, Pos: first.Pos}, nil
+	default:
+		return nil, errorAt(tok.Pos, "expected a value after '%s:' but found %s", first.Value, describe(tok))
+	}
+}
+
+func (p *parser) parseValue() (Token, error) {
+	tok := p.next()
+	if tok.Kind != Word && tok.Kind != Quoted {
+		return tok, errorAt(tok.Pos, "expected a value but found %s", describe(tok))
+	}
+	return tok, nil
+}
+
+func (p *parser) enter(tok Token) error {
+	p.depth++
+	if p.depth > maxDepth {
+		return errorAt(tok.Pos, "query is nested deeper than %d levels", maxDepth)
+	}
+	return nil
+}
+
+func resolveField(tok Token) (Field, error) {
+	name, path, _ := strings.Cut(tok.Value, ".")
+
+	canonical, ok := fieldAliases[strings.ToLower(name)]
+	if !ok {
+		return Field{}, errorAt(tok.Pos, "unknown field %q", tok.Value)
+	}
+
+	field := Field{Name: canonical, Pos: tok.Pos}
+	if canonical != "attributes" {
+		if path != "" {
+			return Field{}, errorAt(tok.Pos, "field %q has no sub fields", name)
+		}
+		return field, nil
+	}
+
+	if path == "" {
+		return Field{}, errorAt(tok.Pos, `attribute path is required, e.g. attributes.user_id or attributes."service.name"`)
+	}
+	for path != "" {
+		var segment string
+		if strings.HasPrefix(path, `"`) {
+			// Quoted segments are attribute keys, which may contain dots
+			end := strings.IndexByte(path[1:], '"') + 1
+			if end == 0 {
+				return Field{}, errorAt(tok.Pos, "unterminated attribute path segment")
+			}
+			segment, path = path[1:end], path[end+1:]
+			if !utils.ValidAttributeKey(segment) || (path != "" && path[0] != '.') {
+				return Field{}, errorAt(tok.Pos, "invalid attribute path segment %q", segment)
+			}
+			path = strings.TrimPrefix(path, ".")
+		} else {
+			segment, path, _ = strings.Cut(path, ".")
+			if !attributePathSegment.MatchString(segment) {
+				return Field{}, errorAt(tok.Pos, "invalid attribute path segment %q", segment)
+			}
+		}
+		field.Path = append(field.Path, segment)
+	}
+	return field, nil
+}
+
+func startsTerm(kind TokenKind) bool {
+	return kind == Word || kind == Quoted || kind == LParen || kind == Not || kind == Minus
+}
+
+func describe(tok Token) string {
+	if tok.Kind == Word {
+		return "'" + tok.Value + "'"
+	}
+	if tok.Kind == Quoted {
+		return "\"" + tok.Value + "\""
+	}
+	return tok.Kind.String()
+}
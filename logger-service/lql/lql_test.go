@@ -0,0 +1,239 @@
+package lql
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// seedQueries cover every construct of the grammar.
+var seedQueries = []string{
+	`level:ERROR AND source:PAYMENTS* AND NOT message:"timeout"`,
+	`message:timeout AND attributes.region:eu`,
+	`timeout`,
+	`"connection reset"`,
+	`level:error OR level:warn`,
+	`level:ERROR && (source:API || source:WEB)`,
+	`-source:LOGGER*`,
+	`NOT (level:DEBUG OR level:TRACE)`,
+	`source:PAY?ENTS`,
+	`message:50\*`,
+	`msg:"quote \" inside"`,
+	`attributes.user_id:*`,
+	`attributes."service.name":api`,
+	`traceId:*`,
+	`attr.http.status:[500 TO 599]`,
+	`attributes.duration:>1.5`,
+	`attributes.region:<=eu`,
+	`time:[2024-01-01 TO *]`,
+	`time:>2024-01-01`,
+	`time:[now-1h TO now]`,
+	`createdAt:<2024-01-01T10:00:00Z`,
+	`traceId:4BF92F3577B34DA6A3CE929D0E0E4736 spanId:00F067AA0BA902B7`,
+	`fingerprint:abc123`,
+	`level:`,
+	`unknown:value`,
+	`time:value`,
+	`(level:ERROR`,
+	`source:[a TO b]`,
+	`"unterminated`,
+	`attributes:x`,
+}
+
+// compiledFragments are the only pieces of text the compiler may write. Values never appear in the
+// SQL, they are bound to the ? placeholders.
+var compiledFragments = regexp.MustCompile(strings.Join([]string{
+	`CAST\(JSON_UNQUOTE\(JSON_EXTRACT\(attributes, \?\)\) AS DECIMAL\(30,10\)\)`,
+	`JSON_UNQUOTE\(JSON_EXTRACT\(attributes, \?\)\)`,
+	`JSON_EXTRACT\(attributes, \?\)`,
+	`\b(?:logLevel|source|message|fingerprint|traceId|spanId|createdAt)\b`,
+	`IS NOT NULL`,
+	`<> ''`,
+	`LIKE \? ESCAPE '!'`,
+	`[=<>]=? \?`,
+	`\b(?:AND|OR|NOT)\b`,
+	`[() ]`,
+}, "|"))
+
+func TestCompileQuery(t *testing.T) {
+	const status = `CAST(JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) AS DECIMAL(30,10))`
+
+	tests := []struct {
+		query string
+		sql   string
+		args  []any
+	}{
+		{
+			`level:ERROR AND source:PAYMENTS* AND NOT message:"timeout"`,
+			`((logLevel = ? AND source LIKE ? ESCAPE '!') AND NOT (message LIKE ? ESCAPE '!'))`,
+			[]any{"ERROR", "PAYMENTS%", "%timeout%"},
+		},
+		{`timeout`, `message LIKE ? ESCAPE '!'`, []any{"%timeout%"}},
+		{`"connection reset"`, `message LIKE ? ESCAPE '!'`, []any{"%connection reset%"}},
+		{`level:error`, `logLevel = ?`, []any{"ERROR"}},
+
+		// AND binds tighter than OR, terms next to each other are ANDed
+		{
+			`level:ERROR OR level:FATAL source:API`,
+			`(logLevel = ? OR (logLevel = ? AND source = ?))`,
+			[]any{"ERROR", "FATAL", "API"},
+		},
+		{
+			`(level:ERROR || level:FATAL) && source:API`,
+			`((logLevel = ? OR logLevel = ?) AND source = ?)`,
+			[]any{"ERROR", "FATAL", "API"},
+		},
+		{`NOT (level:DEBUG OR level:TRACE)`, `NOT ((logLevel = ? OR logLevel = ?))`, []any{"DEBUG", "TRACE"}},
+		{`-source:LOGGER* timeout`, `(NOT (source LIKE ? ESCAPE '!') AND message LIKE ? ESCAPE '!')`, []any{"LOGGER%", "%timeout%"}},
+
+		// Wildcards become LIKE patterns, everything else that LIKE would interpret is escaped
+		{`source:PAY?ENTS`, `source LIKE ? ESCAPE '!'`, []any{"PAY_ENTS"}},
+		{`source:a_b*`, `source LIKE ? ESCAPE '!'`, []any{"a!_b%"}},
+		{`source:a\*b*`, `source LIKE ? ESCAPE '!'`, []any{"a*b%"}},
+		{`message:50\*`, `message LIKE ? ESCAPE '!'`, []any{"%50*%"}},
+		{`message:100%_done!`, `message LIKE ? ESCAPE '!'`, []any{"%100!%!_done!!%"}},
+		{`msg:"quote \" inside"`, `message LIKE ? ESCAPE '!'`, []any{`%quote " inside%`}},
+		{`source:"a*b"`, `source = ?`, []any{"a*b"}},
+
+		// Presence checks, the columns hold '' rather than NULL
+		{`traceId:*`, `traceId <> ''`, nil},
+		{`fingerprint:*`, `fingerprint <> ''`, nil},
+		{`attributes.user_id:*`, `JSON_EXTRACT(attributes, ?) IS NOT NULL`, []any{`$."user_id"`}},
+
+		// Attribute paths, quoted segments are flat keys that contain dots
+		{`attributes.region:eu`, `JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) = ?`, []any{`$."region"`, "eu"}},
+		{`attr.http.method:GET`, `JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) = ?`, []any{`$."http"."method"`, "GET"}},
+		{`attributes."service.name":api`, `JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) = ?`, []any{`$."service.name"`, "api"}},
+		{`attributes."syslog.sd".origin:*`, `JSON_EXTRACT(attributes, ?) IS NOT NULL`, []any{`$."syslog.sd"."origin"`}},
+
+		// Ranges and comparisons
+		{
+			`attr.http.status:[500 TO 599]`,
+			`(` + status + ` >= ? AND ` + status + ` <= ?)`,
+			[]any{`$."http"."status"`, 500.0, `$."http"."status"`, 599.0},
+		},
+		{`attributes.duration:>1.5`, status + ` > ?`, []any{`$."duration"`, 1.5}},
+		{`attributes.region:<=eu`, `JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) <= ?`, []any{`$."region"`, "eu"}},
+		{`time:>2024-01-01`, `createdAt > ?`, []any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{`time:[2024-01-01 TO *]`, `createdAt >= ?`, []any{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}},
+		{`createdAt:<"2024-01-01T10:00:00Z"`, `createdAt < ?`, []any{time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}},
+
+		// Trace context ids are stored in lowercase
+		{`traceId:4BF92F3577B34DA6A3CE929D0E0E4736`, `traceId = ?`, []any{"4bf92f3577b34da6a3ce929d0e0e4736"}},
+	}
+
+	for _, tt := range tests {
+		sql, args, err := CompileQuery(tt.query)
+		if err != nil {
+			t.Errorf("CompileQuery(%q): %v", tt.query, err)
+			continue
+		}
+		if sql != tt.sql {
+			t.Errorf("CompileQuery(%q) sql\n got %s\nwant %s", tt.query, sql, tt.sql)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("CompileQuery(%q) args = %#v, want %#v", tt.query, args, tt.args)
+		}
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	tests := []struct {
+		query   string
+		column  int
+		message string
+	}{
+		{``, 1, "query is empty"},
+		{`level:`, 7, "expected a value after 'level:' but found end of query"},
+		{`level:ERROR AND`, 16, "unexpected end of query"},
+		{`level:ERROR AND OR source:API`, 17, "unexpected OR"},
+		{`(level:ERROR`, 13, "expected ')' but found end of query"},
+		{`level:ERROR)`, 12, "unexpected ')'"},
+		{`"unterminated`, 1, "unterminated quoted string"},
+		{`unknown:value`, 1, `unknown field "unknown"`},
+		{`source.x:value`, 1, `field "source" has no sub fields`},
+		{`attributes:x`, 1, `attribute path is required, e.g. attributes.user_id or attributes."service.name"`},
+		{`a attributes.$x:1`, 3, `invalid attribute path segment "$x"`},
+		{`attributes."a b":x`, 1, `invalid attribute path segment "a b"`},
+		{`attributes."service.name"x:1`, 1, `invalid attribute path segment "service.name"`},
+		{`attributes."open:x`, 12, "unterminated quoted string"},
+		{`level:ERROR time:value`, 13, "time needs a range or a comparison, e.g. time:[2024-01-01 TO *] or time:>2024-01-01"},
+		{`source:[a TO b]`, 1, "field source does not support ranges"},
+		{`time:[* TO *]`, 1, "a range needs at least one bound"},
+		{`time:[2024-01-01 b]`, 18, "expected TO but found 'b'"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := CompileQuery(tt.query)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) {
+			t.Errorf("CompileQuery(%q) error = %v, want a query error", tt.query, err)
+			continue
+		}
+		if queryErr.Column != tt.column || queryErr.Message != tt.message {
+			t.Errorf("CompileQuery(%q) error at column %d: %q, want column %d: %q", tt.query, queryErr.Column, queryErr.Message, tt.column, tt.message)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, query := range seedQueries {
+		f.Add(query)
+	}
+
+	f.Fuzz(func(t *testing.T, query string) {
+		node, err := Parse(query)
+		if err != nil {
+			checkError(t, query, err)
+			return
+		}
+		if node == nil {
+			t.Fatalf("Parse(%q) returned neither a node nor an error", query)
+		}
+	})
+}
+
+func FuzzCompileQuery(f *testing.F) {
+	for _, query := range seedQueries {
+		f.Add(query)
+	}
+
+	f.Fuzz(func(t *testing.T, query string) {
+		sql, args, err := CompileQuery(query)
+		if err != nil {
+			checkError(t, query, err)
+			return
+		}
+
+		if rest := compiledFragments.ReplaceAllString(sql, ""); rest != "" {
+			t.Fatalf("CompileQuery(%q) wrote %q into the SQL: %s", query, rest, sql)
+		}
+		if placeholders := strings.Count(sql, "?"); placeholders != len(args) {
+			t.Fatalf("CompileQuery(%q) has %d placeholders for %d arguments: %s", query, placeholders, len(args), sql)
+		}
+		if strings.Count(sql, "(") != strings.Count(sql, ")") {
+			t.Fatalf("CompileQuery(%q) has unbalanced parentheses: %s", query, sql)
+		}
+	})
+}
+
+// checkError verifies that errors are query errors pointing into the query, or just past its end.
+func checkError(t *testing.T, query string, err error) {
+	t.Helper()
+
+	var queryErr *Error
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("error for %q is not a query error: %v", query, err)
+	}
+	limit := utf8.RuneCountInString(query) + 1
+	if len(query) > MaxQueryLength {
+		limit = MaxQueryLength + 1
+	}
+	if queryErr.Column < 1 || queryErr.Column > limit {
+		t.Fatalf("error for %q is at column %d, outside of 1..%d: %v", query, queryErr.Column, limit, err)
+	}
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"tikube-backend/shared/utils"
+)
+
+type AlertRuleSchema struct {
+	Name            string           `json:"name"`
+	Levels          []utils.LogLevel `json:"levels"`
+	Source          string           `json:"source"`
+	Threshold       int              `json:"threshold"`
+	WindowSeconds   int              `json:"windowSeconds"`
+	CooldownSeconds int              `json:"cooldownSeconds"`
+	WebhookURL      string           `json:"webhookUrl"`
+	Enabled         *bool            `json:"enabled"`
+}
+
+func NewAlertRuleSchema() AlertRuleSchema {
+	return AlertRuleSchema{}
+}
+
+func (s AlertRuleSchema) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	for _, level := range s.Levels {
+		if _, ok := utils.ParseLogLevel(string(level)); !ok {
+			return errors.New("invalid log level " + string(level))
+		}
+	}
+
+	if strings.TrimSpace(s.Source) != "" {
+		if _, _, err := utils.ParseSourcePattern(s.Source); err != nil {
+			return err
+		}
+	}
+
+	if s.Threshold < 0 {
+		return errors.New("threshold must not be negative")
+	}
+
+	if s.WindowSeconds <= 0 || s.WindowSeconds > 86400 {
+		return errors.New("windowSeconds must be between 1 and 86400")
+	}
+
+	if s.CooldownSeconds < 0 {
+		return errors.New("cooldownSeconds must not be negative")
+	}
+
+	return utils.ValidateWebhookURL(s.WebhookURL)
+}
+
+// ToAlertRule converts the payload into a rule, enabling it unless told otherwise.
+func (s AlertRuleSchema) ToAlertRule() utils.AlertRule {
+	levels := make([]utils.LogLevel, len(s.Levels))
+	for i, level := range s.Levels {
+		levels[i], _ = utils.ParseLogLevel(string(level))
+	}
+
+	enabled := true
+	if s.Enabled != nil {
+		enabled = *s.Enabled
+	}
+
+	return utils.AlertRule{
+		Name:            strings.TrimSpace(s.Name),
+		Levels:          levels,
+		Source:          utils.NormalizeSource(s.Source),
+		Threshold:       s.Threshold,
+		WindowSeconds:   s.WindowSeconds,
+		CooldownSeconds: s.CooldownSeconds,
+		WebhookURL:      s.WebhookURL,
+		Enabled:         enabled,
+	}
+}
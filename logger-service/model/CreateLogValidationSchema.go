@@ -1,38 +1,89 @@
 package model
 
 import (
-	"errors"
+	"fmt"
+	"sort"
 	"strings"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/tracing"
 	"tikube-backend/shared/utils"
+	"time"
 )
 
 type CreateLogSchema struct {
-	LogLevel utils.LogLevel `json:"logLevel"`
-	Source   string         `json:"source"`
-	Message  string         `json:"message"`
+	LogLevel   utils.LogLevel `json:"logLevel"`
+	Source     string         `json:"source"`
+	Message    string         `json:"message"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	TraceId    string         `json:"traceId,omitempty"`   // W3C trace id, 32 hex characters
+	SpanId     string         `json:"spanId,omitempty"`    // W3C span id, 16 hex characters
+	Timestamp  *time.Time     `json:"timestamp,omitempty"` // when the event happened, defaults to the time it is stored
+}
+
+// AddAttribute sets key unless it is already set. Ingestion protocols use it to drop what Validate
+// would reject, an invalid key or an attribute beyond utils.MaxAttributes, rather than the whole record.
+func AddAttribute(attributes map[string]any, key string, value any) {
+	if len(attributes) >= utils.MaxAttributes || !utils.ValidAttributeKey(key) {
+		return
+	}
+	if _, exists := attributes[key]; !exists {
+		attributes[key] = value
+	}
 }
 
 func NewCreateLogSchema() CreateLogSchema {
 	return CreateLogSchema{}
 }
 
+// Validate checks every field and returns http_error.FieldErrors listing all the invalid ones.
 func (s CreateLogSchema) Validate() error {
-	// Validate LogLevel
-	if strings.ToUpper(string(s.LogLevel)) != "INFO" && strings.ToUpper(string(s.LogLevel)) != "WARN" && strings.ToUpper(string(s.LogLevel)) != "ERROR" && strings.ToUpper(string(s.LogLevel)) != "FATAL" {
-		return errors.New("invalid log level")
+	var errs http_error.FieldErrors
+
+	// Validate LogLevel, aliases such as "warning" are accepted and normalized on ingestion
+	if _, ok := utils.ParseLogLevel(string(s.LogLevel)); !ok {
+		errs = append(errs, http_error.FieldError{Field: "logLevel", Message: "invalid log level"})
 	}
 
 	// Validate Source
 	trimmedSource := strings.TrimSpace(s.Source)
 	if trimmedSource == "" {
-		return errors.New("source is required")
+		errs = append(errs, http_error.FieldError{Field: "source", Message: "source is required"})
 	}
 
 	// Validate Message
 	trimmedMessage := strings.TrimSpace(s.Message)
 	if trimmedMessage == "" {
-		return errors.New("message is required")
+		errs = append(errs, http_error.FieldError{Field: "message", Message: "message is required"})
+	}
+
+	// Validate Attributes, keys are sorted so the errors come in a stable order
+	if len(s.Attributes) > utils.MaxAttributes {
+		errs = append(errs, http_error.FieldError{Field: "attributes", Message: fmt.Sprintf("at most %d attributes are allowed", utils.MaxAttributes)})
+	}
+	keys := make([]string, 0, len(s.Attributes))
+	for key := range s.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !utils.ValidAttributeKey(key) {
+			errs = append(errs, http_error.FieldError{Field: "attributes", Message: fmt.Sprintf("invalid attribute key %q", key)})
+		}
 	}
 
+	// Validate trace context
+	if s.TraceId != "" && !tracing.IsValidTraceId(s.TraceId) {
+		errs = append(errs, http_error.FieldError{Field: "traceId", Message: "traceId must be 32 hex characters"})
+	}
+	if s.SpanId != "" && !tracing.IsValidSpanId(s.SpanId) {
+		errs = append(errs, http_error.FieldError{Field: "spanId", Message: "spanId must be 16 hex characters"})
+	}
+	if s.SpanId != "" && s.TraceId == "" {
+		errs = append(errs, http_error.FieldError{Field: "spanId", Message: "spanId requires a traceId"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
 	return nil
 }
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"errors"
+	"tikube-backend/logger-service/export"
+	"tikube-backend/logger-service/lql"
+	"tikube-backend/shared/utils"
+)
+
+type ExportJobSchema struct {
+	Format string          `json:"format"`
+	Filter utils.LogFilter `json:"filter"`
+	Limit  int             `json:"limit"` // 0 means the configured maximum
+}
+
+func NewExportJobSchema() ExportJobSchema {
+	return ExportJobSchema{}
+}
+
+func (s ExportJobSchema) Validate() error {
+	if _, err := export.ParseFormat(s.Format); err != nil {
+		return errors.New("format must be one of csv, ndjson, parquet")
+	}
+
+	if s.Limit < 0 {
+		return errors.New("limit must not be negative")
+	}
+
+	if s.Filter.DateFilter != nil {
+		from, to := s.Filter.DateFilter.From, s.Filter.DateFilter.To
+		if from == nil && to == nil {
+			return errors.New("filter.dateFilter needs from, to or both")
+		}
+		if from != nil && to != nil && to.Before(*from) {
+			return errors.New("filter.dateFilter.from must not be after to")
+		}
+	}
+
+	for _, pattern := range s.Filter.SourceFilter {
+		if _, _, err := utils.ParseSourcePattern(pattern); err != nil {
+			return errors.New("filter.sourceFilter: " + err.Error())
+		}
+	}
+
+	if s.Filter.MinLevel != "" {
+		if _, ok := utils.ParseLogLevel(string(s.Filter.MinLevel)); !ok {
+			return errors.New("filter.minLevel: unknown log level " + string(s.Filter.MinLevel))
+		}
+	}
+
+	if s.Filter.Query != "" {
+		if _, _, err := lql.CompileQuery(s.Filter.Query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"tikube-backend/logger-service/lql"
+	"tikube-backend/logger-service/timerange"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+type SavedSearchSchema struct {
+	Name      string                 `json:"name"`
+	Owner     string                 `json:"owner"`
+	Filter    utils.LogFilter        `json:"filter"`
+	Columns   []string               `json:"columns"`
+	TimeRange *utils.SearchTimeRange `json:"timeRange"`
+}
+
+func NewSavedSearchSchema() SavedSearchSchema {
+	return SavedSearchSchema{}
+}
+
+func (s SavedSearchSchema) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	if strings.TrimSpace(s.Owner) == "" {
+		return errors.New("owner is required")
+	}
+
+	if s.Filter.DateFilter != nil {
+		return errors.New("filter.dateFilter is not allowed, use timeRange")
+	}
+
+	for _, pattern := range s.Filter.SourceFilter {
+		if _, _, err := utils.ParseSourcePattern(pattern); err != nil {
+			return errors.New("filter.sourceFilter: " + err.Error())
+		}
+	}
+
+	if s.Filter.MinLevel != "" {
+		if _, ok := utils.ParseLogLevel(string(s.Filter.MinLevel)); !ok {
+			return errors.New("filter.minLevel: unknown log level " + string(s.Filter.MinLevel))
+		}
+	}
+
+	// Compiled rather than only parsed, so that e.g. an unknown field is reported when the search is
+	// saved instead of every time it runs
+	if s.Filter.Query != "" {
+		if _, _, err := lql.CompileQuery(s.Filter.Query); err != nil {
+			return err
+		}
+	}
+
+	for _, column := range s.Columns {
+		if !utils.IsLogColumn(column) {
+			return errors.New("unknown column " + column)
+		}
+	}
+
+	if s.TimeRange != nil {
+		if s.TimeRange.Relative != "" && (s.TimeRange.From != "" || s.TimeRange.To != "") {
+			return errors.New("timeRange must be either relative or absolute")
+		}
+		if _, err := ResolveTimeRange(*s.TimeRange, time.Now()); err != nil {
+			return fmt.Errorf("timeRange: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveTimeRange turns the time range of a saved search into dates. A relative range such as
+// "15m" or "7d" ends at now and uses the units of date_filter, it is the same as "now-15m,now".
+func ResolveTimeRange(timeRange utils.SearchTimeRange, now time.Time) (*utils.DateFilterRange, error) {
+	if timeRange.Relative != "" {
+		return timerange.Parse("now-"+timeRange.Relative+",now", now, time.UTC)
+	}
+	// Absolute ends may themselves be relative expressions such as now-1d/d
+	return timerange.Parse(timeRange.From+","+timeRange.To, now, time.UTC)
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"tikube-backend/shared/utils"
+)
+
+type UpdateIssueSchema struct {
+	Status utils.IssueStatus `json:"status"`
+}
+
+func NewUpdateIssueSchema() UpdateIssueSchema {
+	return UpdateIssueSchema{}
+}
+
+func (s UpdateIssueSchema) Validate() error {
+	switch utils.IssueStatus(strings.ToLower(string(s.Status))) {
+	case utils.IssueOpen, utils.IssueResolved, utils.IssueIgnored:
+		return nil
+	default:
+		return errors.New("status must be one of open, resolved, ignored")
+	}
+}
@@ -9,39 +9,112 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/redis/go-redis/v9"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"tikube-backend/logger-service/fluent"
+	"tikube-backend/logger-service/grpc_server"
 	"tikube-backend/logger-service/handler"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/redaction"
 	"tikube-backend/logger-service/repository"
 	"tikube-backend/logger-service/service"
+	"tikube-backend/logger-service/syslog"
 	"tikube-backend/shared/kafka_client"
 	"tikube-backend/shared/middleware"
+	"tikube-backend/shared/storage"
 	"tikube-backend/shared/utils"
 	"time"
 )
 
-func LoggerModule(router *mux.Router, db *sql.DB, redisCache *cache.Cache, rdb *redis.Client, limiter *redis_rate.Limiter, consumer sarama.ConsumerGroup, producer sarama.AsyncProducer) {
+// LoggerModule mounts the logger routes and starts the background workers. The returned listeners
+// are started by the caller next to the HTTP server.
+func LoggerModule(router *mux.Router, db *sql.DB, redisCache *cache.Cache, rdb *redis.Client, limiter *redis_rate.Limiter, consumer sarama.ConsumerGroup, producer sarama.AsyncProducer) []utils.Listener {
+
+	redactor, err := redaction.NewRedactorFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading redaction config: %v", err)
+	}
 
 	loggerRepository := repository.NewLoggerRepository(db, producer)
-	loggerService := service.NewLoggerService(loggerRepository, rdb, redisCache, producer)
+	issueRepository := repository.NewIssueRepository(db, producer)
+	issueService := service.NewIssueService(issueRepository, producer)
+	alertRepository := repository.NewAlertRepository(db, producer)
+	alertService := service.NewAlertService(alertRepository, rdb, producer, service.NewWebhookNotifier(producer))
+	loggerService := service.NewLoggerService(loggerRepository, rdb, redisCache, producer, redactor, issueService, alertService)
 	loggerHandler := handlers.NewLoggerController(loggerService, producer)
+	issueHandler := handlers.NewIssueController(issueService)
+	alertHandler := handlers.NewAlertController(alertService)
+	savedSearchRepository := repository.NewSavedSearchRepository(db, producer)
+	savedSearchService := service.NewSavedSearchService(savedSearchRepository, loggerService)
+	savedSearchHandler := handlers.NewSavedSearchController(savedSearchService)
+	exportService := service.NewExportService(loggerRepository, producer)
+	exportHandler := handlers.NewExportController(exportService)
+	otlpHandler := handlers.NewOtlpController(loggerService)
+	lokiHandler := handlers.NewLokiController(loggerService)
+	openApiHandler := handlers.NewOpenApiController()
+	exportDir := os.Getenv("EXPORT_STORAGE_DIR")
+	if exportDir == "" {
+		exportDir = "./exports"
+	}
+	exportStorage, err := storage.NewLocalStorage(exportDir)
+	if err != nil {
+		log.Fatalf("Error creating export storage: %v", err)
+	}
+	exportJobRepository := repository.NewExportJobRepository(db, producer)
+	exportJobService := service.NewExportJobService(exportJobRepository, loggerRepository, exportStorage, producer, exportService.MaxRows())
+	exportJobHandler := handlers.NewExportJobController(exportJobService)
+	syslogConfig, err := syslog.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading syslog config: %v", err)
+	}
+	syslogServer := syslog.NewServer(syslogConfig, loggerService)
+	syslogHandler := handlers.NewSyslogController(syslogServer)
+	fluentServer := fluent.NewServerFromEnv(loggerService)
 
-	loggerRouter := router.PathPrefix("/logger").Subrouter()
+	// Shared by the HTTP and gRPC APIs
+	limit := redis_rate.Limit{
+		Rate:   1000,
+		Burst:  100,
+		Period: time.Minute * 1,
+	}
+	rateLimit := shared_middleware.RateLimitMiddleware(limiter, limit)
+
+	grpcConfig, err := grpc_server.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading gRPC config: %v", err)
+	}
+	grpcServer := grpc_server.NewServer(grpcConfig, handlers.NewGrpcController(loggerService), limiter, limit)
 
-	loggerRouter.HandleFunc("/logs",
-		shared_middleware.ErrorHandlerMiddleware(shared_middleware.ChainMiddlewares(
-			loggerHandler.GetLogs,
-			shared_middleware.CorsMiddleware,
-			shared_middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   1000,
-				Burst:  100,
-				Period: time.Minute * 1,
-			}),
-			shared_middleware.LoggingMiddleware))).Methods("GET")
+	// handle wraps a handler with the route specific middlewares followed by the ones shared by every route.
+	// The access log wraps the whole router, see main.
+	handle := func(handler utils.HTTPHandler, middlewares ...utils.Middleware) http.HandlerFunc {
+		middlewares = append(middlewares, shared_middleware.CorsMiddleware, rateLimit, shared_middleware.TraceMiddleware)
+		return shared_middleware.ErrorHandlerMiddleware(shared_middleware.ChainMiddlewares(handler, middlewares...))
+	}
+
+	mountRoutes(router, routeHandlers{
+		logger:     loggerHandler,
+		issues:     issueHandler,
+		alerts:     alertHandler,
+		searches:   savedSearchHandler,
+		export:     exportHandler,
+		exportJobs: exportJobHandler,
+		otlp:       otlpHandler,
+		loki:       lokiHandler,
+		syslog:     syslogHandler,
+		openApi:    openApiHandler,
+	}, handle)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Keep alert rules in sync and send resolve notifications
+	go alertService.Run(ctx)
+
+	// Process asynchronous export jobs, including the ones interrupted by a restart
+	go exportJobService.Run(ctx)
+
 	// Start consuming messages in a goroutine for capturing log events
 	go func() {
 		defer func(consumer sarama.ConsumerGroup) {
@@ -70,4 +143,86 @@ func LoggerModule(router *mux.Router, db *sql.DB, redisCache *cache.Cache, rdb *
 		<-sigchan
 		cancel()
 	}()
+
+	return []utils.Listener{syslogServer, fluentServer, grpcServer}
+}
+
+// routeHandlers are the controllers behind the HTTP routes.
+type routeHandlers struct {
+	logger     *handlers.LoggerHandler
+	issues     *handlers.IssueHandler
+	alerts     *handlers.AlertHandler
+	searches   *handlers.SavedSearchHandler
+	export     *handlers.ExportHandler
+	exportJobs *handlers.ExportJobHandler
+	otlp       *handlers.OtlpHandler
+	loki       *handlers.LokiHandler
+	syslog     *handlers.SyslogHandler
+	openApi    *handlers.OpenApiHandler
+}
+
+// mountRoutes registers the HTTP routes, handle wraps each handler with its middlewares.
+func mountRoutes(router *mux.Router, h routeHandlers, handle func(utils.HTTPHandler, ...utils.Middleware) http.HandlerFunc) {
+	// API documentation, openapi.json has to describe every route mounted below, which the tests check
+	router.HandleFunc("/openapi.json", handle(h.openApi.GetSpec)).Methods("GET")
+	router.HandleFunc("/docs", handle(h.openApi.GetDocs)).Methods("GET")
+
+	// OTLP/HTTP receiver, the path is fixed by the protocol so it lives outside /logger
+	router.HandleFunc("/v1/logs", handle(h.otlp.ExportLogs)).Methods("POST")
+
+	// Loki compatible API for promtail and Grafana
+	lokiRouter := router.PathPrefix("/loki/api/v1").Subrouter()
+	lokiRouter.HandleFunc("/push", handle(h.loki.Push)).Methods("POST")
+	lokiRouter.HandleFunc("/query_range", handle(h.loki.QueryRange)).Methods("GET")
+	lokiRouter.HandleFunc("/labels", handle(h.loki.Labels)).Methods("GET")
+	lokiRouter.HandleFunc("/label/{name}/values", handle(h.loki.LabelValues)).Methods("GET")
+
+	loggerRouter := router.PathPrefix("/logger").Subrouter()
+
+	loggerRouter.HandleFunc("/logs", handle(h.logger.GetLogs)).Methods("GET")
+	loggerRouter.HandleFunc("/logs", handle(h.logger.IngestLogs)).Methods("POST")
+	loggerRouter.HandleFunc("/logs/histogram", handle(h.logger.GetHistogram)).Methods("GET")
+	loggerRouter.HandleFunc("/logs/facets", handle(h.logger.GetFacets)).Methods("GET")
+	loggerRouter.HandleFunc("/logs/tail", handle(h.logger.TailLogs)).Methods("GET")
+	loggerRouter.HandleFunc("/logs/{id:[0-9]+}", handle(h.logger.GetLog)).Methods("GET")
+	loggerRouter.HandleFunc("/logs/{id:[0-9]+}/context", handle(h.logger.GetLogContext)).Methods("GET")
+	loggerRouter.HandleFunc("/logs/export", handle(h.export.ExportLogs,
+		shared_middleware.WriteTimeoutMiddleware(30*time.Minute))).Methods("GET")
+
+	loggerRouter.HandleFunc("/sources", handle(h.logger.GetSources)).Methods("GET")
+	loggerRouter.HandleFunc("/traces/{traceId:[0-9a-fA-F]{32}}", handle(h.logger.GetTrace)).Methods("GET")
+
+	loggerRouter.HandleFunc("/exports", handle(h.exportJobs.CreateJob,
+		shared_middleware.PayloadValidationMiddleware(model.NewExportJobSchema))).Methods("POST")
+	loggerRouter.HandleFunc("/exports/{id:[0-9a-f]{32}}", handle(h.exportJobs.GetJob)).Methods("GET")
+	loggerRouter.HandleFunc("/exports/{id:[0-9a-f]{32}}/download", handle(h.exportJobs.DownloadJob,
+		shared_middleware.WriteTimeoutMiddleware(30*time.Minute))).Methods("GET")
+
+	loggerRouter.HandleFunc("/syslog/stats", handle(h.syslog.GetStats)).Methods("GET")
+
+	loggerRouter.HandleFunc("/issues", handle(h.issues.GetIssues)).Methods("GET")
+	loggerRouter.HandleFunc("/issues/{id:[0-9]+}", handle(h.issues.GetIssue)).Methods("GET")
+	loggerRouter.HandleFunc("/issues/{id:[0-9]+}", handle(h.issues.UpdateIssue,
+		shared_middleware.PayloadValidationMiddleware(model.NewUpdateIssueSchema))).Methods("PATCH")
+	loggerRouter.HandleFunc("/issues/{id:[0-9]+}/logs", handle(h.issues.GetIssueLogs)).Methods("GET")
+
+	loggerRouter.HandleFunc("/alerts", handle(h.alerts.GetRules)).Methods("GET")
+	loggerRouter.HandleFunc("/alerts", handle(h.alerts.CreateRule,
+		shared_middleware.PayloadValidationMiddleware(model.NewAlertRuleSchema))).Methods("POST")
+	loggerRouter.HandleFunc("/alerts/{id:[0-9]+}", handle(h.alerts.GetRule)).Methods("GET")
+	loggerRouter.HandleFunc("/alerts/{id:[0-9]+}", handle(h.alerts.UpdateRule,
+		shared_middleware.PayloadValidationMiddleware(model.NewAlertRuleSchema))).Methods("PUT")
+	loggerRouter.HandleFunc("/alerts/{id:[0-9]+}", handle(h.alerts.DeleteRule)).Methods("DELETE")
+	loggerRouter.HandleFunc("/alerts/{id:[0-9]+}/history", handle(h.alerts.GetHistory)).Methods("GET")
+
+	loggerRouter.HandleFunc("/searches", handle(h.searches.GetSearches)).Methods("GET")
+	loggerRouter.HandleFunc("/searches", handle(h.searches.CreateSearch,
+		shared_middleware.PayloadValidationMiddleware(model.NewSavedSearchSchema))).Methods("POST")
+	loggerRouter.HandleFunc("/searches/shared/{token}", handle(h.searches.ResolveShareToken)).Methods("GET")
+	loggerRouter.HandleFunc("/searches/{id:[0-9]+}", handle(h.searches.GetSearch)).Methods("GET")
+	loggerRouter.HandleFunc("/searches/{id:[0-9]+}", handle(h.searches.UpdateSearch,
+		shared_middleware.PayloadValidationMiddleware(model.NewSavedSearchSchema))).Methods("PUT")
+	loggerRouter.HandleFunc("/searches/{id:[0-9]+}", handle(h.searches.DeleteSearch)).Methods("DELETE")
+	loggerRouter.HandleFunc("/searches/{id:[0-9]+}/run", handle(h.searches.RunSearch)).Methods("GET")
+	loggerRouter.HandleFunc("/searches/{id:[0-9]+}/share", handle(h.searches.ShareSearch)).Methods("POST")
 }
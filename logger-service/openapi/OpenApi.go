@@ -0,0 +1,222 @@
+// Package openapi embeds the OpenAPI document of the HTTP API and renders a page listing its
+// operations. The document is written by hand, CheckRoutes keeps it in step with the routes that
+// are mounted.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// The docs page is self-contained, it loads no scripts or styles from elsewhere.
+//
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// Keys of a path item that are operations, the others hold shared parameters and descriptions.
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// document is the part of Spec read by this package.
+type document struct {
+	Info struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description"`
+	} `json:"info"`
+	Tags []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"tags"`
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+type operation struct {
+	Method      string
+	Path        string
+	Summary     string   `json:"summary"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+}
+
+func parseSpec() (*document, error) {
+	var doc document
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, fmt.Errorf("parsing openapi.json: %w", err)
+	}
+	return &doc, nil
+}
+
+// operations returns the operations of the document sorted by path, then in operationMethods order.
+func (doc *document) operations() ([]operation, error) {
+	paths := make([]string, 0, len(doc.Paths))
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var operations []operation
+	for _, path := range paths {
+		for _, method := range operationMethods {
+			raw, ok := doc.Paths[path][method]
+			if !ok {
+				continue
+			}
+			var op operation
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("parsing %s %s in openapi.json: %w", strings.ToUpper(method), path, err)
+			}
+			op.Method, op.Path = strings.ToUpper(method), path
+			operations = append(operations, op)
+		}
+	}
+	return operations, nil
+}
+
+// RenderDocs writes the docs page: the operations of Spec grouped by tag, with a link to the
+// document for tools such as Swagger UI or Postman.
+func RenderDocs(w io.Writer) error {
+	doc, err := parseSpec()
+	if err != nil {
+		return err
+	}
+	operations, err := doc.operations()
+	if err != nil {
+		return err
+	}
+
+	type section struct {
+		Name        string
+		Description string
+		Operations  []operation
+	}
+	sections := make([]section, 0, len(doc.Tags)+1)
+	index := map[string]int{}
+	for _, tag := range doc.Tags {
+		index[tag.Name] = len(sections)
+		sections = append(sections, section{Name: tag.Name, Description: tag.Description})
+	}
+	for _, op := range operations {
+		name := "other"
+		if len(op.Tags) > 0 {
+			name = op.Tags[0]
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(sections)
+			index[name] = i
+			sections = append(sections, section{Name: name})
+		}
+		sections[i].Operations = append(sections[i].Operations, op)
+	}
+
+	return docsTemplate.Execute(w, map[string]any{
+		"Title":       doc.Info.Title,
+		"Version":     doc.Info.Version,
+		"Description": doc.Info.Description,
+		"Sections":    sections,
+	})
+}
+
+// CheckRoutes compares the routes of router with the operations of Spec. It reports the routes
+// that have no operation and the operations that no route serves, so that a route can't be added
+// or removed without updating the document.
+func CheckRoutes(router *mux.Router) error {
+	doc, err := parseSpec()
+	if err != nil {
+		return err
+	}
+	operations, err := doc.operations()
+	if err != nil {
+		return err
+	}
+
+	documented := map[string]bool{}
+	for _, op := range operations {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	var problems []string
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		// Subrouters show up as routes without a handler
+		if route.GetHandler() == nil {
+			return nil
+		}
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		path := stripPatterns(template)
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			problems = append(problems, path+" does not restrict its methods")
+			return nil
+		}
+		for _, method := range methods {
+			operation := method + " " + path
+			if _, ok := documented[operation]; !ok {
+				problems = append(problems, operation+" is not documented")
+				continue
+			}
+			// Marked as served, whatever is left afterwards has no route
+			documented[operation] = false
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for operation, unserved := range documented {
+		if unserved {
+			problems = append(problems, operation+" is documented but not routed")
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("openapi.json is out of date: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// stripPatterns removes the patterns of the path variables, e.g. /logs/{id:[0-9]+} becomes
+// /logs/{id}, which is how OpenAPI writes them. Patterns may contain braces themselves.
+func stripPatterns(template string) string {
+	var b strings.Builder
+	depth := 0
+	skipping := false
+	for _, c := range template {
+		switch {
+		case c == '{':
+			depth++
+			if depth > 1 {
+				continue
+			}
+		case c == '}':
+			depth--
+			if depth > 0 {
+				continue
+			}
+			skipping = false
+		case c == ':' && depth == 1:
+			skipping = true
+			continue
+		case skipping:
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
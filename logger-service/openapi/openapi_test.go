@@ -0,0 +1,35 @@
+package openapi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRenderDocs(t *testing.T) {
+	var page bytes.Buffer
+	if err := RenderDocs(&page); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := parseSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	operations, err := doc.operations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range operations {
+		if !strings.Contains(page.String(), ">"+op.Path+"<") {
+			t.Errorf("the docs page does not list %s %s", op.Method, op.Path)
+		}
+	}
+
+	// Everything the page needs is inline
+	for _, external := range []string{"<script", "<link", "src=", "http://", "https://"} {
+		if strings.Contains(page.String(), external) {
+			t.Errorf("the docs page contains %q", external)
+		}
+	}
+}
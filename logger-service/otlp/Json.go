@@ -0,0 +1,174 @@
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"strconv"
+)
+
+// The OTLP JSON encoding differs from the canonical protobuf JSON mapping: trace and span ids are
+// hex strings instead of base64, so the request is decoded into these structs and converted.
+
+type jsonRequest struct {
+	ResourceLogs []jsonResourceLogs `json:"resourceLogs"`
+}
+
+type jsonResourceLogs struct {
+	Resource  jsonResource    `json:"resource"`
+	ScopeLogs []jsonScopeLogs `json:"scopeLogs"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonScopeLogs struct {
+	Scope      jsonScope       `json:"scope"`
+	LogRecords []jsonLogRecord `json:"logRecords"`
+}
+
+type jsonScope struct {
+	Name       string         `json:"name"`
+	Version    string         `json:"version"`
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         jsonUint64     `json:"timeUnixNano"`
+	ObservedTimeUnixNano jsonUint64     `json:"observedTimeUnixNano"`
+	SeverityNumber       int32          `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 *jsonAnyValue  `json:"body"`
+	Attributes           []jsonKeyValue `json:"attributes"`
+	TraceId              string         `json:"traceId"`
+	SpanId               string         `json:"spanId"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string     `json:"stringValue"`
+	BoolValue   *bool       `json:"boolValue"`
+	IntValue    *jsonUint64 `json:"intValue"`
+	DoubleValue *float64    `json:"doubleValue"`
+	BytesValue  *string     `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []jsonAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []jsonKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+// jsonUint64 accepts 64-bit integers both as JSON numbers and as strings, as OTLP allows.
+// Negative intValue attributes are kept in two's complement and converted back by the caller.
+type jsonUint64 uint64
+
+func (n *jsonUint64) UnmarshalJSON(data []byte) error {
+	data = bytes.Trim(data, `"`)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if data[0] == '-' {
+		value, err := strconv.ParseInt(string(data), 10, 64)
+		*n = jsonUint64(value)
+		return err
+	}
+	value, err := strconv.ParseUint(string(data), 10, 64)
+	*n = jsonUint64(value)
+	return err
+}
+
+// DecodeJSON decodes a request sent with the OTLP JSON encoding.
+func DecodeJSON(data []byte) (*collogspb.ExportLogsServiceRequest, error) {
+	var request jsonRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+
+	result := &collogspb.ExportLogsServiceRequest{}
+	for _, rl := range request.ResourceLogs {
+		resourceLogs := &logspb.ResourceLogs{Resource: &resourcepb.Resource{Attributes: keyValuesToProto(rl.Resource.Attributes)}}
+		for _, sl := range rl.ScopeLogs {
+			scopeLogs := &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{
+				Name:       sl.Scope.Name,
+				Version:    sl.Scope.Version,
+				Attributes: keyValuesToProto(sl.Scope.Attributes),
+			}}
+			for _, lr := range sl.LogRecords {
+				scopeLogs.LogRecords = append(scopeLogs.LogRecords, &logspb.LogRecord{
+					TimeUnixNano:         uint64(lr.TimeUnixNano),
+					ObservedTimeUnixNano: uint64(lr.ObservedTimeUnixNano),
+					SeverityNumber:       logspb.SeverityNumber(lr.SeverityNumber),
+					SeverityText:         lr.SeverityText,
+					Body:                 anyValueToProto(lr.Body),
+					Attributes:           keyValuesToProto(lr.Attributes),
+					TraceId:              decodeHexId(lr.TraceId),
+					SpanId:               decodeHexId(lr.SpanId),
+				})
+			}
+			resourceLogs.ScopeLogs = append(resourceLogs.ScopeLogs, scopeLogs)
+		}
+		result.ResourceLogs = append(result.ResourceLogs, resourceLogs)
+	}
+	return result, nil
+}
+
+func keyValuesToProto(values []jsonKeyValue) []*commonpb.KeyValue {
+	result := make([]*commonpb.KeyValue, 0, len(values))
+	for _, kv := range values {
+		kv := kv
+		result = append(result, &commonpb.KeyValue{Key: kv.Key, Value: anyValueToProto(&kv.Value)})
+	}
+	return result
+}
+
+func anyValueToProto(value *jsonAnyValue) *commonpb.AnyValue {
+	if value == nil {
+		return nil
+	}
+
+	switch {
+	case value.StringValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: *value.StringValue}}
+	case value.BoolValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: *value.BoolValue}}
+	case value.IntValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(*value.IntValue)}}
+	case value.DoubleValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: *value.DoubleValue}}
+	case value.BytesValue != nil:
+		data, _ := base64.StdEncoding.DecodeString(*value.BytesValue)
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: data}}
+	case value.ArrayValue != nil:
+		array := &commonpb.ArrayValue{}
+		for i := range value.ArrayValue.Values {
+			array.Values = append(array.Values, anyValueToProto(&value.ArrayValue.Values[i]))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: array}}
+	case value.KvlistValue != nil:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: keyValuesToProto(value.KvlistValue.Values)}}}
+	}
+	return &commonpb.AnyValue{}
+}
+
+// decodeHexId returns nil for malformed ids, the mapper then treats them as absent.
+func decodeHexId(id string) []byte {
+	if id == "" {
+		return nil
+	}
+	data, err := hex.DecodeString(id)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,158 @@
+// Package otlp converts OpenTelemetry OTLP log export requests into logs.
+package otlp
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// Used when the resource has no service.name, as the OpenTelemetry SDKs do.
+const unknownService = "unknown_service"
+
+// ToLogs flattens an export request into logs. The source is service.namespace:service.name, the
+// scope, resource and record attributes are merged with record attributes taking precedence.
+func ToLogs(request *collogspb.ExportLogsServiceRequest) []model.CreateLogSchema {
+	var logs []model.CreateLogSchema
+
+	for _, resourceLogs := range request.GetResourceLogs() {
+		resourceAttributes := resourceLogs.GetResource().GetAttributes()
+		source := sourceOf(resourceAttributes)
+
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			scope := scopeLogs.GetScope()
+			for _, record := range scopeLogs.GetLogRecords() {
+				log := model.CreateLogSchema{
+					LogLevel: levelOf(record),
+					Source:   source,
+					Message:  messageOf(record.GetBody()),
+				}
+
+				attributes := map[string]any{}
+				addAttributes(attributes, record.GetAttributes())
+				if scope.GetName() != "" {
+					model.AddAttribute(attributes, "otel.scope.name", scope.GetName())
+				}
+				if scope.GetVersion() != "" {
+					model.AddAttribute(attributes, "otel.scope.version", scope.GetVersion())
+				}
+				addAttributes(attributes, scope.GetAttributes())
+				addAttributes(attributes, resourceAttributes)
+				if len(attributes) > 0 {
+					log.Attributes = attributes
+				}
+
+				if len(record.GetTraceId()) == 16 {
+					log.TraceId = hex.EncodeToString(record.GetTraceId())
+					if len(record.GetSpanId()) == 8 {
+						log.SpanId = hex.EncodeToString(record.GetSpanId())
+					}
+				}
+
+				if ts := timestampOf(record); !ts.IsZero() {
+					log.Timestamp = &ts
+				}
+
+				logs = append(logs, log)
+			}
+		}
+	}
+	return logs
+}
+
+func sourceOf(resourceAttributes []*commonpb.KeyValue) string {
+	name, namespace := unknownService, ""
+	for _, kv := range resourceAttributes {
+		switch kv.GetKey() {
+		case "service.name":
+			if value := kv.GetValue().GetStringValue(); value != "" {
+				name = value
+			}
+		case "service.namespace":
+			namespace = kv.GetValue().GetStringValue()
+		}
+	}
+	if namespace != "" {
+		return namespace + utils.SourceSeparator + name
+	}
+	return name
+}
+
+// levelOf prefers the severity number and falls back to the severity text.
+func levelOf(record *logspb.LogRecord) utils.LogLevel {
+	if level, ok := utils.LogLevelFromSeverity(int(record.GetSeverityNumber())); ok {
+		return level
+	}
+	if level, ok := utils.ParseLogLevel(record.GetSeverityText()); ok {
+		return level
+	}
+	return utils.INFO
+}
+
+// messageOf uses string bodies as they are and encodes structured bodies as JSON.
+func messageOf(body *commonpb.AnyValue) string {
+	if body == nil {
+		return ""
+	}
+	if value, ok := body.GetValue().(*commonpb.AnyValue_StringValue); ok {
+		return value.StringValue
+	}
+	data, err := json.Marshal(anyValue(body))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// timestampOf uses the event time, or the time the collector observed the record when unset.
+func timestampOf(record *logspb.LogRecord) time.Time {
+	nanos := record.GetTimeUnixNano()
+	if nanos == 0 {
+		nanos = record.GetObservedTimeUnixNano()
+	}
+	if nanos == 0 || nanos > uint64(1<<63-1) {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(nanos)).UTC()
+}
+
+// addAttributes keeps values that are already set, so earlier (more specific) attributes win.
+func addAttributes(attributes map[string]any, values []*commonpb.KeyValue) {
+	for _, kv := range values {
+		model.AddAttribute(attributes, kv.GetKey(), anyValue(kv.GetValue()))
+	}
+}
+
+func anyValue(value *commonpb.AnyValue) any {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, len(v.ArrayValue.GetValues()))
+		for i, item := range v.ArrayValue.GetValues() {
+			values[i] = anyValue(item)
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.GetKey()] = anyValue(kv.GetValue())
+		}
+		return values
+	}
+	return nil
+}
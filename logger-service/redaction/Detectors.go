@@ -0,0 +1,142 @@
+package redaction
+
+import (
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Built-in detector names. They are also the keys used in the "detectors"
+// section of the redaction config and in the per-rule redaction counters.
+const (
+	EmailDetector      = "email"
+	CreditCardDetector = "credit_card"
+	IPv4Detector       = "ipv4"
+	IPv6Detector       = "ipv6"
+	JWTDetector        = "jwt"
+	APIKeyDetector     = "api_key"
+)
+
+// builtinDetectors returns the built-in rules in the order they are applied.
+// JWTs and API keys run first so that their dotted segments are not partially
+// consumed by the IP or email detectors.
+func builtinDetectors() []Rule {
+	return []Rule{
+		{
+			Name:    JWTDetector,
+			pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		},
+		{
+			Name:    APIKeyDetector,
+			pattern: regexp.MustCompile(`\b(?:(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_\-]{35})\b`),
+		},
+		{
+			// key=value style secrets: only the value (first capture group) is redacted
+			Name:    APIKeyDetector,
+			pattern: regexp.MustCompile(`(?i)\b(?:api[_-]?key|secret|access[_-]?token|auth[_-]?token|password)["']?\s*[:=]\s*["']?([A-Za-z0-9_\-.+/=]{8,})`),
+		},
+		{
+			Name:    EmailDetector,
+			pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		},
+		{
+			Name:     CreditCardDetector,
+			pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+			validate: isCardNumber,
+		},
+		{
+			// Runs of hex groups and colons that are not part of a longer word, the first alternative
+			// keeps an embedded IPv4 tail (::ffff:192.0.2.1) in the match. net.ParseIP decides whether
+			// the run really is an address, which rules out times such as 12:30:45.
+			Name:     IPv6Detector,
+			pattern:  regexp.MustCompile(`(?i)(?:\b[0-9a-f]|\B:)[0-9a-f:]*:(?:\d{1,3}\.){3}\d{1,3}\b|(?:\b[0-9a-f]|\B:)[0-9a-f:]*(?:[0-9a-f]\b|:\B)`),
+			validate: isIPv6,
+		},
+		{
+			Name:    IPv4Detector,
+			pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+		},
+	}
+}
+
+// cardRanges are the IIN prefixes of the major card networks with the lengths they issue. They
+// tell unformatted card numbers apart from other long numbers, such as epoch milliseconds.
+var cardRanges = []struct {
+	low, high      int // range of the leading digits, both bounds have the same number of digits
+	minLen, maxLen int
+}{
+	{4, 4, 13, 19},       // Visa
+	{34, 34, 15, 15},     // American Express
+	{37, 37, 15, 15},     // American Express
+	{51, 55, 16, 16},     // Mastercard
+	{2221, 2720, 16, 16}, // Mastercard
+	{6011, 6011, 16, 19}, // Discover
+	{644, 649, 16, 19},   // Discover
+	{65, 65, 16, 19},     // Discover
+	{3528, 3589, 16, 19}, // JCB
+	{300, 305, 14, 19},   // Diners Club
+	{36, 36, 14, 19},     // Diners Club
+	{38, 39, 14, 19},     // Diners Club
+	{62, 62, 16, 19},     // UnionPay
+}
+
+// isCardNumber reports whether value passes the Luhn checksum and either is written with
+// separators, as card numbers usually are, or starts with the IIN prefix of a card network.
+func isCardNumber(value string) bool {
+	if !isLuhnValid(value) {
+		return false
+	}
+	if strings.ContainsAny(value, " -") {
+		return true
+	}
+	return hasCardPrefix(value)
+}
+
+func hasCardPrefix(digits string) bool {
+	for _, r := range cardRanges {
+		size := len(strconv.Itoa(r.low))
+		if len(digits) < r.minLen || len(digits) > r.maxLen {
+			continue
+		}
+		prefix, err := strconv.Atoi(digits[:size])
+		if err == nil && prefix >= r.low && prefix <= r.high {
+			return true
+		}
+	}
+	return false
+}
+
+// isLuhnValid reports whether the digits in value (ignoring spaces and dashes)
+// form a 13 to 19 digit number that passes the Luhn checksum.
+func isLuhnValid(value string) bool {
+	var digits []int
+	for _, c := range value {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+func isIPv6(value string) bool {
+	return strings.Contains(value, ":") && net.ParseIP(value) != nil
+}
@@ -0,0 +1,211 @@
+package redaction
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type Action string
+
+const (
+	Mask Action = "mask"
+	Hash Action = "hash"
+	Drop Action = "drop"
+	None Action = "none" // disables a built-in detector
+)
+
+// Rule is a single redaction rule. When the pattern has a capture group only the
+// first group is redacted, otherwise the whole match is.
+type Rule struct {
+	Name     string
+	Action   Action
+	Sources  []string // empty means the rule applies to every source
+	pattern  *regexp.Regexp
+	validate func(string) bool
+}
+
+// RuleConfig is the JSON representation of a custom rule.
+type RuleConfig struct {
+	Name    string   `json:"name"`
+	Pattern string   `json:"pattern"`
+	Action  Action   `json:"action"`
+	Sources []string `json:"sources"`
+}
+
+// Config is the JSON document pointed to by the REDACTION_CONFIG environment variable.
+type Config struct {
+	Salt      string            `json:"salt"`
+	Detectors map[string]Action `json:"detectors"`
+	Rules     []RuleConfig      `json:"rules"`
+}
+
+// Result describes what happened to a single log entry.
+type Result struct {
+	Message string
+	Dropped bool
+	Counts  map[string]int // number of redactions per rule name
+}
+
+type Redactor struct {
+	rules []Rule
+	salt  []byte
+}
+
+// NewRedactor builds a Redactor from config. Built-in detectors default to Mask
+// unless the config overrides them.
+func NewRedactor(config Config) (*Redactor, error) {
+	var rules []Rule
+
+	for _, detector := range builtinDetectors() {
+		detector.Action = Mask
+		if action, ok := config.Detectors[detector.Name]; ok {
+			detector.Action = action
+		}
+		if detector.Action == None {
+			continue
+		}
+		if !isValidAction(detector.Action) {
+			return nil, fmt.Errorf("invalid action %q for detector %s", detector.Action, detector.Name)
+		}
+		rules = append(rules, detector)
+	}
+
+	for _, rc := range config.Rules {
+		if strings.TrimSpace(rc.Name) == "" {
+			return nil, errors.New("redaction rule name is required")
+		}
+		if !isValidAction(rc.Action) {
+			return nil, fmt.Errorf("invalid action %q for rule %s", rc.Action, rc.Name)
+		}
+		pattern, err := regexp.Compile(rc.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for rule %s: %w", rc.Name, err)
+		}
+		rules = append(rules, Rule{Name: rc.Name, Action: rc.Action, Sources: rc.Sources, pattern: pattern})
+	}
+
+	salt := config.Salt
+	if envSalt := os.Getenv("REDACTION_SALT"); envSalt != "" {
+		salt = envSalt
+	}
+	if salt == "" && hasAction(rules, Hash) {
+		return nil, errors.New("a salt is required when a rule uses the hash action")
+	}
+
+	return &Redactor{rules: rules, salt: []byte(salt)}, nil
+}
+
+// NewRedactorFromEnv loads the config file referenced by REDACTION_CONFIG. When the
+// variable is unset every built-in detector is enabled with the mask action.
+func NewRedactorFromEnv() (*Redactor, error) {
+	var config Config
+
+	if path := os.Getenv("REDACTION_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewRedactor(config)
+}
+
+// Redact applies every rule that matches source to message. As soon as a Drop rule
+// matches the entry is marked as dropped and no further rules are applied.
+func (r *Redactor) Redact(source string, message string) Result {
+	result := Result{Message: message, Counts: map[string]int{}}
+
+	for _, rule := range r.rules {
+		if !rule.appliesTo(source) {
+			continue
+		}
+
+		redacted, count := r.apply(rule, result.Message)
+		if count == 0 {
+			continue
+		}
+
+		result.Counts[rule.Name] += count
+		if rule.Action == Drop {
+			result.Dropped = true
+			return result
+		}
+		result.Message = redacted
+	}
+
+	return result
+}
+
+func (r *Redactor) apply(rule Rule, value string) (string, int) {
+	matches := rule.pattern.FindAllStringSubmatchIndex(value, -1)
+	if len(matches) == 0 {
+		return value, 0
+	}
+
+	var sb strings.Builder
+	count := 0
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+
+		match := value[start:end]
+		if rule.validate != nil && !rule.validate(match) {
+			continue
+		}
+
+		sb.WriteString(value[last:start])
+		sb.WriteString(r.replacement(rule, match))
+		last = end
+		count++
+	}
+	sb.WriteString(value[last:])
+
+	return sb.String(), count
+}
+
+func (r *Redactor) replacement(rule Rule, match string) string {
+	if rule.Action == Hash {
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(match))
+		return fmt.Sprintf("[%s:%s]", rule.Name, hex.EncodeToString(mac.Sum(nil))[:16])
+	}
+	return fmt.Sprintf("[REDACTED:%s]", rule.Name)
+}
+
+func (rule Rule) appliesTo(source string) bool {
+	if len(rule.Sources) == 0 {
+		return true
+	}
+	for _, s := range rule.Sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidAction(action Action) bool {
+	return action == Mask || action == Hash || action == Drop
+}
+
+func hasAction(rules []Rule, action Action) bool {
+	for _, rule := range rules {
+		if rule.Action == action {
+			return true
+		}
+	}
+	return false
+}
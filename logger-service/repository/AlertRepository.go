@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/IBM/sarama"
+	"strings"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+)
+
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule utils.AlertRule) (*utils.AlertRule, error)
+	GetRules(ctx context.Context) ([]utils.AlertRule, error)
+	GetRule(ctx context.Context, id int64) (*utils.AlertRule, error)
+	UpdateRule(ctx context.Context, id int64, rule utils.AlertRule) (*utils.AlertRule, error)
+	DeleteRule(ctx context.Context, id int64) error
+	CreateEvent(ctx context.Context, event utils.AlertEvent) error
+	GetEvents(ctx context.Context, ruleId int64, pagination utils.Pagination) (*utils.PaginationResult[utils.AlertEvent], error)
+}
+
+type SQLAlertRepository struct {
+	db       *sql.DB
+	producer sarama.AsyncProducer
+}
+
+func NewAlertRepository(db *sql.DB, producer sarama.AsyncProducer) AlertRepository {
+	return &SQLAlertRepository{db: db, producer: producer}
+}
+
+const alertRuleColumns = "id, name, levels, source, threshold, windowSeconds, cooldownSeconds, webhookUrl, enabled, createdAt, updatedAt"
+
+func (repo *SQLAlertRepository) CreateRule(ctx context.Context, rule utils.AlertRule) (*utils.AlertRule, error) {
+	query := `INSERT INTO alert_rules (name, levels, source, threshold, windowSeconds, cooldownSeconds, webhookUrl, enabled)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := repo.db.ExecContext(ctx, query, rule.Name, joinLevels(rule.Levels), rule.Source, rule.Threshold, rule.WindowSeconds, rule.CooldownSeconds, rule.WebhookURL, rule.Enabled)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return repo.GetRule(ctx, id)
+}
+
+func (repo *SQLAlertRepository) GetRules(ctx context.Context) ([]utils.AlertRule, error) {
+	rows, err := repo.db.QueryContext(ctx, "SELECT "+alertRuleColumns+" FROM alert_rules ORDER BY id")
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	rules := []utils.AlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		rules = append(rules, *rule)
+	}
+	if err := rows.Err(); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (repo *SQLAlertRepository) GetRule(ctx context.Context, id int64) (*utils.AlertRule, error) {
+	rule, err := scanAlertRule(repo.db.QueryRowContext(ctx, "SELECT "+alertRuleColumns+" FROM alert_rules WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (repo *SQLAlertRepository) UpdateRule(ctx context.Context, id int64, rule utils.AlertRule) (*utils.AlertRule, error) {
+	if _, err := repo.GetRule(ctx, id); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE alert_rules SET name = ?, levels = ?, source = ?, threshold = ?, windowSeconds = ?, cooldownSeconds = ?, webhookUrl = ?, enabled = ?
+		WHERE id = ?`
+
+	_, err := repo.db.ExecContext(ctx, query, rule.Name, joinLevels(rule.Levels), rule.Source, rule.Threshold, rule.WindowSeconds, rule.CooldownSeconds, rule.WebhookURL, rule.Enabled, id)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return repo.GetRule(ctx, id)
+}
+
+func (repo *SQLAlertRepository) DeleteRule(ctx context.Context, id int64) error {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM alert_rules WHERE id = ?", id)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (repo *SQLAlertRepository) CreateEvent(ctx context.Context, event utils.AlertEvent) error {
+	query := `INSERT INTO alert_history (ruleId, state, count, message) VALUES (?, ?, ?, ?)`
+
+	_, err := repo.db.ExecContext(ctx, query, event.RuleId, event.State, event.Count, event.Message)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// GetEvents returns the alert history of a rule, newest first.
+func (repo *SQLAlertRepository) GetEvents(ctx context.Context, ruleId int64, pagination utils.Pagination) (*utils.PaginationResult[utils.AlertEvent], error) {
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM alert_history WHERE ruleId = ?", ruleId).Scan(&total); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	query := `SELECT id, ruleId, state, count, message, createdAt FROM alert_history
+		WHERE ruleId = ? ORDER BY createdAt DESC, id DESC LIMIT ? OFFSET ?`
+	rows, err := repo.db.QueryContext(ctx, query, ruleId, pagination.Limit, pagination.Offset)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	events := []utils.AlertEvent{}
+	for rows.Next() {
+		var event utils.AlertEvent
+		if err := rows.Scan(&event.Id, &event.RuleId, &event.State, &event.Count, &event.Message, &event.CreatedAt); err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return &utils.PaginationResult[utils.AlertEvent]{Data: events, Total: total}, nil
+}
+
+func (repo *SQLAlertRepository) reportError(ctx context.Context, err error) {
+	msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+}
+
+func scanAlertRule(row rowScanner) (*utils.AlertRule, error) {
+	var rule utils.AlertRule
+	var levels string
+	err := row.Scan(&rule.Id, &rule.Name, &levels, &rule.Source, &rule.Threshold, &rule.WindowSeconds, &rule.CooldownSeconds, &rule.WebhookURL, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Levels = []utils.LogLevel{}
+	if levels != "" {
+		for _, level := range strings.Split(levels, ",") {
+			rule.Levels = append(rule.Levels, utils.LogLevel(level))
+		}
+	}
+	return &rule, nil
+}
+
+func joinLevels(levels []utils.LogLevel) string {
+	parts := make([]string, len(levels))
+	for i, level := range levels {
+		parts[i] = string(level)
+	}
+	return strings.Join(parts, ",")
+}
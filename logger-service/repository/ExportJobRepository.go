@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/IBM/sarama"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+type ExportJobRepository interface {
+	CreateJob(ctx context.Context, job utils.ExportJob) error
+	GetJob(ctx context.Context, id string) (*utils.ExportJob, error)
+	ClaimJob(ctx context.Context, id string, owner string) (bool, error)
+	RenewLease(ctx context.Context, id string, owner string) error
+	SaveProgress(ctx context.Context, id string, owner string, rowsWritten int64, lastLogId int64, sizeBytes int64) error
+	CompleteJob(ctx context.Context, id string, owner string, rowsWritten int64, sizeBytes int64, expiresAt time.Time) error
+	FailJob(ctx context.Context, id string, owner string, message string) error
+	RequeueJob(ctx context.Context, id string, owner string) error
+	RequeueStaleJobs(ctx context.Context, staleAfter time.Duration) (int64, error)
+	GetJobIdsByStatus(ctx context.Context, status utils.ExportStatus, limit int) ([]string, error)
+	GetExpiredJobIds(ctx context.Context, now time.Time, limit int) ([]string, error)
+	ExpireJob(ctx context.Context, id string) error
+}
+
+// ErrLeaseLost is returned when a running job is no longer owned by the caller, because it was
+// requeued as stale and possibly claimed by another worker. The caller must stop touching the job.
+var ErrLeaseLost = errors.New("export job lease lost")
+
+type SQLExportJobRepository struct {
+	db       *sql.DB
+	producer sarama.AsyncProducer
+}
+
+func NewExportJobRepository(db *sql.DB, producer sarama.AsyncProducer) ExportJobRepository {
+	return &SQLExportJobRepository{db: db, producer: producer}
+}
+
+const exportJobColumns = "id, format, filter, maxRows, status, rowsWritten, lastLogId, sizeBytes, error, createdAt, updatedAt, completedAt, expiresAt"
+
+func (repo *SQLExportJobRepository) CreateJob(ctx context.Context, job utils.ExportJob) error {
+	filter, err := json.Marshal(job.Filter)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO export_jobs (id, format, filter, maxRows, status) VALUES (?, ?, ?, ?, ?)`
+	if _, err := repo.db.ExecContext(ctx, query, job.Id, job.Format, string(filter), job.MaxRows, utils.ExportQueued); err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	return nil
+}
+
+func (repo *SQLExportJobRepository) GetJob(ctx context.Context, id string) (*utils.ExportJob, error) {
+	var job utils.ExportJob
+	var filter string
+	var jobError sql.NullString
+	var completedAt, expiresAt sql.NullTime
+
+	err := repo.db.QueryRowContext(ctx, "SELECT "+exportJobColumns+" FROM export_jobs WHERE id = ?", id).Scan(
+		&job.Id, &job.Format, &filter, &job.MaxRows, &job.Status, &job.RowsWritten, &job.LastLogId, &job.SizeBytes,
+		&jobError, &job.CreatedAt, &job.UpdatedAt, &completedAt, &expiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(filter), &job.Filter); err != nil {
+		return nil, err
+	}
+	job.Error = jobError.String
+	if completedAt.Valid {
+		job.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		job.ExpiresAt = &expiresAt.Time
+	}
+	return &job, nil
+}
+
+// ClaimJob moves a queued job to running and records owner as its lease holder. Only one worker,
+// across all instances, can claim a job; every later update of the job must be made by the owner.
+func (repo *SQLExportJobRepository) ClaimJob(ctx context.Context, id string, owner string) (bool, error) {
+	query := `UPDATE export_jobs SET status = 'running', claimedBy = ?, updatedAt = CURRENT_TIMESTAMP WHERE id = ? AND status = 'queued'`
+	return repo.execAffected(ctx, query, owner, id)
+}
+
+// RenewLease is the heartbeat that keeps a running job from being considered stale.
+func (repo *SQLExportJobRepository) RenewLease(ctx context.Context, id string, owner string) error {
+	return repo.execOwned(ctx, id, owner, `UPDATE export_jobs SET updatedAt = CURRENT_TIMESTAMP WHERE id = ? AND status = 'running' AND claimedBy = ?`)
+}
+
+// SaveProgress records a checkpoint, it renews the lease as well.
+func (repo *SQLExportJobRepository) SaveProgress(ctx context.Context, id string, owner string, rowsWritten int64, lastLogId int64, sizeBytes int64) error {
+	query := `UPDATE export_jobs SET rowsWritten = ?, lastLogId = ?, sizeBytes = ?, updatedAt = CURRENT_TIMESTAMP WHERE id = ? AND status = 'running' AND claimedBy = ?`
+	return repo.execOwned(ctx, id, owner, query, rowsWritten, lastLogId, sizeBytes)
+}
+
+func (repo *SQLExportJobRepository) CompleteJob(ctx context.Context, id string, owner string, rowsWritten int64, sizeBytes int64, expiresAt time.Time) error {
+	query := `UPDATE export_jobs SET status = 'completed', claimedBy = NULL, rowsWritten = ?, sizeBytes = ?, completedAt = CURRENT_TIMESTAMP, expiresAt = ? WHERE id = ? AND status = 'running' AND claimedBy = ?`
+	return repo.execOwned(ctx, id, owner, query, rowsWritten, sizeBytes, expiresAt.UTC())
+}
+
+func (repo *SQLExportJobRepository) FailJob(ctx context.Context, id string, owner string, message string) error {
+	query := `UPDATE export_jobs SET status = 'failed', claimedBy = NULL, error = ? WHERE id = ? AND status = 'running' AND claimedBy = ?`
+	return repo.execOwned(ctx, id, owner, query, message)
+}
+
+func (repo *SQLExportJobRepository) RequeueJob(ctx context.Context, id string, owner string) error {
+	return repo.execOwned(ctx, id, owner, `UPDATE export_jobs SET status = 'queued', claimedBy = NULL WHERE id = ? AND status = 'running' AND claimedBy = ?`)
+}
+
+// RequeueStaleJobs puts running jobs whose worker stopped renewing its lease back in the queue. The
+// age is computed by MySQL so that it is compared with updatedAt in the same clock and time zone.
+func (repo *SQLExportJobRepository) RequeueStaleJobs(ctx context.Context, staleAfter time.Duration) (int64, error) {
+	query := `UPDATE export_jobs SET status = 'queued', claimedBy = NULL WHERE status = 'running' AND updatedAt < NOW() - INTERVAL ? SECOND`
+	result, err := repo.db.ExecContext(ctx, query, int64(staleAfter/time.Second))
+	if err != nil {
+		repo.reportError(ctx, err)
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (repo *SQLExportJobRepository) GetJobIdsByStatus(ctx context.Context, status utils.ExportStatus, limit int) ([]string, error) {
+	return repo.queryIds(ctx, `SELECT id FROM export_jobs WHERE status = ? ORDER BY createdAt LIMIT ?`, status, limit)
+}
+
+func (repo *SQLExportJobRepository) GetExpiredJobIds(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	return repo.queryIds(ctx, `SELECT id FROM export_jobs WHERE status = 'completed' AND expiresAt < ? LIMIT ?`, now.UTC(), limit)
+}
+
+func (repo *SQLExportJobRepository) ExpireJob(ctx context.Context, id string) error {
+	_, err := repo.execAffected(ctx, `UPDATE export_jobs SET status = 'expired' WHERE id = ?`, id)
+	return err
+}
+
+func (repo *SQLExportJobRepository) execAffected(ctx context.Context, query string, args ...any) (bool, error) {
+	result, err := repo.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		repo.reportError(ctx, err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// execOwned runs an update of job id restricted to its lease owner, the query must end with the
+// "id = ? AND status = 'running' AND claimedBy = ?" condition. It returns ErrLeaseLost when the job
+// is no longer owned.
+func (repo *SQLExportJobRepository) execOwned(ctx context.Context, id string, owner string, query string, args ...any) error {
+	changed, err := repo.execAffected(ctx, query, append(args, id, owner)...)
+	if err != nil || changed {
+		return err
+	}
+
+	// MySQL reports 0 affected rows when nothing changed, e.g. two heartbeats within the same second
+	var one int
+	err = repo.db.QueryRowContext(ctx, `SELECT 1 FROM export_jobs WHERE id = ? AND status = 'running' AND claimedBy = ?`, id, owner).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLeaseLost
+	}
+	if err != nil {
+		repo.reportError(ctx, err)
+	}
+	return err
+}
+
+func (repo *SQLExportJobRepository) queryIds(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (repo *SQLExportJobRepository) reportError(ctx context.Context, err error) {
+	msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+}
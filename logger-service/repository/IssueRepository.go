@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/IBM/sarama"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// ErrNotFound is returned when a lookup by id matches no row.
+var ErrNotFound = errors.New("record not found")
+
+type IssueRepository interface {
+	RecordOccurrence(ctx context.Context, fingerprint string, log model.CreateLogSchema) error
+	GetIssues(ctx context.Context, filter utils.IssueFilter, pagination utils.Pagination) (*utils.PaginationResult[utils.Issue], error)
+	GetIssue(ctx context.Context, id int64) (*utils.Issue, error)
+	GetIssueLogs(ctx context.Context, fingerprint string, pagination utils.Pagination) (*utils.PaginationResult[utils.Log], error)
+	UpdateIssueStatus(ctx context.Context, id int64, status utils.IssueStatus) error
+}
+
+type SQLIssueRepository struct {
+	db       *sql.DB
+	producer sarama.AsyncProducer
+}
+
+func NewIssueRepository(db *sql.DB, producer sarama.AsyncProducer) IssueRepository {
+	return &SQLIssueRepository{db: db, producer: producer}
+}
+
+const issueColumns = "id, fingerprint, logLevel, source, message, status, occurrences, firstSeen, lastSeen"
+
+// RecordOccurrence creates the issue for a fingerprint or bumps its counters. The occurrence counts
+// at the time of the log, so a backdated log doesn't move lastSeen forward. A resolved issue is
+// reopened by an occurrence after its resolution, ignored issues stay ignored.
+func (repo *SQLIssueRepository) RecordOccurrence(ctx context.Context, fingerprint string, log model.CreateLogSchema) error {
+	seen := time.Now().UTC()
+	if log.Timestamp != nil {
+		seen = log.Timestamp.UTC()
+	}
+
+	// The assignments run in order and see the values set before them, status reads the old resolvedAt
+	query := `INSERT INTO issues (fingerprint, logLevel, source, message, firstSeen, lastSeen) VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			occurrences = occurrences + 1,
+			status = IF(status = 'resolved' AND (resolvedAt IS NULL OR VALUES(lastSeen) > resolvedAt), 'open', status),
+			resolvedAt = IF(status = 'resolved', resolvedAt, NULL),
+			firstSeen = LEAST(firstSeen, VALUES(firstSeen)),
+			lastSeen = GREATEST(lastSeen, VALUES(lastSeen)),
+			logLevel = VALUES(logLevel)`
+
+	_, err := repo.db.ExecContext(ctx, query, fingerprint, strings.ToUpper(string(log.LogLevel)), log.Source, log.Message, seen, seen)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	return nil
+}
+
+func (repo *SQLIssueRepository) GetIssues(ctx context.Context, filter utils.IssueFilter, pagination utils.Pagination) (*utils.PaginationResult[utils.Issue], error) {
+	var conditions []string
+	var params []any
+
+	if len(filter.StatusFilter) > 0 {
+		placeholders := make([]string, len(filter.StatusFilter))
+		for i, status := range filter.StatusFilter {
+			placeholders[i] = "?"
+			params = append(params, strings.ToLower(status))
+		}
+		conditions = append(conditions, "status IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if filter.Source != "" {
+		conditions = append(conditions, "source = ?")
+		params = append(params, filter.Source)
+	}
+
+	whereQuery := ""
+	if len(conditions) > 0 {
+		whereQuery = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM issues"+whereQuery, params...).Scan(&total); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	query := "SELECT " + issueColumns + " FROM issues" + whereQuery + " ORDER BY lastSeen DESC LIMIT ? OFFSET ?"
+	rows, err := repo.db.QueryContext(ctx, query, append(params, pagination.Limit, pagination.Offset)...)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	issues := []utils.Issue{}
+	for rows.Next() {
+		issue, err := scanIssue(rows)
+		if err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		issues = append(issues, *issue)
+	}
+	if err := rows.Err(); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return &utils.PaginationResult[utils.Issue]{Data: issues, Total: total}, nil
+}
+
+func (repo *SQLIssueRepository) GetIssue(ctx context.Context, id int64) (*utils.Issue, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT "+issueColumns+" FROM issues WHERE id = ?", id)
+	issue, err := scanIssue(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	return issue, nil
+}
+
+// GetIssueLogs returns the individual occurrences of an issue, newest first.
+func (repo *SQLIssueRepository) GetIssueLogs(ctx context.Context, fingerprint string, pagination utils.Pagination) (*utils.PaginationResult[utils.Log], error) {
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM logs WHERE fingerprint = ?", fingerprint).Scan(&total); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	query := "SELECT " + logColumns + " FROM logs WHERE fingerprint = ? ORDER BY createdAt DESC LIMIT ? OFFSET ?"
+	rows, err := repo.db.QueryContext(ctx, query, fingerprint, pagination.Limit, pagination.Offset)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	logs := []utils.Log{}
+	for rows.Next() {
+		dLog, err := scanLog(rows)
+		if err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		logs = append(logs, *dLog)
+	}
+	if err := rows.Err(); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return &utils.PaginationResult[utils.Log]{Data: logs, Total: total}, nil
+}
+
+func (repo *SQLIssueRepository) UpdateIssueStatus(ctx context.Context, id int64, status utils.IssueStatus) error {
+	// resolvedAt keeps the first resolution while the status stays resolved
+	query := "UPDATE issues SET status = ?, resolvedAt = IF(status = 'resolved', IFNULL(resolvedAt, CURRENT_TIMESTAMP), NULL) WHERE id = ?"
+	result, err := repo.db.ExecContext(ctx, query, status, id)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	if affected == 0 {
+		// MySQL reports 0 affected rows when the status is unchanged, so check existence explicitly
+		if _, err := repo.GetIssue(ctx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (repo *SQLIssueRepository) reportError(ctx context.Context, err error) {
+	msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+}
+
+func scanIssue(row rowScanner) (*utils.Issue, error) {
+	var issue utils.Issue
+	err := row.Scan(&issue.Id, &issue.Fingerprint, &issue.LogLevel, &issue.Source, &issue.Message, &issue.Status, &issue.Occurrences, &issue.FirstSeen, &issue.LastSeen)
+	if err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
@@ -3,17 +3,29 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/IBM/sarama"
+	"strconv"
 	"strings"
+	"tikube-backend/logger-service/lql"
 	"tikube-backend/logger-service/model"
 	"tikube-backend/shared/kafka_client"
 	"tikube-backend/shared/utils"
+	"time"
 )
 
 type LoggerRepository interface {
-	CreateLog(ctx context.Context, log model.CreateLogSchema) error
-	GetLogs(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination) (*utils.PaginationResult[utils.Log], error)
+	CreateLog(ctx context.Context, log model.CreateLogSchema, fingerprint string) error
+	GetLogs(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination, view utils.LogView) (*utils.PaginationResult[utils.Log], error)
+	GetHistogram(ctx context.Context, filter utils.LogFilter, bucketSeconds int64, groupBy string) ([]utils.HistogramCount, error)
+	GetFacet(ctx context.Context, filter utils.LogFilter, field string, limit int) ([]utils.FacetValue, error)
+	GetAttributeFacet(ctx context.Context, filter utils.LogFilter, key string, limit int) ([]utils.FacetValue, error)
+	StreamLogs(ctx context.Context, filter utils.LogFilter, afterId int64, limit int, fn func(log *utils.Log) error) error
+	GetLog(ctx context.Context, id int64) (*utils.Log, error)
+	GetNeighborLogs(ctx context.Context, log utils.Log, before int, after int, sameSource bool) ([]utils.Log, []utils.Log, error)
+	GetTraceLogs(ctx context.Context, traceId string, limit int) ([]utils.Log, error)
 }
 
 type SQLLoggerRepository struct {
@@ -25,29 +37,39 @@ func NewLoggerRepository(db *sql.DB, producer sarama.AsyncProducer) LoggerReposi
 	return &SQLLoggerRepository{db: db, producer: producer}
 }
 
-func (repo *SQLLoggerRepository) CreateLog(ctx context.Context, log model.CreateLogSchema) error {
+func (repo *SQLLoggerRepository) CreateLog(ctx context.Context, log model.CreateLogSchema, fingerprint string) error {
 
-	query := `INSERT INTO logs (logLevel, source, message) VALUES (?, ?, ?)`
+	attributes, err := marshalAttributes(log.Attributes)
+	if err != nil {
+		return err
+	}
 
-	result, err := repo.db.ExecContext(ctx, query, log.LogLevel, log.Source, log.Message)
+	var createdAt any
+	if log.Timestamp != nil {
+		createdAt = log.Timestamp.UTC()
+	}
+
+	query := `INSERT INTO logs (logLevel, source, message, fingerprint, traceId, spanId, attributes, createdAt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, IFNULL(?, CURRENT_TIMESTAMP))`
+
+	result, err := repo.db.ExecContext(ctx, query, log.LogLevel, log.Source, log.Message, fingerprint, log.TraceId, log.SpanId, attributes, createdAt)
 	if err != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return err
 	}
 
 	lastInsertedId, err := result.LastInsertId()
 	if err != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return err
 	}
 
-	selectStmt := `SELECT id, logLevel, source, message, createdAt, updatedAt FROM logs WHERE id = ?`
-	var insertedLog utils.Log
-	err = repo.db.QueryRowContext(ctx, selectStmt, lastInsertedId).Scan(&insertedLog.Id, &insertedLog.LogLevel, &insertedLog.Source, &insertedLog.Message, &insertedLog.CreatedAt, &insertedLog.UpdatedAt)
+	selectStmt := "SELECT " + logColumns + " FROM logs WHERE id = ?"
+	_, err = scanLog(repo.db.QueryRowContext(ctx, selectStmt, lastInsertedId))
 	if err != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return err
 	}
@@ -55,43 +77,32 @@ func (repo *SQLLoggerRepository) CreateLog(ctx context.Context, log model.Create
 	return nil
 }
 
-func (repo *SQLLoggerRepository) GetLogs(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination) (*utils.PaginationResult[utils.Log], error) {
-	// Start building the query
-	baseQuery := "SELECT id, logLevel, source, message, createdAt, updatedAt FROM logs"
-	whereBaseQuery := ""
-	var queryParams []any
-	var countQueryParams []any // An extra slice is needed for count query because limit and offset are omitted when counting. QueryContext is strict about the number of args passed for a query
-
-	//Dynamically constructing WHERE statement for log level using ? as query parameters.
-	//Also ensuring that OR is not appended at the end of the query.
-	lenOfLevelFilter := len(filter.LevelFilter)
-	if lenOfLevelFilter > 0 {
-		whereBaseQuery += " WHERE ( "
-		for i, v := range filter.LevelFilter {
-			queryParams = append(queryParams, strings.ToUpper(v))
-			countQueryParams = append(countQueryParams, strings.ToUpper(v))
-			whereBaseQuery += fmt.Sprintf(" logLevel = %s ", "?")
-			if i != lenOfLevelFilter-1 {
-				whereBaseQuery += " OR "
-			}
-		}
-
-		whereBaseQuery += " ) "
+// GetLogs returns a page of logs. Only the fields listed in the view are selected and filled in.
+func (repo *SQLLoggerRepository) GetLogs(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination, view utils.LogView) (*utils.PaginationResult[utils.Log], error) {
+	fields := view.Fields
+	if len(fields) == 0 {
+		fields = utils.LogColumns
+	}
+	selectList, err := buildSelectList(fields, view.MessageLength)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := buildOrderBy(view.Sort)
+	if err != nil {
+		return nil, err
 	}
 
-	//Date filter
-	if filter.DateFilter != nil {
-		queryParams = append(queryParams, filter.DateFilter.From, filter.DateFilter.To)
-		countQueryParams = append(countQueryParams, filter.DateFilter.From, filter.DateFilter.To)
-		if lenOfLevelFilter > 0 {
-			whereBaseQuery += fmt.Sprintf(" AND ( createdAt >= %s AND createdAt <= %s )", "?", "?")
-		} else {
-			whereBaseQuery += fmt.Sprintf(" WHERE createdAt >= %s AND createdAt <= %s ", "?", "?")
-		}
+	// Start building the query
+	baseQuery := "SELECT " + selectList + " FROM logs"
+	whereBaseQuery, filterParams, err := buildWhereQuery(filter)
+	if err != nil {
+		return nil, err
 	}
+	queryParams := append([]any{}, filterParams...)
+	countQueryParams := append([]any{}, filterParams...) // An extra slice is needed for count query because limit and offset are omitted when counting. QueryContext is strict about the number of args passed for a query
 
-	//Add where query
-	baseQuery += whereBaseQuery
+	//Add where query and order
+	baseQuery += whereBaseQuery + orderBy
 
 	// Add pagination
 	queryParams = append(queryParams, pagination.Limit, pagination.Offset)
@@ -104,31 +115,31 @@ func (repo *SQLLoggerRepository) GetLogs(ctx context.Context, filter utils.LogFi
 	defer func() {
 		err := baseQueryRows.Close()
 		if err != nil {
-			msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		}
 	}()
 
 	if baseQueryErr != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", baseQueryErr.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", baseQueryErr.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return nil, baseQueryErr
 	}
 
 	var logs []utils.Log = nil
 	for baseQueryRows.Next() {
-		var dLog utils.Log
-		if err := baseQueryRows.Scan(&dLog.Id, &dLog.LogLevel, &dLog.Source, &dLog.Message, &dLog.CreatedAt, &dLog.UpdatedAt); err != nil {
-			msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		dLog, err := scanLogFields(baseQueryRows, fields)
+		if err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 			return nil, err
 		}
-		logs = append(logs, dLog)
+		logs = append(logs, *dLog)
 	}
 
 	// Check for any errors encountered during base query iteration
 	if baseQueryIterErr := baseQueryRows.Err(); baseQueryIterErr != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", baseQueryIterErr.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", baseQueryIterErr.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return nil, baseQueryIterErr
 	}
@@ -142,13 +153,13 @@ func (repo *SQLLoggerRepository) GetLogs(ctx context.Context, filter utils.LogFi
 	defer func() {
 		err := countQueryRows.Close()
 		if err != nil {
-			msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		}
 	}()
 
 	if countQueryErr != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", countQueryErr.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", countQueryErr.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return nil, countQueryErr
 	}
@@ -156,14 +167,14 @@ func (repo *SQLLoggerRepository) GetLogs(ctx context.Context, filter utils.LogFi
 	var totalResult int
 	for countQueryRows.Next() {
 		if err := countQueryRows.Scan(&totalResult); err != nil {
-			msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
 			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 			return nil, err
 		}
 	}
 	// Check for any errors encountered during count query iteration
 	if countQueryIterErr := countQueryRows.Err(); countQueryIterErr != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:REPOSITORY", countQueryIterErr.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", countQueryIterErr.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
 		return nil, countQueryIterErr
 	}
@@ -175,3 +186,468 @@ func (repo *SQLLoggerRepository) GetLogs(ctx context.Context, filter utils.LogFi
 
 	return &utils.PaginationResult[utils.Log]{Data: logs, Total: totalResult}, nil
 }
+
+func (repo *SQLLoggerRepository) GetLog(ctx context.Context, id int64) (*utils.Log, error) {
+	dLog, err := scanLog(repo.db.QueryRowContext(ctx, "SELECT "+logColumns+" FROM logs WHERE id = ?", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	return dLog, nil
+}
+
+// GetNeighborLogs returns up to before logs written just before log and up to after logs written just
+// after it, both oldest first. Logs are ordered by (createdAt, id) so that entries sharing a second
+// keep their insertion order.
+func (repo *SQLLoggerRepository) GetNeighborLogs(ctx context.Context, log utils.Log, before int, after int, sameSource bool) ([]utils.Log, []utils.Log, error) {
+	sourceCondition := ""
+	params := []any{log.CreatedAt, log.CreatedAt, log.Id}
+	if sameSource {
+		sourceCondition = " AND source = ?"
+		params = append(params, log.Source)
+	}
+
+	beforeQuery := "SELECT " + logColumns + " FROM logs WHERE (createdAt < ? OR (createdAt = ? AND id < ?))" + sourceCondition +
+		" ORDER BY createdAt DESC, id DESC LIMIT ?"
+	beforeLogs, err := repo.queryLogs(ctx, beforeQuery, append(params, before)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Fetched newest first so that the limit keeps the closest ones
+	for i, j := 0, len(beforeLogs)-1; i < j; i, j = i+1, j-1 {
+		beforeLogs[i], beforeLogs[j] = beforeLogs[j], beforeLogs[i]
+	}
+
+	afterQuery := "SELECT " + logColumns + " FROM logs WHERE (createdAt > ? OR (createdAt = ? AND id > ?))" + sourceCondition +
+		" ORDER BY createdAt, id LIMIT ?"
+	afterLogs, err := repo.queryLogs(ctx, afterQuery, append(params, after)...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return beforeLogs, afterLogs, nil
+}
+
+// GetTraceLogs returns the logs of a trace ordered by time.
+func (repo *SQLLoggerRepository) GetTraceLogs(ctx context.Context, traceId string, limit int) ([]utils.Log, error) {
+	query := "SELECT " + logColumns + " FROM logs WHERE traceId = ? ORDER BY createdAt, id LIMIT ?"
+	return repo.queryLogs(ctx, query, traceId, limit)
+}
+
+func (repo *SQLLoggerRepository) queryLogs(ctx context.Context, query string, args ...any) ([]utils.Log, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	logs := []utils.Log{}
+	for rows.Next() {
+		dLog, err := scanLog(rows)
+		if err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		logs = append(logs, *dLog)
+	}
+	if err := rows.Err(); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	return logs, nil
+}
+
+func (repo *SQLLoggerRepository) reportError(ctx context.Context, err error) {
+	msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+}
+
+// escapeLikePattern escapes the LIKE wildcards in value using ! as the escape character.
+func escapeLikePattern(value string) string {
+	replacer := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	return replacer.Replace(value)
+}
+
+// logColumns is the column list scanned by scanLog.
+var logColumns = strings.Join(utils.LogColumns, ", ")
+
+// sortColumns maps the sortable fields to the expression they are ordered by. Each one is backed by
+// an index on (column, createdAt) or the primary key. Levels are ordered by severity, not alphabetically.
+var sortColumns = map[string]string{
+	"id":        "id",
+	"createdAt": "createdAt",
+	"source":    "source",
+	"logLevel":  levelSeverityExpr(),
+}
+
+func levelSeverityExpr() string {
+	levels := make([]string, len(utils.LogLevels))
+	for i, level := range utils.LogLevels {
+		levels[i] = "'" + string(level) + "'"
+	}
+	return "FIELD(logLevel, " + strings.Join(levels, ", ") + ")"
+}
+
+// buildSelectList only writes column names taken from utils.LogColumns into the query.
+func buildSelectList(fields []string, messageLength int) (string, error) {
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		if !utils.IsLogColumn(field) {
+			return "", fmt.Errorf("unknown log field %s", field)
+		}
+		columns[i] = field
+		if field == "message" && messageLength > 0 {
+			columns[i] = "LEFT(message, " + strconv.Itoa(messageLength) + ")"
+		}
+	}
+	return strings.Join(columns, ", "), nil
+}
+
+// buildOrderBy returns an empty clause when there is nothing to sort by. The id is appended as a
+// tie-breaker so that pages are stable.
+func buildOrderBy(sort []utils.SortField) (string, error) {
+	if len(sort) == 0 {
+		return "", nil
+	}
+
+	terms := make([]string, 0, len(sort)+1)
+	hasId := false
+	for _, s := range sort {
+		column, ok := sortColumns[s.Field]
+		if !ok {
+			return "", fmt.Errorf("logs can't be sorted by %s", s.Field)
+		}
+		hasId = hasId || s.Field == "id"
+		terms = append(terms, column+sortDirection(s.Desc))
+	}
+	if !hasId {
+		terms = append(terms, "id"+sortDirection(sort[0].Desc))
+	}
+	return " ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+func sortDirection(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
+// scanLogFields scans a row selected with buildSelectList into the matching fields of a Log.
+func scanLogFields(row rowScanner, fields []string) (*utils.Log, error) {
+	var dLog utils.Log
+	var attributes sql.NullString
+	dest := make([]any, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "id":
+			dest[i] = &dLog.Id
+		case "logLevel":
+			dest[i] = &dLog.LogLevel
+		case "source":
+			dest[i] = &dLog.Source
+		case "message":
+			dest[i] = &dLog.Message
+		case "fingerprint":
+			dest[i] = &dLog.Fingerprint
+		case "traceId":
+			dest[i] = &dLog.TraceId
+		case "spanId":
+			dest[i] = &dLog.SpanId
+		case "attributes":
+			dest[i] = &attributes
+		case "createdAt":
+			dest[i] = &dLog.CreatedAt
+		case "updatedAt":
+			dest[i] = &dLog.UpdatedAt
+		}
+	}
+
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if attributes.Valid && attributes.String != "" {
+		if err := json.Unmarshal([]byte(attributes.String), &dLog.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	return &dLog, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanLog(row rowScanner) (*utils.Log, error) {
+	return scanLogFields(row, utils.LogColumns)
+}
+
+// marshalAttributes stores empty attributes as NULL.
+func marshalAttributes(attributes map[string]any) (any, error) {
+	if len(attributes) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// histogramGroupColumns maps the group_by values to columns. Columns can't be query parameters so only these are allowed.
+var histogramGroupColumns = map[string]string{
+	"level":  "logLevel",
+	"source": "source",
+}
+
+func (repo *SQLLoggerRepository) GetHistogram(ctx context.Context, filter utils.LogFilter, bucketSeconds int64, groupBy string) ([]utils.HistogramCount, error) {
+	groupColumn, ok := histogramGroupColumns[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported histogram group %q", groupBy)
+	}
+
+	whereQuery, params, err := buildWhereQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT FLOOR(UNIX_TIMESTAMP(createdAt) / ?) * ? AS bucket, %s AS grp, COUNT(*) AS total
+		FROM logs%s GROUP BY bucket, grp ORDER BY bucket`, groupColumn, whereQuery)
+
+	rows, err := repo.db.QueryContext(ctx, query, append([]any{bucketSeconds, bucketSeconds}, params...)...)
+	if err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		}
+	}()
+
+	counts := []utils.HistogramCount{}
+	for rows.Next() {
+		var bucket int64
+		var count utils.HistogramCount
+		if err := rows.Scan(&bucket, &count.Group, &count.Count); err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+			return nil, err
+		}
+		count.Bucket = time.Unix(bucket, 0).UTC()
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// StreamLogs calls fn for every log matching the filter with an id greater than afterId, in id order,
+// stopping after limit rows. Rows are read straight from the result set and never collected.
+func (repo *SQLLoggerRepository) StreamLogs(ctx context.Context, filter utils.LogFilter, afterId int64, limit int, fn func(log *utils.Log) error) error {
+	whereQuery, params, err := buildWhereQuery(filter)
+	if err != nil {
+		return err
+	}
+	if whereQuery == "" {
+		whereQuery = " WHERE id > ?"
+	} else {
+		whereQuery += " AND id > ?"
+	}
+
+	query := "SELECT " + logColumns + " FROM logs" + whereQuery + " ORDER BY id LIMIT ?"
+	rows, err := repo.db.QueryContext(ctx, query, append(params, afterId, limit)...)
+	if err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		}
+	}()
+
+	for rows.Next() {
+		dLog, err := scanLog(rows)
+		if err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+			return err
+		}
+		if err := fn(dLog); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// facetColumns maps the facet names to columns.
+var facetColumns = map[string]string{
+	"level":  "logLevel",
+	"source": "source",
+}
+
+// GetFacet returns the most frequent values of a column for the filter.
+func (repo *SQLLoggerRepository) GetFacet(ctx context.Context, filter utils.LogFilter, field string, limit int) ([]utils.FacetValue, error) {
+	column, ok := facetColumns[field]
+	if !ok {
+		return nil, fmt.Errorf("unsupported facet %q", field)
+	}
+
+	whereQuery, params, err := buildWhereQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s AS value, COUNT(*) AS total FROM logs%s GROUP BY value ORDER BY total DESC LIMIT ?", column, whereQuery)
+
+	return repo.queryFacet(ctx, query, append(params, limit)...)
+}
+
+// GetAttributeFacet returns the most frequent values of an attribute for the filter. The key must
+// already be validated, it is embedded in a JSON path which is still passed as a parameter.
+func (repo *SQLLoggerRepository) GetAttributeFacet(ctx context.Context, filter utils.LogFilter, key string, limit int) ([]utils.FacetValue, error) {
+	path := fmt.Sprintf(`$."%s"`, key)
+
+	whereQuery, params, err := buildWhereQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+	if whereQuery == "" {
+		whereQuery = " WHERE JSON_EXTRACT(attributes, ?) IS NOT NULL"
+	} else {
+		whereQuery += " AND JSON_EXTRACT(attributes, ?) IS NOT NULL"
+	}
+	query := "SELECT JSON_UNQUOTE(JSON_EXTRACT(attributes, ?)) AS value, COUNT(*) AS total FROM logs" + whereQuery +
+		" GROUP BY value ORDER BY total DESC LIMIT ?"
+
+	args := append([]any{path}, params...)
+	args = append(args, path, limit)
+	return repo.queryFacet(ctx, query, args...)
+}
+
+func (repo *SQLLoggerRepository) queryFacet(ctx context.Context, query string, args ...any) ([]utils.FacetValue, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		}
+	}()
+
+	values := []utils.FacetValue{}
+	for rows.Next() {
+		var value utils.FacetValue
+		if err := rows.Scan(&value.Value, &value.Count); err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	if err := rows.Err(); err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// buildWhereQuery turns a LogFilter into a WHERE clause using ? placeholders and the matching parameters.
+// The clause is empty when the filter has no conditions.
+func buildWhereQuery(filter utils.LogFilter) (string, []any, error) {
+	var conditions []string
+	var params []any
+
+	//Dynamically constructing the condition for log level using ? as query parameters.
+	if len(filter.LevelFilter) > 0 {
+		placeholders := make([]string, len(filter.LevelFilter))
+		for i, v := range filter.LevelFilter {
+			placeholders[i] = "?"
+			params = append(params, strings.ToUpper(v))
+		}
+		conditions = append(conditions, "logLevel IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	//Minimum level, expanded to the list of levels at that severity or higher
+	if filter.MinLevel != "" {
+		minLevel, _ := utils.ParseLogLevel(string(filter.MinLevel))
+		levels := utils.LogLevelsAtLeast(minLevel)
+		if len(levels) == 0 {
+			return "", nil, fmt.Errorf("unknown log level %s", filter.MinLevel)
+		}
+		placeholders := make([]string, len(levels))
+		for i, level := range levels {
+			placeholders[i] = "?"
+			params = append(params, level)
+		}
+		conditions = append(conditions, "logLevel IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	//Date filter
+	if filter.DateFilter != nil {
+		if filter.DateFilter.From != nil {
+			params = append(params, filter.DateFilter.From.UTC())
+			conditions = append(conditions, "createdAt >= ?")
+		}
+		if filter.DateFilter.To != nil {
+			params = append(params, filter.DateFilter.To.UTC())
+			conditions = append(conditions, "createdAt <= ?")
+		}
+	}
+
+	//Source filter, prefixes use the source index through LIKE 'PREFIX:%'
+	if len(filter.SourceFilter) > 0 {
+		var sourceConditions []string
+		for _, pattern := range filter.SourceFilter {
+			source, isPrefix, err := utils.ParseSourcePattern(pattern)
+			if err != nil {
+				return "", nil, err
+			}
+			if isPrefix {
+				sourceConditions = append(sourceConditions, "source = ?", "source LIKE ? ESCAPE '!'")
+				params = append(params, source, escapeLikePattern(source+utils.SourceSeparator)+"%")
+			} else {
+				sourceConditions = append(sourceConditions, "source = ?")
+				params = append(params, source)
+			}
+		}
+		conditions = append(conditions, "("+strings.Join(sourceConditions, " OR ")+")")
+	}
+
+	//LQL query
+	if filter.Query != "" {
+		condition, queryParams, err := lql.CompileQuery(filter.Query)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, "("+condition+")")
+		params = append(params, queryParams...)
+	}
+
+	if len(conditions) == 0 {
+		return "", params, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), params, nil
+}
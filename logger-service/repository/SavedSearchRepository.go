@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/IBM/sarama"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+)
+
+type SavedSearchRepository interface {
+	CreateSearch(ctx context.Context, search utils.SavedSearch) (*utils.SavedSearch, error)
+	GetSearches(ctx context.Context, owner string, pagination utils.Pagination) (*utils.PaginationResult[utils.SavedSearch], error)
+	GetSearch(ctx context.Context, id int64) (*utils.SavedSearch, error)
+	GetSearchByShareToken(ctx context.Context, token string) (*utils.SavedSearch, error)
+	UpdateSearch(ctx context.Context, id int64, search utils.SavedSearch) (*utils.SavedSearch, error)
+	DeleteSearch(ctx context.Context, id int64) error
+	SetShareToken(ctx context.Context, id int64, token string) error
+}
+
+type SQLSavedSearchRepository struct {
+	db       *sql.DB
+	producer sarama.AsyncProducer
+}
+
+func NewSavedSearchRepository(db *sql.DB, producer sarama.AsyncProducer) SavedSearchRepository {
+	return &SQLSavedSearchRepository{db: db, producer: producer}
+}
+
+const savedSearchColumns = "id, name, owner, filter, columns, timeRange, shareToken, createdAt, updatedAt"
+
+func (repo *SQLSavedSearchRepository) CreateSearch(ctx context.Context, search utils.SavedSearch) (*utils.SavedSearch, error) {
+	filter, columns, timeRange, err := marshalSavedSearch(search)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO saved_searches (name, owner, filter, columns, timeRange) VALUES (?, ?, ?, ?, ?)`
+	result, err := repo.db.ExecContext(ctx, query, search.Name, search.Owner, filter, columns, timeRange)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return repo.GetSearch(ctx, id)
+}
+
+func (repo *SQLSavedSearchRepository) GetSearches(ctx context.Context, owner string, pagination utils.Pagination) (*utils.PaginationResult[utils.SavedSearch], error) {
+	whereQuery := ""
+	var params []any
+	if owner != "" {
+		whereQuery = " WHERE owner = ?"
+		params = append(params, owner)
+	}
+
+	var total int
+	if err := repo.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM saved_searches"+whereQuery, params...).Scan(&total); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	query := "SELECT " + savedSearchColumns + " FROM saved_searches" + whereQuery + " ORDER BY name LIMIT ? OFFSET ?"
+	rows, err := repo.db.QueryContext(ctx, query, append(params, pagination.Limit, pagination.Offset)...)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			repo.reportError(ctx, err)
+		}
+	}()
+
+	searches := []utils.SavedSearch{}
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			repo.reportError(ctx, err)
+			return nil, err
+		}
+		searches = append(searches, *search)
+	}
+	if err := rows.Err(); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return &utils.PaginationResult[utils.SavedSearch]{Data: searches, Total: total}, nil
+}
+
+func (repo *SQLSavedSearchRepository) GetSearch(ctx context.Context, id int64) (*utils.SavedSearch, error) {
+	return repo.getSearchBy(ctx, "id", id)
+}
+
+func (repo *SQLSavedSearchRepository) GetSearchByShareToken(ctx context.Context, token string) (*utils.SavedSearch, error) {
+	return repo.getSearchBy(ctx, "shareToken", token)
+}
+
+// getSearchBy looks a search up by one of its unique columns, the column name is never user input.
+func (repo *SQLSavedSearchRepository) getSearchBy(ctx context.Context, column string, value any) (*utils.SavedSearch, error) {
+	row := repo.db.QueryRowContext(ctx, "SELECT "+savedSearchColumns+" FROM saved_searches WHERE "+column+" = ?", value)
+	search, err := scanSavedSearch(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+	return search, nil
+}
+
+func (repo *SQLSavedSearchRepository) UpdateSearch(ctx context.Context, id int64, search utils.SavedSearch) (*utils.SavedSearch, error) {
+	if _, err := repo.GetSearch(ctx, id); err != nil {
+		return nil, err
+	}
+
+	filter, columns, timeRange, err := marshalSavedSearch(search)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE saved_searches SET name = ?, owner = ?, filter = ?, columns = ?, timeRange = ? WHERE id = ?`
+	if _, err := repo.db.ExecContext(ctx, query, search.Name, search.Owner, filter, columns, timeRange, id); err != nil {
+		repo.reportError(ctx, err)
+		return nil, err
+	}
+
+	return repo.GetSearch(ctx, id)
+}
+
+func (repo *SQLSavedSearchRepository) DeleteSearch(ctx context.Context, id int64) error {
+	result, err := repo.db.ExecContext(ctx, "DELETE FROM saved_searches WHERE id = ?", id)
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (repo *SQLSavedSearchRepository) SetShareToken(ctx context.Context, id int64, token string) error {
+	if _, err := repo.db.ExecContext(ctx, "UPDATE saved_searches SET shareToken = ? WHERE id = ?", token, id); err != nil {
+		repo.reportError(ctx, err)
+		return err
+	}
+	return nil
+}
+
+func (repo *SQLSavedSearchRepository) reportError(ctx context.Context, err error) {
+	msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:REPOSITORY", err.Error())
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, repo.producer)
+}
+
+func marshalSavedSearch(search utils.SavedSearch) (string, string, any, error) {
+	filter, err := json.Marshal(search.Filter)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	if search.Columns == nil {
+		search.Columns = []string{}
+	}
+	columns, err := json.Marshal(search.Columns)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	var timeRange any
+	if search.TimeRange != nil {
+		data, err := json.Marshal(search.TimeRange)
+		if err != nil {
+			return "", "", nil, err
+		}
+		timeRange = string(data)
+	}
+
+	return string(filter), string(columns), timeRange, nil
+}
+
+func scanSavedSearch(row rowScanner) (*utils.SavedSearch, error) {
+	var search utils.SavedSearch
+	var filter, columns string
+	var timeRange, shareToken sql.NullString
+
+	err := row.Scan(&search.Id, &search.Name, &search.Owner, &filter, &columns, &timeRange, &shareToken, &search.CreatedAt, &search.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(filter), &search.Filter); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(columns), &search.Columns); err != nil {
+		return nil, err
+	}
+	if timeRange.Valid {
+		if err := json.Unmarshal([]byte(timeRange.String), &search.TimeRange); err != nil {
+			return nil, err
+		}
+	}
+	search.ShareToken = shareToken.String
+
+	return &search, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/IBM/sarama"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// WebhookPayload is Slack compatible: Slack only reads "text", other receivers can use the structured fields.
+type WebhookPayload struct {
+	Text      string           `json:"text"`
+	State     utils.AlertState `json:"state"`
+	RuleId    int64            `json:"ruleId"`
+	RuleName  string           `json:"ruleName"`
+	Count     int64            `json:"count"`
+	Threshold int              `json:"threshold"`
+	Timestamp time.Time        `json:"timestamp"`
+}
+
+type WebhookNotifier struct {
+	client   *http.Client
+	producer sarama.AsyncProducer
+}
+
+const webhookAttempts = 3
+
+func NewWebhookNotifier(producer sarama.AsyncProducer) *WebhookNotifier {
+	// Host names are checked once resolved, so that a name pointing at an internal address (or
+	// rebinding to one) can't be used to reach internal services. Redirects dial the same way.
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: checkWebhookAddress}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookNotifier{client: &http.Client{Timeout: 5 * time.Second, Transport: transport}, producer: producer}
+}
+
+func checkWebhookAddress(_ string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !utils.IsPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+	}
+	return nil
+}
+
+// Notify delivers the notification in the background so that the consumer path is never blocked
+// by a slow webhook. Failed deliveries are retried with a linear backoff.
+func (n *WebhookNotifier) Notify(rule utils.AlertRule, state utils.AlertState, count int64) {
+	payload := WebhookPayload{
+		Text:      describeAlert(rule, state, count),
+		State:     state,
+		RuleId:    rule.Id,
+		RuleName:  rule.Name,
+		Count:     count,
+		Threshold: rule.Threshold,
+		Timestamp: time.Now().UTC(),
+	}
+
+	go func() {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+
+		for attempt := 1; attempt <= webhookAttempts; attempt++ {
+			if err = n.post(rule.WebhookURL, body); err == nil {
+				return
+			}
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+
+		msg := utils.CreateSerializedLog(context.Background(), utils.WARN, "LOGGER:ALERTS", fmt.Sprintf("webhook for alert rule %d failed: %v", rule.Id, err))
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, n.producer)
+	}()
+}
+
+func (n *WebhookNotifier) post(url string, body []byte) error {
+	resp, err := n.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func describeAlert(rule utils.AlertRule, state utils.AlertState, count int64) string {
+	levels := "any level"
+	if len(rule.Levels) > 0 {
+		parts := make([]string, len(rule.Levels))
+		for i, level := range rule.Levels {
+			parts[i] = string(level)
+		}
+		levels = strings.Join(parts, "/")
+	}
+
+	source := "any source"
+	if rule.Source != "" {
+		source = rule.Source
+	}
+
+	window := time.Duration(rule.WindowSeconds) * time.Second
+	if state == utils.AlertResolved {
+		return fmt.Sprintf("[RESOLVED] %s: %d %s logs from %s in the last %s (threshold %d)", rule.Name, count, levels, source, window, rule.Threshold)
+	}
+	return fmt.Sprintf("[FIRING] %s: %d %s logs from %s in the last %s (threshold %d)", rule.Name, count, levels, source, window, rule.Threshold)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"github.com/redis/go-redis/v9"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/repository"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// How often rules are reloaded from MySQL and firing alerts are checked for resolution.
+const alertTickInterval = 15 * time.Second
+
+// The logs the service writes about itself, e.g. LOGGER:ALERTS when a webhook fails, are not
+// evaluated: a rule matching them would report its own failures through the failing webhook.
+const loggerSource = "LOGGER"
+
+type AlertService struct {
+	alertRepository repository.AlertRepository
+	rdb             *redis.Client
+	producer        sarama.AsyncProducer
+	notifier        *WebhookNotifier
+
+	mu    sync.RWMutex
+	rules []utils.AlertRule
+}
+
+func NewAlertService(alertRepository repository.AlertRepository, rdb *redis.Client, producer sarama.AsyncProducer, notifier *WebhookNotifier) *AlertService {
+	return &AlertService{alertRepository: alertRepository, rdb: rdb, producer: producer, notifier: notifier}
+}
+
+// Run keeps the in-memory rule set fresh and sends resolve notifications until ctx is cancelled.
+func (as *AlertService) Run(ctx context.Context) {
+	_ = as.loadRules(ctx)
+
+	ticker := time.NewTicker(alertTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = as.loadRules(ctx)
+			as.checkResolved(ctx)
+		}
+	}
+}
+
+// Evaluate records the log in the sliding window of every rule it matches and fires the
+// rules whose window now holds more logs than their threshold.
+func (as *AlertService) Evaluate(ctx context.Context, log model.CreateLogSchema) {
+	as.mu.RLock()
+	rules := as.rules
+	as.mu.RUnlock()
+
+	if utils.MatchSource(loggerSource+utils.SourceSeparator+"*", log.Source) {
+		return
+	}
+
+	now := time.Now()
+	for _, rule := range rules {
+		if !rule.Enabled || !ruleMatches(rule, log) {
+			continue
+		}
+
+		key := windowKey(rule.Id)
+		window := time.Duration(rule.WindowSeconds) * time.Second
+		member := fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())
+
+		pipe := as.rdb.TxPipeline()
+		pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMilli()), Member: member})
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixMilli(), 10))
+		count := pipe.ZCard(ctx, key)
+		pipe.Expire(ctx, key, window)
+		if _, err := pipe.Exec(ctx); err != nil {
+			as.reportError(ctx, err)
+			continue
+		}
+
+		if count.Val() > int64(rule.Threshold) {
+			as.fire(ctx, rule, count.Val())
+		}
+	}
+}
+
+// fire notifies once per firing period. SETNX deduplicates across consumer instances and the
+// cooldown key suppresses rules that flap between firing and resolved.
+func (as *AlertService) fire(ctx context.Context, rule utils.AlertRule, count int64) {
+	cooling, err := as.rdb.Exists(ctx, cooldownKey(rule.Id)).Result()
+	if err != nil {
+		as.reportError(ctx, err)
+		return
+	}
+	if cooling > 0 {
+		return
+	}
+
+	ttl := firingTTL(rule)
+	firstToFire, err := as.rdb.SetNX(ctx, firingKey(rule.Id), count, ttl).Result()
+	if err != nil {
+		as.reportError(ctx, err)
+		return
+	}
+	if !firstToFire {
+		// Still firing, the key lives on as long as the rule keeps matching
+		as.rdb.Expire(ctx, firingKey(rule.Id), ttl)
+		return
+	}
+
+	as.record(ctx, rule, utils.AlertFiring, count)
+}
+
+func (as *AlertService) checkResolved(ctx context.Context) {
+	as.mu.RLock()
+	rules := as.rules
+	as.mu.RUnlock()
+
+	now := time.Now()
+	for _, rule := range rules {
+		firing, err := as.rdb.Exists(ctx, firingKey(rule.Id)).Result()
+		if err != nil || firing == 0 {
+			continue
+		}
+
+		window := time.Duration(rule.WindowSeconds) * time.Second
+		key := windowKey(rule.Id)
+		pipe := as.rdb.TxPipeline()
+		pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(now.Add(-window).UnixMilli(), 10))
+		count := pipe.ZCard(ctx, key)
+		if _, err := pipe.Exec(ctx); err != nil {
+			as.reportError(ctx, err)
+			continue
+		}
+
+		if rule.Enabled && count.Val() > int64(rule.Threshold) {
+			continue
+		}
+
+		// Only the instance that actually deletes the key sends the resolve notification
+		deleted, err := as.rdb.Del(ctx, firingKey(rule.Id)).Result()
+		if err != nil || deleted == 0 {
+			continue
+		}
+		if rule.CooldownSeconds > 0 {
+			as.rdb.Set(ctx, cooldownKey(rule.Id), 1, time.Duration(rule.CooldownSeconds)*time.Second)
+		}
+
+		as.record(ctx, rule, utils.AlertResolved, count.Val())
+	}
+}
+
+func (as *AlertService) record(ctx context.Context, rule utils.AlertRule, state utils.AlertState, count int64) {
+	as.notifier.Notify(rule, state, count)
+
+	event := utils.AlertEvent{RuleId: rule.Id, State: state, Count: count, Message: describeAlert(rule, state, count)}
+	_ = as.alertRepository.CreateEvent(ctx, event)
+}
+
+func (as *AlertService) loadRules(ctx context.Context) error {
+	rules, err := as.alertRepository.GetRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	as.mu.Lock()
+	as.rules = rules
+	as.mu.Unlock()
+	return nil
+}
+
+func (as *AlertService) CreateRule(ctx context.Context, rule utils.AlertRule) (*utils.AlertRule, error) {
+	created, err := as.alertRepository.CreateRule(ctx, rule)
+	if err != nil {
+		return nil, fmt.Errorf("creating alert rule: %w", err)
+	}
+	_ = as.loadRules(ctx)
+	return created, nil
+}
+
+func (as *AlertService) GetRules(ctx context.Context) ([]utils.AlertRule, error) {
+	rules, err := as.alertRepository.GetRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (as *AlertService) GetRule(ctx context.Context, id int64) (*utils.AlertRule, error) {
+	rule, err := as.alertRepository.GetRule(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http_error.NotFound("Alert rule not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting alert rule %d: %w", id, err)
+	}
+	return rule, nil
+}
+
+func (as *AlertService) UpdateRule(ctx context.Context, id int64, rule utils.AlertRule) (*utils.AlertRule, error) {
+	updated, err := as.alertRepository.UpdateRule(ctx, id, rule)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http_error.NotFound("Alert rule not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating alert rule %d: %w", id, err)
+	}
+	_ = as.loadRules(ctx)
+	return updated, nil
+}
+
+func (as *AlertService) DeleteRule(ctx context.Context, id int64) error {
+	err := as.alertRepository.DeleteRule(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return http_error.NotFound("Alert rule not found")
+	}
+	if err != nil {
+		return fmt.Errorf("deleting alert rule %d: %w", id, err)
+	}
+
+	as.rdb.Del(ctx, windowKey(id), firingKey(id), cooldownKey(id))
+	_ = as.loadRules(ctx)
+	return nil
+}
+
+func (as *AlertService) GetHistory(ctx context.Context, id int64, pagination utils.Pagination) (*utils.PaginationResult[utils.AlertEvent], error) {
+	if _, err := as.GetRule(ctx, id); err != nil {
+		return nil, err
+	}
+
+	events, err := as.alertRepository.GetEvents(ctx, id, pagination)
+	if err != nil {
+		return nil, fmt.Errorf("getting events of alert rule %d: %w", id, err)
+	}
+	return events, nil
+}
+
+func (as *AlertService) reportError(ctx context.Context, err error) {
+	msg := utils.CreateSerializedLog(ctx, utils.ERROR, "LOGGER:ALERTS", err.Error())
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, as.producer)
+}
+
+// ruleMatches checks the level list and the source, which may end with :* to match a source hierarchy.
+func ruleMatches(rule utils.AlertRule, log model.CreateLogSchema) bool {
+	if len(rule.Levels) > 0 {
+		matched := false
+		for _, level := range rule.Levels {
+			if strings.EqualFold(string(level), string(log.LogLevel)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return rule.Source == "" || utils.MatchSource(rule.Source, log.Source)
+}
+
+func windowKey(ruleId int64) string {
+	return fmt.Sprintf("alert:window:%d", ruleId)
+}
+
+func firingKey(ruleId int64) string {
+	return fmt.Sprintf("alert:firing:%d", ruleId)
+}
+
+// firingTTL outlives the window by a few ticks, so that checkResolved deletes the key and sends the
+// resolve notification first. The key only expires on its own when no instance evaluates the rule
+// anymore, e.g. when it was removed from the database directly.
+func firingTTL(rule utils.AlertRule) time.Duration {
+	return time.Duration(rule.WindowSeconds)*time.Second + 4*alertTickInterval
+}
+
+func cooldownKey(ruleId int64) string {
+	return fmt.Sprintf("alert:cooldown:%d", ruleId)
+}
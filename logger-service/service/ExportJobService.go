@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"io"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"tikube-backend/logger-service/export"
+	"tikube-backend/logger-service/repository"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/storage"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+const (
+	defaultExportWorkers = 2
+	defaultExportTTL     = 24 * time.Hour
+	// Rows written between two checkpoints of an export job.
+	exportCheckpointEvery = 1000
+	// A running job whose lease has not been renewed for this long is assumed to belong to a dead worker.
+	exportStaleAfter = 10 * time.Minute
+	// Live workers renew their lease well before it goes stale, even when no rows are being written.
+	exportLeaseRenewInterval = exportStaleAfter / 5
+	exportPollInterval       = 30 * time.Second
+	exportQueueSize          = 100
+)
+
+type ExportJobService struct {
+	jobRepository    repository.ExportJobRepository
+	loggerRepository repository.LoggerRepository
+	storage          storage.Storage
+	producer         sarama.AsyncProducer
+	maxRows          int
+	workers          int
+	ttl              time.Duration
+	queue            chan string
+}
+
+func NewExportJobService(jobRepository repository.ExportJobRepository, loggerRepository repository.LoggerRepository, storage storage.Storage, producer sarama.AsyncProducer, maxRows int) *ExportJobService {
+	workers := defaultExportWorkers
+	if value, err := strconv.Atoi(os.Getenv("EXPORT_WORKERS")); err == nil && value > 0 {
+		workers = value
+	}
+
+	ttl := defaultExportTTL
+	if value, err := time.ParseDuration(os.Getenv("EXPORT_TTL")); err == nil && value > 0 {
+		ttl = value
+	}
+
+	return &ExportJobService{
+		jobRepository:    jobRepository,
+		loggerRepository: loggerRepository,
+		storage:          storage,
+		producer:         producer,
+		maxRows:          maxRows,
+		workers:          workers,
+		ttl:              ttl,
+		queue:            make(chan string, exportQueueSize),
+	}
+}
+
+// CreateJob stores a queued export job and hands it to a worker.
+func (es *ExportJobService) CreateJob(ctx context.Context, format export.Format, filter utils.LogFilter, limit int) (*utils.ExportJob, error) {
+	if limit <= 0 || limit > es.maxRows {
+		limit = es.maxRows
+	}
+
+	id, err := newRandomId()
+	if err != nil {
+		return nil, fmt.Errorf("creating export job id: %w", err)
+	}
+
+	job := utils.ExportJob{Id: id, Format: string(format), Filter: filter, MaxRows: limit}
+	if err := es.jobRepository.CreateJob(ctx, job); err != nil {
+		return nil, fmt.Errorf("creating export job: %w", err)
+	}
+
+	es.enqueue(id)
+	return es.GetJob(ctx, id)
+}
+
+func (es *ExportJobService) GetJob(ctx context.Context, id string) (*utils.ExportJob, error) {
+	job, err := es.jobRepository.GetJob(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http_error.NotFound("Export job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting export job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// OpenDownload returns the file of a completed job. The caller must close it.
+func (es *ExportJobService) OpenDownload(ctx context.Context, id string) (*utils.ExportJob, io.ReadSeekCloser, error) {
+	job, err := es.GetJob(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch job.Status {
+	case utils.ExportCompleted:
+	case utils.ExportExpired:
+		return nil, nil, http_error.NotFound("Export has expired")
+	case utils.ExportFailed:
+		return nil, nil, http_error.Conflict("Export failed: " + job.Error)
+	default:
+		return nil, nil, http_error.Conflict("Export is not ready yet")
+	}
+
+	file, _, err := es.storage.Open(exportKey(job))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, http_error.NotFound("Export has expired")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening export %s: %w", job.Id, err)
+	}
+	return job, file, nil
+}
+
+// Run starts the worker pool and keeps it fed until ctx is cancelled. Jobs are picked up from MySQL,
+// so jobs created before a restart or by another instance are processed as well.
+func (es *ExportJobService) Run(ctx context.Context) {
+	for i := 0; i < es.workers; i++ {
+		go es.work(ctx)
+	}
+
+	es.maintain(ctx)
+
+	ticker := time.NewTicker(exportPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			es.maintain(ctx)
+		}
+	}
+}
+
+// maintain requeues jobs abandoned by dead workers, queues pending jobs and removes expired files.
+func (es *ExportJobService) maintain(ctx context.Context) {
+	if _, err := es.jobRepository.RequeueStaleJobs(ctx, exportStaleAfter); err != nil {
+		return
+	}
+
+	if ids, err := es.jobRepository.GetJobIdsByStatus(ctx, utils.ExportQueued, exportQueueSize); err == nil {
+		for _, id := range ids {
+			es.enqueue(id)
+		}
+	}
+
+	ids, err := es.jobRepository.GetExpiredJobIds(ctx, time.Now(), exportQueueSize)
+	if err != nil {
+		return
+	}
+	for _, id := range ids {
+		job, err := es.jobRepository.GetJob(ctx, id)
+		if err != nil {
+			continue
+		}
+		if err := es.storage.Delete(exportKey(job)); err != nil {
+			es.report(ctx, utils.ERROR, "could not delete expired export "+id+": "+err.Error())
+			continue
+		}
+		_ = es.jobRepository.ExpireJob(ctx, id)
+	}
+}
+
+// enqueue never blocks; a job that does not fit in the queue is picked up by the next poll.
+func (es *ExportJobService) enqueue(id string) {
+	select {
+	case es.queue <- id:
+	default:
+	}
+}
+
+func (es *ExportJobService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-es.queue:
+			es.process(ctx, id)
+		}
+	}
+}
+
+func (es *ExportJobService) process(ctx context.Context, id string) {
+	owner, err := newRandomId()
+	if err != nil {
+		return
+	}
+
+	// The same job can be queued more than once, claiming it makes sure only one worker runs it
+	claimed, err := es.jobRepository.ClaimJob(ctx, id, owner)
+	if err != nil || !claimed {
+		return
+	}
+
+	job, err := es.jobRepository.GetJob(ctx, id)
+	if err != nil {
+		return
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	var leaseLost atomic.Bool
+	go es.keepLease(jobCtx, cancel, id, owner, &leaseLost)
+	err = es.runJob(jobCtx, job, owner)
+	cancel()
+	if err == nil {
+		return
+	}
+
+	if leaseLost.Load() || errors.Is(err, repository.ErrLeaseLost) {
+		// The job was requeued and may be running elsewhere, its file is no longer ours to touch
+		es.report(ctx, utils.WARN, "export job "+id+" lost its lease and was stopped")
+		return
+	}
+
+	if ctx.Err() != nil {
+		// Shutting down: leave the checkpoint in place so the job resumes after the restart
+		_ = es.jobRepository.RequeueJob(context.Background(), id, owner)
+		return
+	}
+
+	es.report(ctx, utils.ERROR, "export job "+id+" failed: "+err.Error())
+	if err := es.jobRepository.FailJob(context.Background(), id, owner, err.Error()); err != nil {
+		return
+	}
+	_ = es.storage.Delete(exportKey(job))
+}
+
+// keepLease renews the lease of a running job until ctx is done, so that a slow query does not get
+// the job requeued. When the lease is lost anyway it stops the job through cancel.
+func (es *ExportJobService) keepLease(ctx context.Context, cancel context.CancelFunc, id string, owner string, lost *atomic.Bool) {
+	ticker := time.NewTicker(exportLeaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := es.jobRepository.RenewLease(ctx, id, owner); errors.Is(err, repository.ErrLeaseLost) {
+				lost.Store(true)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// runJob writes the export file, continuing from the last checkpoint when the format allows it.
+func (es *ExportJobService) runJob(ctx context.Context, job *utils.ExportJob, owner string) error {
+	format, err := export.ParseFormat(job.Format)
+	if err != nil {
+		return err
+	}
+	key := exportKey(job)
+
+	resume := job.LastLogId > 0 && format.Appendable()
+	var file storage.Writer
+	if resume {
+		file, err = es.storage.Append(key, job.SizeBytes)
+		if errors.Is(err, storage.ErrNotFound) {
+			resume = false
+		} else if err != nil {
+			return err
+		}
+	}
+	if !resume {
+		job.RowsWritten, job.LastLogId, job.SizeBytes = 0, 0, 0
+		if file, err = es.storage.Create(key); err != nil {
+			return err
+		}
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	out := &sizeWriter{w: file, size: job.SizeBytes}
+	writer, err := export.NewWriter(format, out, !resume)
+	if err != nil {
+		return err
+	}
+
+	remaining := job.MaxRows - int(job.RowsWritten)
+	if remaining > 0 {
+		err = es.loggerRepository.StreamLogs(ctx, job.Filter, job.LastLogId, remaining, func(log *utils.Log) error {
+			if err := writer.Write(log); err != nil {
+				return err
+			}
+			job.RowsWritten++
+			job.LastLogId = log.Id
+
+			if job.RowsWritten%exportCheckpointEvery != 0 {
+				return nil
+			}
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			// A resumed job truncates the file to the checkpointed size, which must therefore be on disk
+			if err := file.Sync(); err != nil {
+				return err
+			}
+			return es.jobRepository.SaveProgress(ctx, job.Id, owner, job.RowsWritten, job.LastLogId, out.size)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return es.jobRepository.CompleteJob(ctx, job.Id, owner, job.RowsWritten, out.size, time.Now().Add(es.ttl))
+}
+
+func (es *ExportJobService) report(ctx context.Context, level utils.LogLevel, message string) {
+	msg := utils.CreateSerializedLog(ctx, level, "LOGGER:SERVICE", message)
+	kafka_client.SendLogToKafka(msg, utils.LoggerTopic, es.producer)
+}
+
+func exportKey(job *utils.ExportJob) string {
+	return job.Id + "." + export.Format(job.Format).Extension()
+}
+
+// newRandomId returns a random 32 character hex id, used for job ids and lease owners.
+func newRandomId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sizeWriter tracks the size of the file being written so that it can be recorded in checkpoints.
+type sizeWriter struct {
+	w    io.Writer
+	size int64
+}
+
+func (sw *sizeWriter) Write(p []byte) (int, error) {
+	n, err := sw.w.Write(p)
+	sw.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"github.com/IBM/sarama"
+	"io"
+	"os"
+	"strconv"
+	"tikube-backend/logger-service/export"
+	"tikube-backend/logger-service/repository"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+)
+
+// Rows written between two flushes of a streamed export.
+const exportFlushEvery = 500
+
+const defaultMaxExportRows = 1_000_000
+
+type ExportService struct {
+	loggerRepository repository.LoggerRepository
+	producer         sarama.AsyncProducer
+	maxRows          int
+}
+
+func NewExportService(loggerRepository repository.LoggerRepository, producer sarama.AsyncProducer) *ExportService {
+	maxRows := defaultMaxExportRows
+	if value, err := strconv.Atoi(os.Getenv("EXPORT_MAX_ROWS")); err == nil && value > 0 {
+		maxRows = value
+	}
+	return &ExportService{loggerRepository: loggerRepository, producer: producer, maxRows: maxRows}
+}
+
+// MaxRows is the most rows a single export may contain.
+func (es *ExportService) MaxRows() int {
+	return es.maxRows
+}
+
+// Export streams the logs matching filter to w in the given format and returns the number of rows
+// written. flush is called periodically so that rows reach the client while the query runs.
+func (es *ExportService) Export(ctx context.Context, filter utils.LogFilter, format export.Format, limit int, w io.Writer, flush func() error) (int, error) {
+	if limit <= 0 || limit > es.maxRows {
+		limit = es.maxRows
+	}
+
+	writer, err := export.NewWriter(format, w, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = es.loggerRepository.StreamLogs(ctx, filter, 0, limit, func(log *utils.Log) error {
+		if err := writer.Write(log); err != nil {
+			return err
+		}
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.ERROR, "LOGGER:SERVICE", "export failed: "+err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, es.producer)
+		return count, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strconv"
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// Hourly counters are kept a little longer than the widest range they are used for.
+const (
+	facetCounterRetention = 32 * 24 * time.Hour
+	facetCounterMaxRange  = 31 * 24 * time.Hour
+)
+
+// FacetCounter keeps facet counts in Redis so that large time ranges don't need a full scan of the
+// logs table. The level and source counts are exact counters per hour, kept in hashes; the number
+// of distinct sources is estimated with a HyperLogLog. They are approximate for a range in that the
+// range is widened to whole hours and no other condition of a filter can be applied.
+type FacetCounter struct {
+	rdb *redis.Client
+}
+
+func NewFacetCounter(rdb *redis.Client) *FacetCounter {
+	return &FacetCounter{rdb: rdb}
+}
+
+func (fc *FacetCounter) Record(ctx context.Context, log model.CreateLogSchema, at time.Time) error {
+	// The counters of that hour have expired, counting the log alone would make them look complete
+	if time.Since(at) > facetCounterRetention {
+		return nil
+	}
+	hour := hourKey(at)
+	level := strings.ToUpper(string(log.LogLevel))
+
+	pipe := fc.rdb.Pipeline()
+	pipe.HIncrBy(ctx, "facets:"+hour+":level", level, 1)
+	pipe.HIncrBy(ctx, "facets:"+hour+":source", log.Source, 1)
+	pipe.PFAdd(ctx, "facets:"+hour+":sources", log.Source)
+	for _, suffix := range []string{":level", ":source", ":sources"} {
+		pipe.Expire(ctx, "facets:"+hour+suffix, facetCounterRetention)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Query sums the hourly counters of the hours between from and to. The caller keeps the range
+// within facetCounterMaxRange, older counters have expired.
+func (fc *FacetCounter) Query(ctx context.Context, from time.Time, to time.Time, sourceLimit int) (*utils.Facets, error) {
+	pipe := fc.rdb.Pipeline()
+	var levelCmds, sourceCmds []*redis.MapStringStringCmd
+	var hllKeys []string
+	for hour := from.UTC().Truncate(time.Hour); !hour.After(to); hour = hour.Add(time.Hour) {
+		key := hourKey(hour)
+		levelCmds = append(levelCmds, pipe.HGetAll(ctx, "facets:"+key+":level"))
+		sourceCmds = append(sourceCmds, pipe.HGetAll(ctx, "facets:"+key+":source"))
+		hllKeys = append(hllKeys, "facets:"+key+":sources")
+	}
+	distinct := pipe.PFCount(ctx, hllKeys...)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	return &utils.Facets{
+		Levels:          sumCounters(levelCmds, 0),
+		Sources:         sumCounters(sourceCmds, sourceLimit),
+		Attributes:      map[string][]utils.FacetValue{},
+		DistinctSources: distinct.Val(),
+		Approximate:     true,
+	}, nil
+}
+
+func sumCounters(cmds []*redis.MapStringStringCmd, limit int) []utils.FacetValue {
+	totals := map[string]int64{}
+	for _, cmd := range cmds {
+		for value, countStr := range cmd.Val() {
+			count, _ := strconv.ParseInt(countStr, 10, 64)
+			totals[value] += count
+		}
+	}
+
+	values := make([]utils.FacetValue, 0, len(totals))
+	for value, count := range totals {
+		values = append(values, utils.FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].Count != values[j].Count {
+			return values[i].Count > values[j].Count
+		}
+		return values[i].Value < values[j].Value
+	})
+
+	if limit > 0 && len(values) > limit {
+		values = values[:limit]
+	}
+	return values
+}
+
+func hourKey(t time.Time) string {
+	return t.UTC().Format("2006010215")
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"tikube-backend/shared/utils"
+)
+
+// The order matters: quoted strings are removed before UUIDs, hex ids and numbers so
+// that their contents do not leave partial placeholders behind.
+var (
+	quotedStringPattern = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`)
+	uuidPattern         = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexIdPattern        = regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9a-f]*[0-9][0-9a-f]*[a-f][0-9a-f]*|[0-9a-f]*[a-f][0-9a-f]*[0-9][0-9a-f]*)\b`)
+	numberPattern       = regexp.MustCompile(`\d+(?:\.\d+)?`)
+	whitespacePattern   = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeMessage strips the variable parts of a log message so that occurrences of
+// the same error produce the same text.
+func NormalizeMessage(message string) string {
+	normalized := quotedStringPattern.ReplaceAllString(message, "<str>")
+	normalized = uuidPattern.ReplaceAllString(normalized, "<uuid>")
+	normalized = hexIdPattern.ReplaceAllStringFunc(normalized, func(match string) string {
+		// Short tokens such as "a1" are more likely to be words than ids
+		if len(match) < 6 && !strings.HasPrefix(strings.ToLower(match), "0x") {
+			return match
+		}
+		return "<hex>"
+	})
+	normalized = numberPattern.ReplaceAllString(normalized, "<num>")
+	normalized = whitespacePattern.ReplaceAllString(normalized, " ")
+	return strings.TrimSpace(normalized)
+}
+
+// Fingerprint identifies a group of similar logs by their source and normalized message.
+func Fingerprint(source string, message string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(source)) + "\x00" + NormalizeMessage(message)))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsFingerprinted reports whether logs of the given level are grouped into issues.
+func IsFingerprinted(level utils.LogLevel) bool {
+	parsed, ok := utils.ParseLogLevel(string(level))
+	return ok && parsed.Severity() >= utils.ERROR.Severity()
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+type histogramInterval struct {
+	name     string
+	duration time.Duration
+}
+
+// histogramIntervals are the supported bucket sizes, smallest first.
+var histogramIntervals = []histogramInterval{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+	{"30m", 30 * time.Minute},
+	{"1h", time.Hour},
+	{"3h", 3 * time.Hour},
+	{"6h", 6 * time.Hour},
+	{"12h", 12 * time.Hour},
+	{"1d", 24 * time.Hour},
+	{"7d", 7 * 24 * time.Hour},
+}
+
+// Charts stay readable up to roughly this many buckets; requested intervals that would produce
+// more are widened.
+const maxHistogramBuckets = 200
+
+// Used when the filter has no date range.
+const defaultHistogramRange = 24 * time.Hour
+
+func lookupHistogramInterval(name string) (histogramInterval, bool) {
+	for _, interval := range histogramIntervals {
+		if interval.name == name {
+			return interval, true
+		}
+	}
+	return histogramInterval{}, false
+}
+
+// chooseHistogramInterval picks the smallest interval that is at least the requested one and keeps
+// the number of buckets for the span under maxHistogramBuckets.
+func chooseHistogramInterval(span time.Duration, requested histogramInterval) histogramInterval {
+	for _, interval := range histogramIntervals {
+		if interval.duration < requested.duration {
+			continue
+		}
+		if span/interval.duration <= maxHistogramBuckets {
+			return interval
+		}
+	}
+	return histogramIntervals[len(histogramIntervals)-1]
+}
+
+// histogramCacheTTL scales with the bucket size: small buckets change quickly and big ones barely move.
+func histogramCacheTTL(interval histogramInterval) time.Duration {
+	ttl := interval.duration / 4
+	if ttl < 10*time.Second {
+		return 10 * time.Second
+	}
+	if ttl > 10*time.Minute {
+		return 10 * time.Minute
+	}
+	return ttl
+}
+
+// histogramRange returns the date range the histogram covers. An open start uses the default range
+// before the end and an open end stops at now.
+func histogramRange(filter utils.LogFilter, now time.Time) (time.Time, time.Time) {
+	to := now
+	if filter.DateFilter != nil && filter.DateFilter.To != nil {
+		to = *filter.DateFilter.To
+	}
+	if filter.DateFilter == nil || filter.DateFilter.From == nil {
+		return to.Add(-defaultHistogramRange), to
+	}
+	return *filter.DateFilter.From, to
+}
+
+// Zero filling stops past this many buckets, which only happens for ranges of many years.
+const maxFilledHistogramBuckets = 10 * maxHistogramBuckets
+
+// fillHistogramBuckets returns a bucket for every interval between from and to, buckets without
+// logs have zero counts. Buckets are aligned on the Unix epoch like the ones of the query.
+func fillHistogramBuckets(buckets []utils.HistogramBucket, from time.Time, to time.Time, interval histogramInterval) []utils.HistogramBucket {
+	seconds := int64(interval.duration / time.Second)
+	first, last := from.Unix()/seconds*seconds, to.Unix()/seconds*seconds
+	if last < first || (last-first)/seconds >= maxFilledHistogramBuckets {
+		return buckets
+	}
+
+	byTime := make(map[int64]utils.HistogramBucket, len(buckets))
+	for _, bucket := range buckets {
+		byTime[bucket.Time.Unix()] = bucket
+	}
+
+	filled := make([]utils.HistogramBucket, 0, (last-first)/seconds+1)
+	for t := first; t <= last; t += seconds {
+		bucket, ok := byTime[t]
+		if !ok {
+			bucket = utils.HistogramBucket{Time: time.Unix(t, 0).UTC(), Counts: map[string]int64{}}
+		}
+		filled = append(filled, bucket)
+	}
+	return filled
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/repository"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/utils"
+)
+
+type IssueService struct {
+	issueRepository repository.IssueRepository
+	producer        sarama.AsyncProducer
+}
+
+func NewIssueService(issueRepository repository.IssueRepository, producer sarama.AsyncProducer) *IssueService {
+	return &IssueService{issueRepository: issueRepository, producer: producer}
+}
+
+// IssueFingerprint returns the fingerprint of the issue the log belongs to, or an empty string for
+// levels that are not grouped.
+func IssueFingerprint(log model.CreateLogSchema) string {
+	if !IsFingerprinted(log.LogLevel) {
+		return ""
+	}
+	return Fingerprint(log.Source, log.Message)
+}
+
+// RecordOccurrence counts a log against the issue of its fingerprint. It is called once the log is
+// stored, so that a log that failed to be inserted is not counted.
+func (is *IssueService) RecordOccurrence(ctx context.Context, fingerprint string, log model.CreateLogSchema) error {
+	if fingerprint == "" {
+		return nil
+	}
+
+	if err := is.issueRepository.RecordOccurrence(ctx, fingerprint, log); err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, is.producer)
+		return err
+	}
+	return nil
+}
+
+func (is *IssueService) GetIssues(ctx context.Context, filter utils.IssueFilter, pagination utils.Pagination) (*utils.PaginationResult[utils.Issue], error) {
+	issues, err := is.issueRepository.GetIssues(ctx, filter, pagination)
+	if err != nil {
+		return nil, fmt.Errorf("getting issues: %w", err)
+	}
+	return issues, nil
+}
+
+func (is *IssueService) GetIssue(ctx context.Context, id int64) (*utils.Issue, error) {
+	issue, err := is.issueRepository.GetIssue(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http_error.NotFound("Issue not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting issue %d: %w", id, err)
+	}
+	return issue, nil
+}
+
+func (is *IssueService) GetIssueLogs(ctx context.Context, id int64, pagination utils.Pagination) (*utils.PaginationResult[utils.Log], error) {
+	issue, err := is.GetIssue(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := is.issueRepository.GetIssueLogs(ctx, issue.Fingerprint, pagination)
+	if err != nil {
+		return nil, fmt.Errorf("getting logs of issue %d: %w", id, err)
+	}
+	return logs, nil
+}
+
+func (is *IssueService) UpdateIssueStatus(ctx context.Context, id int64, status utils.IssueStatus) (*utils.Issue, error) {
+	err := is.issueRepository.UpdateIssueStatus(ctx, id, status)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http_error.NotFound("Issue not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating issue %d: %w", id, err)
+	}
+	return is.GetIssue(ctx, id)
+}
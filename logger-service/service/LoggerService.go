@@ -8,11 +8,15 @@ import (
 	"github.com/IBM/sarama"
 	"github.com/go-redis/cache/v9"
 	"github.com/redis/go-redis/v9"
+	"strconv"
 	"strings"
+	"sync"
 	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/redaction"
 	"tikube-backend/logger-service/repository"
 	"tikube-backend/shared/http_error"
 	"tikube-backend/shared/kafka_client"
+	"tikube-backend/shared/tracing"
 	"tikube-backend/shared/utils"
 	"time"
 )
@@ -22,44 +26,224 @@ type LoggerService struct {
 	rdb              *redis.Client
 	cache            *cache.Cache
 	producer         sarama.AsyncProducer
+	redactor         *redaction.Redactor
+	issueService     *IssueService
+	alertService     *AlertService
+	facetCounter     *FacetCounter
 }
 
-func NewLoggerService(loggerRepository repository.LoggerRepository, rdb *redis.Client, cache *cache.Cache, producer sarama.AsyncProducer) *LoggerService {
-	return &LoggerService{loggerRepository: loggerRepository, rdb: rdb, cache: cache}
+const redactionCountsKey = "redaction_counts"
+
+const (
+	topSourcesLimit         = 20
+	topAttributeValuesLimit = 10
+	maxFacetAttributes      = 5
+	// Wider ranges without other conditions are answered from the Redis counters
+	exactFacetMaxRange = 7 * 24 * time.Hour
+)
+
+func NewLoggerService(loggerRepository repository.LoggerRepository, rdb *redis.Client, cache *cache.Cache, producer sarama.AsyncProducer, redactor *redaction.Redactor, issueService *IssueService, alertService *AlertService) *LoggerService {
+	return &LoggerService{loggerRepository: loggerRepository, rdb: rdb, cache: cache, producer: producer, redactor: redactor, issueService: issueService, alertService: alertService, facetCounter: NewFacetCounter(rdb)}
 }
 
 func (ls *LoggerService) ProcessLogs(ctx context.Context, kafkaMessage *sarama.ConsumerMessage) error {
 	var receivedLogMsg utils.CreateLogSchema
 	err := json.Unmarshal(kafkaMessage.Value, &receivedLogMsg)
 	if err != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:SERVICE", err.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
 		return err
 	}
-	err = ls.loggerRepository.CreateLog(ctx, model.CreateLogSchema(receivedLogMsg))
+
+	// Producers that don't set the ids in the payload can send the trace context as a header
+	if receivedLogMsg.TraceId == "" {
+		for _, header := range kafkaMessage.Headers {
+			if header == nil || string(header.Key) != tracing.TraceParentHeader {
+				continue
+			}
+			if tc, ok := tracing.ParseTraceParent(string(header.Value)); ok {
+				receivedLogMsg.TraceId, receivedLogMsg.SpanId = tc.TraceId, tc.SpanId
+			}
+			break
+		}
+	}
+
+	return ls.IngestLog(ctx, model.CreateLogSchema(receivedLogMsg))
+}
+
+// PublishLogs validates logs received over HTTP and queues the valid ones on the log topic, where
+// ProcessLogs picks them up like any other log. It returns how many logs were rejected and why the
+// first one was. Validation failures are http_error.FieldErrors whose fields start with the index of
+// the log, e.g. [2].source.
+func (ls *LoggerService) PublishLogs(logs []model.CreateLogSchema) (int, error) {
+	messages, rejected, reason := serializeLogs(logs)
+	for _, msg := range messages {
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+	}
+	return rejected, reason
+}
+
+// PublishLogsConfirmed is PublishLogs for protocols that acknowledge what they receive, it only
+// returns once Kafka has confirmed the valid logs. rejected and reason are those of PublishLogs,
+// sending the rejected logs again would not help. err is set when Kafka did not confirm every valid
+// log, the sender should then retry.
+func (ls *LoggerService) PublishLogsConfirmed(ctx context.Context, logs []model.CreateLogSchema) (rejected int, reason error, err error) {
+	messages, rejected, reason := serializeLogs(logs)
+	if err := kafka_client.SendLogsToKafkaAndWait(ctx, messages, utils.LoggerTopic, ls.producer); err != nil {
+		return rejected, reason, fmt.Errorf("publishing logs: %w", err)
+	}
+	return rejected, reason, nil
+}
+
+// serializeLogs returns the Kafka messages of the valid logs, with how many were rejected and why
+// the first one was.
+func serializeLogs(logs []model.CreateLogSchema) ([]string, int, error) {
+	messages := make([]string, 0, len(logs))
+	rejected := 0
+	var reason error
+	for i, log := range logs {
+		if err := log.Validate(); err != nil {
+			if rejected == 0 {
+				reason = err
+				var fieldErrors http_error.FieldErrors
+				if errors.As(err, &fieldErrors) {
+					reason = fieldErrors.Prefix(fmt.Sprintf("[%d].", i))
+				}
+			}
+			rejected++
+			continue
+		}
+
+		msg, err := utils.SerializeKafkaMessage(utils.CreateLogSchema(log))
+		if err != nil {
+			if rejected == 0 {
+				reason = http_error.InternalServerError().WithCause(fmt.Errorf("serializing log %d: %w", i, err))
+			}
+			rejected++
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rejected, reason
+}
+
+// IngestLog runs the redaction stage, groups errors into issues, persists the log and
+// evaluates the alert rules.
+// Every ingestion path must go through it.
+func (ls *LoggerService) IngestLog(ctx context.Context, log model.CreateLogSchema) error {
+	// Store the canonical name so that aliases such as "warning" are filtered and counted as WARN
+	if level, ok := utils.ParseLogLevel(string(log.LogLevel)); ok {
+		log.LogLevel = level
+	}
+	log.Source = utils.NormalizeSource(log.Source)
+
+	// Invalid trace ids are dropped rather than rejecting the log
+	log.TraceId, log.SpanId = strings.ToLower(log.TraceId), strings.ToLower(log.SpanId)
+	if !tracing.IsValidTraceId(log.TraceId) {
+		log.TraceId, log.SpanId = "", ""
+	} else if !tracing.IsValidSpanId(log.SpanId) {
+		log.SpanId = ""
+	}
+
+	redacted, keep := ls.redact(ctx, log)
+	if !keep {
+		return nil
+	}
+
+	fingerprint := IssueFingerprint(redacted)
+	err := ls.loggerRepository.CreateLog(ctx, redacted, fingerprint)
 	if err != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:SERVICE", err.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
 		return err
 	}
+
+	// The occurrence is counted only now that the log is stored. RecordOccurrence reports its own
+	// failure, returning it would have the message redelivered and the log stored a second time.
+	_ = ls.issueService.RecordOccurrence(ctx, fingerprint, redacted)
+
+	// Counted in the hour the log happened, as the exact facets, which filter on createdAt
+	at := time.Now()
+	if redacted.Timestamp != nil {
+		at = *redacted.Timestamp
+	}
+	if err := ls.facetCounter.Record(ctx, redacted, at); err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.ERROR, "LOGGER:SERVICE", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+	}
+
+	ls.alertService.Evaluate(ctx, redacted)
 	return nil
 }
 
-func (ls *LoggerService) GetLogs(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination) (*utils.PaginationResult[utils.Log], error) {
+// redact applies the redaction rules to the log message and attributes and records how often each rule fired.
+// It returns false when a drop rule matched and the log must not be stored.
+func (ls *LoggerService) redact(ctx context.Context, log model.CreateLogSchema) (model.CreateLogSchema, bool) {
+	if ls.redactor == nil {
+		return log, true
+	}
+
+	result := ls.redactor.Redact(log.Source, log.Message)
+	log.Message = result.Message
+	if !result.Dropped {
+		log.Attributes = ls.redactAttributes(log.Source, log.Attributes, result.Counts)
+	}
+
+	if len(result.Counts) > 0 {
+		pipe := ls.rdb.Pipeline()
+		for rule, count := range result.Counts {
+			pipe.HIncrBy(ctx, redactionCountsKey, rule, int64(count))
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			msg := utils.CreateSerializedLog(ctx, utils.ERROR, "LOGGER:SERVICE", err.Error())
+			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+		}
+	}
+
+	return log, !result.Dropped
+}
+
+// redactAttributes applies the redaction rules to string attribute values, adding to counts.
+// A drop rule that matches an attribute drops only that attribute.
+func (ls *LoggerService) redactAttributes(source string, attributes map[string]any, counts map[string]int) map[string]any {
+	if len(attributes) == 0 {
+		return attributes
+	}
+
+	redacted := make(map[string]any, len(attributes))
+	for key, value := range attributes {
+		str, ok := value.(string)
+		if !ok {
+			redacted[key] = value
+			continue
+		}
+
+		result := ls.redactor.Redact(source, str)
+		for rule, count := range result.Counts {
+			counts[rule] += count
+		}
+		if !result.Dropped {
+			redacted[key] = result.Message
+		}
+	}
+	return redacted
+}
+
+func (ls *LoggerService) GetLogs(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination, view utils.LogView) (*utils.PaginationResult[utils.Log], error) {
 	var logTemplate *utils.PaginationResult[utils.Log]
 	var repoError error
 
-	key := generateCacheKey(filter, pagination)
+	key := generateCacheKey(filter, pagination, view)
 
 	err := ls.cache.Get(ctx, key, &logTemplate)
 
 	if errors.Is(err, cache.ErrCacheMiss) {
-		logTemplate, repoError = ls.loggerRepository.GetLogs(ctx, filter, pagination)
+		logTemplate, repoError = ls.loggerRepository.GetLogs(ctx, filter, pagination, view)
 
 		if repoError != nil {
-			msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:SERVICE", repoError.Error())
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", repoError.Error())
 			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
-			return nil, http_error.InternalServerError()
+			return nil, fmt.Errorf("getting logs: %w", repoError)
 		}
 
 		err := ls.cache.Set(&cache.Item{
@@ -68,29 +252,446 @@ func (ls *LoggerService) GetLogs(ctx context.Context, filter utils.LogFilter, pa
 			TTL:   time.Second * 30,
 		})
 		if err != nil {
-			msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:SERVICE", err.Error())
+			msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
 			kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
-			return nil, http_error.InternalServerError()
+			return nil, fmt.Errorf("caching logs: %w", err)
 		}
 	} else if err != nil {
-		msg := utils.CreateSerializedLog(utils.FATAL, "LOGGER:SERVICE", err.Error())
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
 		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
-		return nil, http_error.InternalServerError()
+		return nil, fmt.Errorf("reading cached logs: %w", err)
 	}
 
 	return logTemplate, nil
 }
 
-func generateCacheKey(filter utils.LogFilter, pagination utils.Pagination) string {
-	// Combine filter and pagination into a single string
+func (ls *LoggerService) GetLog(ctx context.Context, id int64) (*utils.Log, error) {
+	dLog, err := ls.loggerRepository.GetLog(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, http_error.NotFound("Log not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting log %d: %w", id, err)
+	}
+	return dLog, nil
+}
+
+// GetLogContext returns a log with the logs written right before and after it, optionally only
+// those from the same source.
+func (ls *LoggerService) GetLogContext(ctx context.Context, id int64, before int, after int, sameSource bool) (*utils.LogContext, error) {
+	dLog, err := ls.GetLog(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	beforeLogs, afterLogs, err := ls.loggerRepository.GetNeighborLogs(ctx, *dLog, before, after, sameSource)
+	if err != nil {
+		return nil, fmt.Errorf("getting neighbors of log %d: %w", id, err)
+	}
+	return &utils.LogContext{Log: *dLog, Before: beforeLogs, After: afterLogs}, nil
+}
+
+const (
+	tailPollInterval = time.Second
+	// Logs are stored with second precision and may be stored after newer ones, so every poll
+	// re-reads this window and skips the logs already sent.
+	tailLookback = 10 * time.Second
+	tailPageSize = 500
+)
+
+// TailLogs calls send with the last backlog logs matching the filter, then with the new ones as
+// they are stored, oldest first, until ctx is done or send fails. The filter's date range is ignored.
+// The database is polled directly, GetLogs' cache would delay new logs.
+func (ls *LoggerService) TailLogs(ctx context.Context, filter utils.LogFilter, backlog int, send func([]utils.Log) error) error {
+	filter.DateFilter = nil
+	newestFirst := utils.LogView{Sort: []utils.SortField{{Field: "createdAt", Desc: true}, {Field: "id", Desc: true}}}
+	latest, err := ls.loggerRepository.GetLogs(ctx, filter, utils.Pagination{Limit: max(backlog, 1)}, newestFirst)
+	if err != nil {
+		return fmt.Errorf("getting tail backlog: %w", err)
+	}
+
+	// Logs with an id up to the newest one found here were stored before the call, the ones that
+	// are not part of the backlog must not be sent when a poll window includes them
+	var lastId int64
+	var cursor time.Time
+	logs := make([]utils.Log, 0, len(latest.Data))
+	for i := len(latest.Data) - 1; i >= 0; i-- {
+		log := latest.Data[i]
+		lastId = max(lastId, log.Id)
+		if log.CreatedAt.After(cursor) {
+			cursor = log.CreatedAt
+		}
+		if i < backlog {
+			logs = append(logs, log)
+		}
+	}
+	if len(logs) > 0 {
+		if err := send(logs); err != nil {
+			return err
+		}
+	}
+
+	seen := map[int64]time.Time{}
+	oldestFirst := utils.LogView{Sort: []utils.SortField{{Field: "createdAt"}, {Field: "id"}}}
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if !cursor.IsZero() {
+			from := cursor.Add(-tailLookback)
+			filter.DateFilter = &utils.DateFilterRange{From: &from}
+		}
+		for offset := 0; ; offset += tailPageSize {
+			page, err := ls.loggerRepository.GetLogs(ctx, filter, utils.Pagination{Offset: offset, Limit: tailPageSize}, oldestFirst)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fmt.Errorf("polling new logs: %w", err)
+			}
+
+			logs := make([]utils.Log, 0, len(page.Data))
+			for _, log := range page.Data {
+				if _, ok := seen[log.Id]; ok || log.Id <= lastId {
+					continue
+				}
+				seen[log.Id] = log.CreatedAt
+				if log.CreatedAt.After(cursor) {
+					cursor = log.CreatedAt
+				}
+				logs = append(logs, log)
+			}
+			if len(logs) > 0 {
+				if err := send(logs); err != nil {
+					return err
+				}
+			}
+			if len(page.Data) < tailPageSize {
+				break
+			}
+		}
+
+		for id, createdAt := range seen {
+			if createdAt.Before(cursor.Add(-tailLookback)) {
+				delete(seen, id)
+			}
+		}
+	}
+}
+
+// Traces with more logs than this are returned truncated.
+const maxTraceLogs = 10_000
+
+// GetTrace returns the logs of a trace from every service, oldest first.
+func (ls *LoggerService) GetTrace(ctx context.Context, traceId string) (*utils.Trace, error) {
+	traceId = strings.ToLower(traceId)
+	if !tracing.IsValidTraceId(traceId) {
+		return nil, http_error.BadRequest("Invalid trace id")
+	}
+
+	logs, err := ls.loggerRepository.GetTraceLogs(ctx, traceId, maxTraceLogs+1)
+	if err != nil {
+		return nil, fmt.Errorf("getting logs of trace %s: %w", traceId, err)
+	}
+	if len(logs) == 0 {
+		return nil, http_error.NotFound("Trace not found")
+	}
+
+	trace := &utils.Trace{TraceId: traceId, Sources: []string{}, Logs: logs}
+	if len(logs) > maxTraceLogs {
+		trace.Logs, trace.Truncated = logs[:maxTraceLogs], true
+	}
+
+	seen := map[string]bool{}
+	for _, log := range trace.Logs {
+		if !seen[log.Source] {
+			seen[log.Source] = true
+			trace.Sources = append(trace.Sources, log.Source)
+		}
+	}
+	return trace, nil
+}
+
+// GetSources returns the source hierarchy of the logs matching the filter with the number of logs per node.
+func (ls *LoggerService) GetSources(ctx context.Context, filter utils.LogFilter) (*utils.SourceNode, error) {
+	key := "sources_" + filterCacheKey(filter)
+
+	var tree *utils.SourceNode
+	err := ls.cache.Get(ctx, key, &tree)
+	if err == nil {
+		return tree, nil
+	}
+	if !errors.Is(err, cache.ErrCacheMiss) {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+		return nil, fmt.Errorf("reading cached sources: %w", err)
+	}
+
+	counts, err := ls.loggerRepository.GetFacet(ctx, filter, "source", maxTreeSources)
+	if err != nil {
+		return nil, fmt.Errorf("counting sources: %w", err)
+	}
+	tree = buildSourceTree(counts)
+
+	err = ls.cache.Set(&cache.Item{
+		Key:   key,
+		Value: tree,
+		TTL:   sourceTreeCacheTTL,
+	})
+	if err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+	}
+
+	return tree, nil
+}
+
+// GetHistogram counts logs per time bucket and group. An empty interval lets the bucket size follow the date range.
+func (ls *LoggerService) GetHistogram(ctx context.Context, filter utils.LogFilter, intervalName string, groupBy string) (*utils.Histogram, error) {
+	if groupBy != "level" && groupBy != "source" {
+		return nil, http_error.BadRequest("group_by must be level or source")
+	}
+
+	requested := histogramIntervals[0]
+	if intervalName != "" {
+		var ok bool
+		if requested, ok = lookupHistogramInterval(intervalName); !ok {
+			return nil, http_error.BadRequest("Unsupported interval " + intervalName)
+		}
+	}
+
+	// The query is bounded by the default range too, so that it doesn't scan the whole table
+	from, to := histogramRange(filter, time.Now())
+	interval := chooseHistogramInterval(to.Sub(from), requested)
+
+	key := fmt.Sprintf("histogram_%s_%s_%s", filterCacheKey(filter), interval.name, groupBy)
+	filter.DateFilter = &utils.DateFilterRange{From: &from, To: &to}
+
+	var histogram *utils.Histogram
+	err := ls.cache.Get(ctx, key, &histogram)
+	if err == nil {
+		return histogram, nil
+	}
+	if !errors.Is(err, cache.ErrCacheMiss) {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+		return nil, fmt.Errorf("reading cached histogram: %w", err)
+	}
+
+	counts, err := ls.loggerRepository.GetHistogram(ctx, filter, int64(interval.duration/time.Second), groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("counting histogram: %w", err)
+	}
+
+	histogram = &utils.Histogram{Interval: interval.name, GroupBy: groupBy, Buckets: []utils.HistogramBucket{}}
+	for _, count := range counts {
+		// Rows are ordered by bucket so a new bucket starts whenever the time changes
+		last := len(histogram.Buckets) - 1
+		if last < 0 || !histogram.Buckets[last].Time.Equal(count.Bucket) {
+			histogram.Buckets = append(histogram.Buckets, utils.HistogramBucket{Time: count.Bucket, Counts: map[string]int64{}})
+			last++
+		}
+		histogram.Buckets[last].Counts[count.Group] += count.Count
+		histogram.Buckets[last].Total += count.Count
+	}
+	histogram.Buckets = fillHistogramBuckets(histogram.Buckets, from, to, interval)
+
+	err = ls.cache.Set(&cache.Item{
+		Key:   key,
+		Value: histogram,
+		TTL:   histogramCacheTTL(interval),
+	})
+	if err != nil {
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", err.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+	}
+
+	return histogram, nil
+}
+
+// GetFacets returns the page of logs for the filter together with the level, top source and top
+// attribute value counts. All queries run concurrently. The hourly Redis counters are used when
+// asked for, the conditions they can't apply are then listed in IgnoredFilters. Wide date ranges
+// use them on their own only when the filter has no such condition.
+func (ls *LoggerService) GetFacets(ctx context.Context, filter utils.LogFilter, pagination utils.Pagination, attributeKeys []string, approximate bool) (*utils.Facets, error) {
+	if len(attributeKeys) > maxFacetAttributes {
+		return nil, http_error.BadRequest(fmt.Sprintf("At most %d facet attributes are allowed", maxFacetAttributes))
+	}
+	for _, key := range attributeKeys {
+		if !utils.ValidAttributeKey(key) {
+			return nil, http_error.BadRequest("Invalid facet attribute " + key)
+		}
+	}
+
+	from, to := histogramRange(filter, time.Now())
+	ignored := approximateIgnoredFilters(filter, attributeKeys)
+	if approximate && to.Sub(from) > facetCounterMaxRange {
+		return nil, http_error.BadRequest(fmt.Sprintf("Approximate counts cover at most %d days", facetCounterMaxRange/(24*time.Hour)))
+	}
+	if !approximate && to.Sub(from) > exactFacetMaxRange && to.Sub(from) <= facetCounterMaxRange && len(ignored) == 0 {
+		approximate = true
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	run := func(task func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := task(); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	facets := &utils.Facets{Attributes: map[string][]utils.FacetValue{}}
+
+	run(func() error {
+		logs, err := ls.GetLogs(ctx, filter, pagination, utils.LogView{})
+		facets.Logs = logs
+		return err
+	})
+
+	if approximate {
+		run(func() error {
+			counts, err := ls.facetCounter.Query(ctx, from, to, topSourcesLimit)
+			if err != nil {
+				return err
+			}
+			facets.Levels, facets.Sources, facets.DistinctSources, facets.Approximate = counts.Levels, counts.Sources, counts.DistinctSources, true
+			facets.IgnoredFilters = ignored
+			return nil
+		})
+	} else {
+		run(func() (err error) {
+			facets.Levels, err = ls.loggerRepository.GetFacet(ctx, filter, "level", len(utils.LogLevels))
+			return err
+		})
+		run(func() (err error) {
+			facets.Sources, err = ls.loggerRepository.GetFacet(ctx, filter, "source", topSourcesLimit)
+			return err
+		})
+		for _, key := range attributeKeys {
+			key := key
+			run(func() error {
+				values, err := ls.loggerRepository.GetAttributeFacet(ctx, filter, key, topAttributeValuesLimit)
+				mu.Lock()
+				facets.Attributes[key] = values
+				mu.Unlock()
+				return err
+			})
+		}
+	}
+
+	wg.Wait()
+	if firstErr != nil {
+		var httpErr *http_error.HTTPError
+		if errors.As(firstErr, &httpErr) {
+			return nil, httpErr
+		}
+		msg := utils.CreateSerializedLog(ctx, utils.FATAL, "LOGGER:SERVICE", firstErr.Error())
+		kafka_client.SendLogToKafka(msg, utils.LoggerTopic, ls.producer)
+		return nil, fmt.Errorf("counting facets: %w", firstErr)
+	}
+
+	return facets, nil
+}
+
+// approximateIgnoredFilters lists the query parameters of the filter that the facet counters can't
+// apply, they only count per hour, level and source.
+func approximateIgnoredFilters(filter utils.LogFilter, attributeKeys []string) []string {
+	var ignored []string
+	if len(filter.LevelFilter) > 0 {
+		ignored = append(ignored, "level_filter")
+	}
+	if filter.MinLevel != "" {
+		ignored = append(ignored, "min_level")
+	}
+	if len(filter.SourceFilter) > 0 {
+		ignored = append(ignored, "source_filter")
+	}
+	if filter.Query != "" {
+		ignored = append(ignored, "q")
+	}
+	if len(attributeKeys) > 0 {
+		ignored = append(ignored, "facet_attributes")
+	}
+	return ignored
+}
+
+func generateCacheKey(filter utils.LogFilter, pagination utils.Pagination, view utils.LogView) string {
+	return fmt.Sprintf("logs_%s_%d_%d%s", filterCacheKey(filter), pagination.Limit, pagination.Offset, viewCacheKey(view))
+}
+
+// viewCacheKey is empty for the default view so existing keys stay valid.
+func viewCacheKey(view utils.LogView) string {
+	var sb strings.Builder
+	if len(view.Sort) > 0 {
+		sb.WriteString("_s:")
+		for i, s := range view.Sort {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(s.Field)
+			if s.Desc {
+				sb.WriteString("-desc")
+			}
+		}
+	}
+	if len(view.Fields) > 0 {
+		sb.WriteString("_f:" + strings.Join(view.Fields, ","))
+	}
+	if view.MessageLength > 0 {
+		sb.WriteString("_m:" + strconv.Itoa(view.MessageLength))
+	}
+	return sb.String()
+}
+
+// filterCacheKey combines the filter into a single string
+func filterCacheKey(filter utils.LogFilter) string {
 	var sortKey string
 	if len(filter.LevelFilter) > 0 {
 		sortKey = strings.Join(filter.LevelFilter, "_")
 	}
 
+	if filter.MinLevel != "" {
+		sortKey += "_min:" + string(filter.MinLevel)
+	}
+
+	if len(filter.SourceFilter) > 0 {
+		sortKey += "_src:" + strings.Join(filter.SourceFilter, ",")
+	}
+
+	// The range is already resolved, so equivalent spellings (time zones, epoch or RFC3339) share a key.
+	// Relative ranges resolve to a new instant on every request, hence the rounding in cacheKeyTime.
 	if filter.DateFilter != nil {
-		sortKey += fmt.Sprintf("%s_%s", filter.DateFilter.From, filter.DateFilter.To)
+		sortKey += "_t:" + cacheKeyTime(filter.DateFilter.From) + "-" + cacheKeyTime(filter.DateFilter.To)
 	}
 
-	return fmt.Sprintf("logs_%s_%d_%d", sortKey, pagination.Limit, pagination.Offset)
+	if filter.Query != "" {
+		sortKey += "_q:" + filter.Query
+	}
+
+	return sortKey
+}
+
+// cacheKeyResolution is how far apart two bounds can be and still share a cache entry. It is not
+// longer than the shortest TTL of the entries keyed with it, which already lets results be that old.
+const cacheKeyResolution = 10 * time.Second
+
+func cacheKeyTime(t *time.Time) string {
+	if t == nil {
+		return "*"
+	}
+	return strconv.FormatInt(t.Truncate(cacheKeyResolution).UnixMilli(), 10)
 }
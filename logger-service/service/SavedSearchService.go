@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/repository"
+	"tikube-backend/shared/http_error"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+type SavedSearchService struct {
+	savedSearchRepository repository.SavedSearchRepository
+	loggerService         *LoggerService
+}
+
+func NewSavedSearchService(savedSearchRepository repository.SavedSearchRepository, loggerService *LoggerService) *SavedSearchService {
+	return &SavedSearchService{savedSearchRepository: savedSearchRepository, loggerService: loggerService}
+}
+
+func (ss *SavedSearchService) CreateSearch(ctx context.Context, search utils.SavedSearch) (*utils.SavedSearch, error) {
+	created, err := ss.savedSearchRepository.CreateSearch(ctx, search)
+	if err != nil {
+		return nil, fmt.Errorf("creating saved search: %w", err)
+	}
+	return created, nil
+}
+
+func (ss *SavedSearchService) GetSearches(ctx context.Context, owner string, pagination utils.Pagination) (*utils.PaginationResult[utils.SavedSearch], error) {
+	searches, err := ss.savedSearchRepository.GetSearches(ctx, owner, pagination)
+	if err != nil {
+		return nil, fmt.Errorf("getting saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+func (ss *SavedSearchService) GetSearch(ctx context.Context, id int64) (*utils.SavedSearch, error) {
+	search, err := ss.savedSearchRepository.GetSearch(ctx, id)
+	return search, savedSearchError(err)
+}
+
+func (ss *SavedSearchService) UpdateSearch(ctx context.Context, id int64, search utils.SavedSearch) (*utils.SavedSearch, error) {
+	updated, err := ss.savedSearchRepository.UpdateSearch(ctx, id, search)
+	return updated, savedSearchError(err)
+}
+
+func (ss *SavedSearchService) DeleteSearch(ctx context.Context, id int64) error {
+	return savedSearchError(ss.savedSearchRepository.DeleteSearch(ctx, id))
+}
+
+// RunSearch resolves the time range of a saved search against the current time and fetches the logs
+// with the columns of the search.
+func (ss *SavedSearchService) RunSearch(ctx context.Context, id int64, pagination utils.Pagination) (*utils.SavedSearchResult, error) {
+	search, err := ss.GetSearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := search.Filter
+	if search.TimeRange != nil {
+		dateFilter, err := model.ResolveTimeRange(*search.TimeRange, time.Now())
+		if err != nil {
+			return nil, http_error.BadRequest(err.Error())
+		}
+		filter.DateFilter = dateFilter
+	}
+
+	logs, err := ss.loggerService.GetLogs(ctx, filter, pagination, utils.LogView{Fields: search.Columns})
+	if err != nil {
+		return nil, err
+	}
+	return &utils.SavedSearchResult{Search: search, Logs: logs}, nil
+}
+
+// ShareSearch returns the share token of a search, creating one the first time it is shared.
+func (ss *SavedSearchService) ShareSearch(ctx context.Context, id int64) (*utils.SavedSearch, error) {
+	search, err := ss.GetSearch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if search.ShareToken != "" {
+		return search, nil
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, fmt.Errorf("creating share token: %w", err)
+	}
+	if err := ss.savedSearchRepository.SetShareToken(ctx, id, token); err != nil {
+		return nil, fmt.Errorf("sharing saved search %d: %w", id, err)
+	}
+
+	search.ShareToken = token
+	return search, nil
+}
+
+func (ss *SavedSearchService) ResolveShareToken(ctx context.Context, token string) (*utils.SavedSearch, error) {
+	search, err := ss.savedSearchRepository.GetSearchByShareToken(ctx, token)
+	return search, savedSearchError(err)
+}
+
+// newShareToken returns a short URL safe token with 72 bits of randomness.
+func newShareToken() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func savedSearchError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return http_error.NotFound("Saved search not found")
+	}
+	return fmt.Errorf("saved search: %w", err)
+}
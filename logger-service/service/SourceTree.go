@@ -0,0 +1,53 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// The tree is built from at most this many distinct sources, the most active ones first.
+const maxTreeSources = 10_000
+
+const sourceTreeCacheTTL = time.Minute
+
+// buildSourceTree turns per-source counts into a hierarchy split on the source separator. The root
+// has an empty name and the total count. Children are ordered by count, highest first.
+func buildSourceTree(counts []utils.FacetValue) *utils.SourceNode {
+	root := &utils.SourceNode{}
+	index := map[string]*utils.SourceNode{}
+
+	for _, count := range counts {
+		root.Count += count.Count
+
+		node := root
+		segments := strings.Split(count.Value, utils.SourceSeparator)
+		for i, segment := range segments {
+			path := strings.Join(segments[:i+1], utils.SourceSeparator)
+			child, ok := index[path]
+			if !ok {
+				child = &utils.SourceNode{Name: segment, Path: path}
+				index[path] = child
+				node.Children = append(node.Children, child)
+			}
+			child.Count += count.Count
+			node = child
+		}
+	}
+
+	sortSourceTree(root)
+	return root
+}
+
+func sortSourceTree(node *utils.SourceNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		if node.Children[i].Count != node.Children[j].Count {
+			return node.Children[i].Count > node.Children[j].Count
+		}
+		return node.Children[i].Name < node.Children[j].Name
+	})
+	for _, child := range node.Children {
+		sortSourceTree(child)
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/repository"
+	"tikube-backend/logger-service/timerange"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+func TestFilterCacheKeyRelativeRange(t *testing.T) {
+	key := func(value string, now time.Time) string {
+		t.Helper()
+		dateFilter, err := timerange.Parse(value, now, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filterCacheKey(utils.LogFilter{DateFilter: dateFilter})
+	}
+
+	now := time.Date(2024, 5, 15, 13, 47, 20, 0, time.UTC)
+
+	// The same relative range requested a moment later hits the same entry
+	if first, second := key("now-1h,now", now), key("now-1h,now", now.Add(1500*time.Millisecond)); first != second {
+		t.Errorf("keys differ for requests 1.5s apart: %q and %q", first, second)
+	}
+	if first, second := key("now-15m,", now), key("now-15m,", now.Add(9*time.Second)); first != second {
+		t.Errorf("keys differ for requests 9s apart: %q and %q", first, second)
+	}
+
+	// Spellings of the same range share a key, different ranges do not
+	if relative, absolute := key("now-1h,now", now), key("2024-05-15T12:47:20Z,2024-05-15T13:47:20Z", now); relative != absolute {
+		t.Errorf("relative and absolute spellings differ: %q and %q", relative, absolute)
+	}
+	if first, second := key("now-1h,now", now), key("now-1h,now", now.Add(cacheKeyResolution)); first == second {
+		t.Error("requests a full resolution apart share a key")
+	}
+	if hour, day := key("now-1h,now", now), key("now-1d,now", now); hour == day {
+		t.Error("different ranges share a key")
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		source  string
+		log     string
+		matches bool
+	}{
+		{"", "ANYTHING", true},
+		{"PAYMENTS", "PAYMENTS", true},
+		{"PAYMENTS", "payments", true},
+		{"PAYMENTS", "PAYMENTS:API", false},
+		{"PAYMENTS:*", "PAYMENTS", true},
+		{"PAYMENTS:*", "PAYMENTS:API:EU", true},
+		{"PAYMENTS:*", "PAYMENTSX", false},
+		{"PAY:*", "PAYROLL", false},
+		{"PAY*", "PAYROLL", false},
+		{"payments : api", "PAYMENTS:API", true},
+	}
+
+	for _, tt := range tests {
+		rule := utils.AlertRule{Source: tt.source}
+		if got := ruleMatches(rule, model.CreateLogSchema{Source: tt.log}); got != tt.matches {
+			t.Errorf("rule source %q, log source %q: matches = %v, want %v", tt.source, tt.log, got, tt.matches)
+		}
+	}
+}
+
+// tailRepository serves GetLogs from memory. The logs in stored are added after the first call,
+// as if they were stored while the backlog was being read.
+type tailRepository struct {
+	repository.LoggerRepository
+	logs   []utils.Log
+	stored []utils.Log
+}
+
+func (repo *tailRepository) GetLogs(_ context.Context, filter utils.LogFilter, pagination utils.Pagination, view utils.LogView) (*utils.PaginationResult[utils.Log], error) {
+	var logs []utils.Log
+	for _, log := range repo.logs {
+		if filter.DateFilter == nil || filter.DateFilter.From == nil || !log.CreatedAt.Before(*filter.DateFilter.From) {
+			logs = append(logs, log)
+		}
+	}
+	desc := len(view.Sort) > 0 && view.Sort[0].Desc
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].CreatedAt.Equal(logs[j].CreatedAt) {
+			return logs[i].CreatedAt.Before(logs[j].CreatedAt) != desc
+		}
+		return logs[i].Id < logs[j].Id != desc
+	})
+
+	logs = logs[min(pagination.Offset, len(logs)):]
+	logs = logs[:min(pagination.Limit, len(logs))]
+	repo.logs, repo.stored = append(repo.logs, repo.stored...), nil
+	return &utils.PaginationResult[utils.Log]{Data: logs, Total: len(logs)}, nil
+}
+
+func TestTailLogsBacklogCutoff(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	repo := &tailRepository{}
+	for id := int64(1); id <= 5; id++ {
+		repo.logs = append(repo.logs, utils.Log{Id: id, CreatedAt: now})
+	}
+	// A new log, and one that arrives late with a timestamp inside the lookback window
+	repo.stored = []utils.Log{{Id: 6, CreatedAt: now}, {Id: 7, CreatedAt: now.Add(-5 * time.Second)}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	errDone := errors.New("done")
+	var batches [][]int64
+	ls := &LoggerService{loggerRepository: repo}
+	err := ls.TailLogs(ctx, utils.LogFilter{}, 2, func(logs []utils.Log) error {
+		var ids []int64
+		for _, log := range logs {
+			ids = append(ids, log.Id)
+		}
+		batches = append(batches, ids)
+		if len(batches) == 2 {
+			return errDone
+		}
+		return nil
+	})
+	if !errors.Is(err, errDone) {
+		t.Fatalf("TailLogs returned %v after %v", err, batches)
+	}
+
+	// Logs 1 to 3 existed before the tail but are older than the backlog, a poll must not send them
+	want := [][]int64{{4, 5}, {7, 6}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+}
@@ -0,0 +1,82 @@
+package syslog
+
+import (
+	"strings"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/shared/utils"
+)
+
+// Used when a message names neither its host nor its application.
+const unknownSource = "syslog"
+
+var facilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// ToLog converts a syslog message. The source is HOSTNAME:APP-NAME, the other header fields and the
+// structured data are kept as syslog.* attributes.
+func ToLog(msg *Message) model.CreateLogSchema {
+	log := model.CreateLogSchema{
+		LogLevel:  levelOf(msg.Severity),
+		Source:    sourceOf(msg),
+		Message:   strings.TrimRight(msg.Message, "\r\n\x00"),
+		Timestamp: msg.Timestamp,
+	}
+
+	attributes := map[string]any{
+		"syslog.facility": facilities[msg.Facility],
+		"syslog.severity": msg.Severity,
+	}
+	addAttribute(attributes, "syslog.hostname", msg.Hostname)
+	addAttribute(attributes, "syslog.appname", msg.AppName)
+	addAttribute(attributes, "syslog.procid", msg.ProcId)
+	addAttribute(attributes, "syslog.msgid", msg.MsgId)
+	for _, element := range msg.StructuredData {
+		for _, param := range element.Params {
+			// SD-IDs such as exampleSDID@32473 contain characters attribute keys don't allow
+			addAttribute(attributes, utils.SanitizeAttributeKey("syslog.sd."+element.Id+"."+param.Name), param.Value)
+		}
+	}
+	log.Attributes = attributes
+
+	return log
+}
+
+// levelOf maps the syslog severities: emergency, alert and critical are FATAL, notice and
+// informational are INFO.
+func levelOf(severity int) utils.LogLevel {
+	switch {
+	case severity <= 2:
+		return utils.FATAL
+	case severity == 3:
+		return utils.ERROR
+	case severity == 4:
+		return utils.WARN
+	case severity <= 6:
+		return utils.INFO
+	}
+	return utils.DEBUG
+}
+
+func sourceOf(msg *Message) string {
+	var segments []string
+	for _, segment := range []string{msg.Hostname, msg.AppName} {
+		// IPv6 hostnames would otherwise be split into several levels of the hierarchy
+		if segment = strings.ReplaceAll(segment, utils.SourceSeparator, "_"); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	if len(segments) == 0 {
+		return unknownSource
+	}
+	return strings.Join(segments, utils.SourceSeparator)
+}
+
+// addAttribute skips empty values, the nil value of syslog header fields.
+func addAttribute(attributes map[string]any, key string, value string) {
+	if value != "" {
+		model.AddAttribute(attributes, key, value)
+	}
+}
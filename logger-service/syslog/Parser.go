@@ -0,0 +1,241 @@
+// Package syslog receives RFC 5424 and RFC 3164 messages over TCP, TLS and UDP and turns them into logs.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const nilValue = "-"
+
+// RFC 5424 messages may start with a byte order mark to flag UTF-8 content.
+var utf8BOM = []byte("\xEF\xBB\xBF")
+
+// Message is a parsed syslog message. Fields missing from the message are left empty.
+type Message struct {
+	Facility       int
+	Severity       int
+	Timestamp      *time.Time
+	Hostname       string
+	AppName        string
+	ProcId         string
+	MsgId          string
+	StructuredData []Element
+	Message        string
+}
+
+// Element is an RFC 5424 structured data element such as [exampleSDID@32473 iut="3"].
+type Element struct {
+	Id     string
+	Params []Param
+}
+
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Parse parses an RFC 5424 message, or an RFC 3164 one when the header has no version.
+// RFC 3164 timestamps have no year or zone, they are read in loc and in the year closest to now.
+func Parse(data []byte, now time.Time, loc *time.Location) (*Message, error) {
+	priority, rest, err := parsePriority(data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &Message{Facility: priority / 8, Severity: priority % 8}
+	if version, ok := bytes.CutPrefix(rest, []byte("1 ")); ok {
+		err = parseRFC5424(msg, version)
+	} else {
+		parseRFC3164(msg, rest, now, loc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func parsePriority(data []byte) (int, []byte, error) {
+	if len(data) == 0 || data[0] != '<' {
+		return 0, nil, errors.New("message must start with <PRI>")
+	}
+
+	end := bytes.IndexByte(data, '>')
+	if end < 2 || end > 4 {
+		return 0, nil, errors.New("invalid PRI")
+	}
+	priority, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, nil, errors.New("PRI must be between 0 and 191")
+	}
+	return priority, data[end+1:], nil
+}
+
+// parseRFC5424 parses what follows "<PRI>1 ": TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(msg *Message, data []byte) error {
+	var fields [5]string
+	for i := range fields {
+		field, rest, ok := bytes.Cut(data, []byte(" "))
+		if !ok || len(field) == 0 {
+			return errors.New("RFC 5424 header is incomplete")
+		}
+		fields[i], data = string(field), rest
+	}
+
+	if fields[0] != nilValue {
+		timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid timestamp %q", fields[0])
+		}
+		timestamp = timestamp.UTC()
+		msg.Timestamp = &timestamp
+	}
+	msg.Hostname = optional(fields[1])
+	msg.AppName = optional(fields[2])
+	msg.ProcId = optional(fields[3])
+	msg.MsgId = optional(fields[4])
+
+	if rest, ok := bytes.CutPrefix(data, []byte(nilValue)); ok {
+		data = rest
+	} else {
+		elements, rest, err := parseStructuredData(data)
+		if err != nil {
+			return err
+		}
+		msg.StructuredData, data = elements, rest
+	}
+
+	if len(data) > 0 {
+		if data[0] != ' ' {
+			return errors.New("expected a space after the structured data")
+		}
+		msg.Message = string(bytes.TrimPrefix(data[1:], utf8BOM))
+	}
+	return nil
+}
+
+// parseStructuredData parses one or more [SD-ID PARAM-NAME="PARAM-VALUE" ...] elements. Values may
+// escape ", \ and ] with a backslash.
+func parseStructuredData(data []byte) ([]Element, []byte, error) {
+	if len(data) == 0 || data[0] != '[' {
+		return nil, nil, errors.New("expected structured data or -")
+	}
+
+	var elements []Element
+	for len(data) > 0 && data[0] == '[' {
+		end := bytes.IndexAny(data, " ]")
+		if end <= 1 {
+			return nil, nil, errors.New("structured data element has no id")
+		}
+		element := Element{Id: string(data[1:end])}
+		data = data[end:]
+
+		for len(data) > 0 && data[0] == ' ' {
+			eq := bytes.IndexByte(data, '=')
+			if eq <= 1 || eq+1 >= len(data) || data[eq+1] != '"' {
+				return nil, nil, fmt.Errorf("invalid parameter in structured data element %q", element.Id)
+			}
+			name := string(data[1:eq])
+			value, rest, err := parseParamValue(data[eq+2:])
+			if err != nil {
+				return nil, nil, err
+			}
+			element.Params = append(element.Params, Param{Name: name, Value: value})
+			data = rest
+		}
+
+		if len(data) == 0 || data[0] != ']' {
+			return nil, nil, fmt.Errorf("structured data element %q is not closed", element.Id)
+		}
+		elements = append(elements, element)
+		data = data[1:]
+	}
+	return elements, data, nil
+}
+
+// parseParamValue reads a parameter value up to its closing quote and returns what follows it.
+func parseParamValue(data []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			// Only ", \ and ] are escaped, a backslash before anything else is kept
+			if i+1 < len(data) && (data[i+1] == '"' || data[i+1] == '\\' || data[i+1] == ']') {
+				i++
+			}
+			value = append(value, data[i])
+		case '"':
+			return string(value), data[i+1:], nil
+		default:
+			value = append(value, data[i])
+		}
+	}
+	return "", nil, errors.New("unterminated structured data value")
+}
+
+// parseRFC3164 parses what follows "<PRI>": TIMESTAMP HOSTNAME TAG[PID]: MSG. RFC 3164 only describes
+// what was observed in the wild, so every part but PRI is optional and anything unrecognised is
+// kept in the message.
+func parseRFC3164(msg *Message, data []byte, now time.Time, loc *time.Location) {
+	if timestamp, rest, ok := parseBSDTimestamp(data, now, loc); ok {
+		msg.Timestamp = &timestamp
+		data = rest
+
+		// Without a timestamp there is no reliable way to tell the hostname from the tag
+		if field, rest, ok := bytes.Cut(data, []byte(" ")); ok && len(field) > 0 && !isTag(field) {
+			msg.Hostname = string(field)
+			data = rest
+		}
+	}
+
+	if tag, rest, ok := bytes.Cut(data, []byte(":")); ok && len(tag) > 0 && len(tag) <= 48 && !bytes.ContainsAny(tag, " \t") {
+		name, pid, hasPid := bytes.Cut(tag, []byte("["))
+		if !hasPid || bytes.HasSuffix(pid, []byte("]")) {
+			msg.AppName = string(name)
+			if hasPid {
+				msg.ProcId = string(pid[:len(pid)-1])
+			}
+			data = bytes.TrimPrefix(rest, []byte(" "))
+		}
+	}
+
+	msg.Message = string(data)
+}
+
+// isTag reports whether a header field is a TAG rather than a HOSTNAME, as in "sshd[42]: ..." sent without a hostname.
+func isTag(field []byte) bool {
+	return bytes.HasSuffix(field, []byte(":")) || bytes.IndexByte(field, '[') >= 0
+}
+
+// parseBSDTimestamp reads an "Mmm dd hh:mm:ss" timestamp. Senders that use RFC3339 timestamps in
+// RFC 3164 messages, like rsyslog's high precision format, are understood as well.
+func parseBSDTimestamp(data []byte, now time.Time, loc *time.Location) (time.Time, []byte, bool) {
+	if len(data) > len(time.Stamp) && data[len(time.Stamp)] == ' ' {
+		if t, err := time.ParseInLocation(time.Stamp, string(data[:len(time.Stamp)]), loc); err == nil {
+			now = now.In(loc)
+			t = time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+			// A message from December received in January belongs to the previous year
+			if t.After(now.Add(24 * time.Hour)) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t.UTC(), data[len(time.Stamp)+1:], true
+		}
+	}
+
+	if field, rest, ok := bytes.Cut(data, []byte(" ")); ok {
+		if t, err := time.Parse(time.RFC3339Nano, string(field)); err == nil {
+			return t.UTC(), rest, true
+		}
+	}
+	return time.Time{}, data, false
+}
+
+func optional(field string) string {
+	if field == nilValue {
+		return ""
+	}
+	return field
+}
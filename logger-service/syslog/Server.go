@@ -0,0 +1,339 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"tikube-backend/logger-service/model"
+	"tikube-backend/logger-service/timerange"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+const (
+	// Largest message accepted on any transport, UDP datagrams cannot be larger anyway.
+	maxMessageSize = 64 * 1024
+	// TCP connections without any traffic for this long are closed.
+	idleTimeout = 5 * time.Minute
+)
+
+// Publisher is the ingestion path shared with the HTTP endpoints, implemented by LoggerService.
+type Publisher interface {
+	PublishLogs(logs []model.CreateLogSchema) (int, error)
+}
+
+type Config struct {
+	TCPAddr string
+	UDPAddr string
+	// TLS is used for the TCP listener when set.
+	TLS *tls.Config
+	// Location of the RFC 3164 timestamps, which carry no zone.
+	Location *time.Location
+}
+
+// ConfigFromEnv reads SYSLOG_TCP_ADDR, SYSLOG_UDP_ADDR, SYSLOG_TLS_CERT_FILE, SYSLOG_TLS_KEY_FILE and
+// SYSLOG_TIMEZONE. The listener is disabled when neither address is set.
+func ConfigFromEnv() (Config, error) {
+	config := Config{TCPAddr: os.Getenv("SYSLOG_TCP_ADDR"), UDPAddr: os.Getenv("SYSLOG_UDP_ADDR")}
+
+	certFile, keyFile := os.Getenv("SYSLOG_TLS_CERT_FILE"), os.Getenv("SYSLOG_TLS_KEY_FILE")
+	if certFile != "" || keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return config, fmt.Errorf("loading syslog TLS certificate: %w", err)
+		}
+		config.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}, MinVersion: tls.VersionTLS12}
+	}
+
+	location, err := timerange.LoadLocation(os.Getenv("SYSLOG_TIMEZONE"))
+	if err != nil {
+		return config, err
+	}
+	config.Location = location
+
+	return config, nil
+}
+
+type Server struct {
+	config    Config
+	publisher Publisher
+
+	received      atomic.Uint64
+	published     atomic.Uint64
+	rejected      atomic.Uint64
+	parseErrors   atomic.Uint64
+	framingErrors atomic.Uint64
+
+	mu       sync.Mutex
+	closed   bool
+	listener net.Listener
+	packet   net.PacketConn
+	conns    map[net.Conn]struct{}
+}
+
+func NewServer(config Config, publisher Publisher) *Server {
+	if config.Location == nil {
+		config.Location = time.UTC
+	}
+	return &Server{config: config, publisher: publisher, conns: map[net.Conn]struct{}{}}
+}
+
+func (s *Server) Name() string {
+	return "syslog"
+}
+
+// Enabled reports whether a TCP or UDP address is configured.
+func (s *Server) Enabled() bool {
+	return s.config.TCPAddr != "" || s.config.UDPAddr != ""
+}
+
+// Stats returns the message counters since the server started.
+func (s *Server) Stats() utils.SyslogStats {
+	return utils.SyslogStats{
+		Enabled:       s.Enabled(),
+		Received:      s.received.Load(),
+		Published:     s.published.Load(),
+		Rejected:      s.rejected.Load(),
+		ParseErrors:   s.parseErrors.Load(),
+		FramingErrors: s.framingErrors.Load(),
+	}
+}
+
+// ListenAndServe listens on the configured addresses and blocks until Close is called, after which
+// it returns utils.ErrListenerClosed.
+func (s *Server) ListenAndServe() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return utils.ErrListenerClosed
+	}
+
+	if s.config.TCPAddr != "" {
+		listener, err := net.Listen("tcp", s.config.TCPAddr)
+		if err != nil {
+			s.mu.Unlock()
+			return err
+		}
+		if s.config.TLS != nil {
+			listener = tls.NewListener(listener, s.config.TLS)
+		}
+		s.listener = listener
+	}
+	if s.config.UDPAddr != "" {
+		packet, err := net.ListenPacket("udp", s.config.UDPAddr)
+		if err != nil {
+			if s.listener != nil {
+				_ = s.listener.Close()
+			}
+			s.mu.Unlock()
+			return err
+		}
+		s.packet = packet
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	if s.listener != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveTCP(s.listener)
+		}()
+	}
+	if s.packet != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveUDP(s.packet)
+		}()
+	}
+	wg.Wait()
+
+	return utils.ErrListenerClosed
+}
+
+// Close stops the listeners and closes the open TCP connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = errors.Join(err, s.listener.Close())
+	}
+	if s.packet != nil {
+		err = errors.Join(err, s.packet.Close())
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	return err
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) serveTCP(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return
+			}
+			log.Printf("Error accepting syslog connection: %v", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		if !s.track(conn) {
+			_ = conn.Close()
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	reader := bufio.NewReaderSize(conn, maxMessageSize)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		frame, err := readFrame(reader)
+		if err != nil {
+			// A broken frame leaves the stream out of sync, the sender has to reconnect
+			if !errors.Is(err, io.EOF) && !s.isClosed() && !isTimeout(err) {
+				s.framingErrors.Add(1)
+			}
+			return
+		}
+		if len(frame) > 0 {
+			s.handle(frame, conn.RemoteAddr())
+		}
+	}
+}
+
+// readFrame reads one message framed as described in RFC 6587: octet counting ("LEN SP MSG") when
+// the frame starts with a digit, otherwise a message terminated by LF or NUL.
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '0' && first[0] <= '9' {
+		prefix, err := reader.ReadSlice(' ')
+		if err != nil {
+			return nil, frameError(err)
+		}
+		length, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+		if err != nil || length <= 0 || length > maxMessageSize {
+			return nil, errors.New("invalid syslog frame length")
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(reader, frame); err != nil {
+			return nil, frameError(err)
+		}
+		return frame, nil
+	}
+
+	var frame []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(frame) > 0 {
+				// The last message of a stream doesn't need a trailer
+				return frame, nil
+			}
+			return nil, err
+		}
+		if b == '\n' || b == 0 {
+			return frame, nil
+		}
+		if len(frame) >= maxMessageSize {
+			return nil, errors.New("syslog message is too large")
+		}
+		frame = append(frame, b)
+	}
+}
+
+// frameError turns an end of stream in the middle of a frame into an error of its own.
+func frameError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func (s *Server) serveUDP(packet net.PacketConn) {
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := packet.ReadFrom(buf)
+		if err != nil {
+			if s.isClosed() {
+				return
+			}
+			log.Printf("Error reading syslog datagram: %v", err)
+			continue
+		}
+		if n > 0 {
+			s.handle(buf[:n], addr)
+		}
+	}
+}
+
+// handle parses a message and publishes it through the same validation and Kafka path as the HTTP
+// endpoints. Messages without a hostname are attributed to the address of the sender, as RFC 3164
+// asks relays to do.
+func (s *Server) handle(frame []byte, remote net.Addr) {
+	s.received.Add(1)
+
+	msg, err := Parse(frame, time.Now(), s.config.Location)
+	if err != nil {
+		s.parseErrors.Add(1)
+		return
+	}
+	if msg.Hostname == "" && remote != nil {
+		if host, _, err := net.SplitHostPort(remote.String()); err == nil {
+			msg.Hostname = host
+		}
+	}
+
+	if rejected, _ := s.publisher.PublishLogs([]model.CreateLogSchema{ToLog(msg)}); rejected > 0 {
+		s.rejected.Add(1)
+		return
+	}
+	s.published.Add(1)
+}
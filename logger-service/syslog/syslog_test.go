@@ -0,0 +1,319 @@
+package syslog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+func timestamp(t time.Time) *time.Time {
+	return &t
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		line string
+		want Message
+	}{
+		// RFC 5424, as sent by rsyslog's RSYSLOG_SyslogProtocol23Format and syslog-ng's syslog() driver
+		{
+			"rfc 5424 with structured data",
+			`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"] An application event log entry...`,
+			Message{
+				Facility: 20, Severity: 5, Timestamp: timestamp(time.Date(2003, 10, 11, 22, 14, 15, 3_000_000, time.UTC)),
+				Hostname: "mymachine.example.com", AppName: "evntslog", MsgId: "ID47",
+				StructuredData: []Element{{Id: "exampleSDID@32473", Params: []Param{
+					{"iut", "3"}, {"eventSource", "Application"}, {"eventID", "1011"},
+				}}},
+				Message: "An application event log entry...",
+			},
+		},
+		{
+			"rfc 5424 with a bom",
+			"<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - \xEF\xBB\xBF'su root' failed for lonvick on /dev/pts/8",
+			Message{
+				Facility: 4, Severity: 2, Timestamp: timestamp(time.Date(2003, 10, 11, 22, 14, 15, 3_000_000, time.UTC)),
+				Hostname: "mymachine.example.com", AppName: "su", MsgId: "ID47",
+				Message: "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			"rfc 5424 with an offset",
+			`<13>1 2024-05-15T15:47:21.123456+02:00 web01 nginx 1234 - [timeQuality tzKnown="1" isSynced="1"][origin ip="10.0.0.1"] GET /health`,
+			Message{
+				Facility: 1, Severity: 5, Timestamp: timestamp(time.Date(2024, 5, 15, 13, 47, 21, 123_456_000, time.UTC)),
+				Hostname: "web01", AppName: "nginx", ProcId: "1234",
+				StructuredData: []Element{
+					{Id: "timeQuality", Params: []Param{{"tzKnown", "1"}, {"isSynced", "1"}}},
+					{Id: "origin", Params: []Param{{"ip", "10.0.0.1"}}},
+				},
+				Message: "GET /health",
+			},
+		},
+		{
+			"sd-param escapes",
+			`<14>1 2024-05-15T13:47:21Z host app - - [meta path="C:\\temp" quote="say \"hi\"" bracket="a\]b" other="a\nb"] escaped`,
+			Message{
+				Facility: 1, Severity: 6, Timestamp: timestamp(now),
+				Hostname: "host", AppName: "app",
+				StructuredData: []Element{{Id: "meta", Params: []Param{
+					{"path", `C:\temp`}, {"quote", `say "hi"`}, {"bracket", "a]b"}, {"other", `a\nb`},
+				}}},
+				Message: "escaped",
+			},
+		},
+		{"nil values", "<14>1 - - - - - -", Message{Facility: 1, Severity: 6}},
+		{"nil values with a message", "<14>1 - - - - - - hello", Message{Facility: 1, Severity: 6, Message: "hello"}},
+
+		// RFC 3164, as sent by rsyslog's traditional formats, syslog-ng's network() driver and logger(1)
+		{
+			"rfc 3164",
+			`<34>Oct 11 22:14:15 mymachine su: 'su root' failed for lonvick on /dev/pts/8`,
+			Message{
+				Facility: 4, Severity: 2, Timestamp: timestamp(time.Date(2023, 10, 11, 22, 14, 15, 0, time.UTC)),
+				Hostname: "mymachine", AppName: "su",
+				Message: "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			"rfc 3164 with a pid and a padded day",
+			`<38>May  5 09:01:02 host01 sshd[4242]: Accepted publickey for root from 10.0.0.2 port 51234 ssh2`,
+			Message{
+				Facility: 4, Severity: 6, Timestamp: timestamp(time.Date(2024, 5, 5, 9, 1, 2, 0, time.UTC)),
+				Hostname: "host01", AppName: "sshd", ProcId: "4242",
+				Message: "Accepted publickey for root from 10.0.0.2 port 51234 ssh2",
+			},
+		},
+		{
+			"rfc 3164 without a hostname",
+			`<13>May 15 13:40:00 sshd[4242]: Connection closed`,
+			Message{
+				Facility: 1, Severity: 5, Timestamp: timestamp(time.Date(2024, 5, 15, 13, 40, 0, 0, time.UTC)),
+				AppName: "sshd", ProcId: "4242", Message: "Connection closed",
+			},
+		},
+		{
+			"rfc 3164 with an rfc 3339 timestamp",
+			`<30>2024-05-15T15:47:21.123456+02:00 host01 systemd[1]: Started Session 42 of user root.`,
+			Message{
+				Facility: 3, Severity: 6, Timestamp: timestamp(time.Date(2024, 5, 15, 13, 47, 21, 123_456_000, time.UTC)),
+				Hostname: "host01", AppName: "systemd", ProcId: "1",
+				Message: "Started Session 42 of user root.",
+			},
+		},
+		{"rfc 3164 without a header", "<13>hello world", Message{Facility: 1, Severity: 5, Message: "hello world"}},
+		{"rfc 3164 with a sentence", "<13>note: disk is full", Message{Facility: 1, Severity: 5, AppName: "note", Message: "disk is full"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.line), now, time.UTC)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned %v", tt.line, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.line, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"no pri", "hello"},
+		{"empty pri", "<>1 - - - - - -"},
+		{"pri too large", "<192>1 - - - - - -"},
+		{"incomplete header", "<13>1 2024-05-15T13:47:21Z host app"},
+		{"invalid timestamp", "<13>1 2024-05-15 host app - - - hello"},
+		{"unterminated element", `<13>1 - host app - - [meta a="1" hello`},
+		{"unterminated value", `<13>1 - host app - - [meta a="1]`},
+		{"element without id", `<13>1 - host app - - [ a="1"]`},
+		{"unquoted value", `<13>1 - host app - - [meta a=1]`},
+		{"no space before the message", `<13>1 - host app - - [meta a="1"]hello`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Parse([]byte(tt.line), time.Now(), time.UTC); err == nil {
+				t.Errorf("Parse(%q) = %+v, want an error", tt.line, *got)
+			}
+		})
+	}
+}
+
+func TestParseBSDTimestamp(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		now   time.Time
+		loc   *time.Location
+		want  time.Time
+	}{
+		{"same year", "May 15 13:47:21", time.Date(2024, 5, 15, 14, 0, 0, 0, time.UTC), time.UTC, time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)},
+		{"december in january", "Dec 31 23:59:59", time.Date(2024, 1, 1, 0, 0, 30, 0, time.UTC), time.UTC, time.Date(2023, 12, 31, 23, 59, 59, 0, time.UTC)},
+		// A sender with a clock slightly ahead stays in the current year
+		{"slightly ahead", "Dec 31 23:59:59", time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), time.UTC, time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{"more than a day ahead", "May 17 00:00:00", time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), time.UTC, time.Date(2023, 5, 17, 0, 0, 0, 0, time.UTC)},
+		// The year is the one of now in loc, which is still 2023 in New York
+		{"year in loc", "Dec 31 20:00:00", time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), newYork, time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)},
+		{"leap day", "Feb 29 12:00:00", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.UTC, time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, ok := parseBSDTimestamp([]byte(tt.value+" host app: hello"), tt.now, tt.loc)
+			if !ok {
+				t.Fatalf("parseBSDTimestamp(%q) found no timestamp", tt.value)
+			}
+			if !got.Equal(tt.want) || got.Location() != time.UTC {
+				t.Errorf("parseBSDTimestamp(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+			if string(rest) != "host app: hello" {
+				t.Errorf("parseBSDTimestamp(%q) left %q", tt.value, rest)
+			}
+		})
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	// Octet counting keeps the line breaks of multi-line messages, the other frames end at LF or NUL
+	multiLine := "<11>1 2024-05-15T13:47:21Z host app - - - panic: boom\ngoroutine 1 [running]:"
+	stream := fmt.Sprintf("%d %s", len(multiLine), multiLine) +
+		"<13>May 15 13:47:21 host app: lf terminated\n" +
+		"<13>May 15 13:47:21 host app: nul terminated\x00" +
+		"\n" +
+		"<13>May 15 13:47:21 host app: last"
+
+	want := []string{
+		multiLine,
+		"<13>May 15 13:47:21 host app: lf terminated",
+		"<13>May 15 13:47:21 host app: nul terminated",
+		"",
+		"<13>May 15 13:47:21 host app: last",
+	}
+
+	reader := bufio.NewReader(strings.NewReader(stream))
+	for _, frame := range want {
+		got, err := readFrame(reader)
+		if err != nil {
+			t.Fatalf("readFrame returned %v, want %q", err, frame)
+		}
+		if string(got) != frame {
+			t.Errorf("readFrame = %q, want %q", got, frame)
+		}
+	}
+	if got, err := readFrame(reader); !errors.Is(err, io.EOF) {
+		t.Errorf("readFrame at the end of the stream = %q, %v, want io.EOF", got, err)
+	}
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		want   error
+	}{
+		{"truncated frame", "20 <13>1 - host", io.ErrUnexpectedEOF},
+		{"truncated length", "20", io.ErrUnexpectedEOF},
+		{"zero length", "0 <13>hello", nil},
+		{"length too large", "70000 <13>hello", nil},
+		{"invalid length", "12a <13>hello", nil},
+		{"message too large", strings.Repeat("a", maxMessageSize+1) + "\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readFrame(bufio.NewReader(strings.NewReader(tt.stream)))
+			if err == nil || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("readFrame = %q, %v, want an error", got, err)
+			}
+		})
+	}
+}
+
+func TestToLog(t *testing.T) {
+	seen := time.Date(2003, 10, 11, 22, 14, 15, 3_000_000, time.UTC)
+
+	tests := []struct {
+		name       string
+		msg        Message
+		level      utils.LogLevel
+		source     string
+		message    string
+		attributes map[string]any
+	}{
+		{
+			"rfc 5424",
+			Message{
+				Facility: 20, Severity: 5, Timestamp: &seen,
+				Hostname: "mymachine.example.com", AppName: "evntslog", MsgId: "ID47",
+				StructuredData: []Element{{Id: "exampleSDID@32473", Params: []Param{{"iut", "3"}, {"eventSource", "Application"}}}},
+				Message:        "An application event log entry...",
+			},
+			utils.INFO, "mymachine.example.com:evntslog", "An application event log entry...",
+			map[string]any{
+				"syslog.facility":                         "local4",
+				"syslog.severity":                         5,
+				"syslog.hostname":                         "mymachine.example.com",
+				"syslog.appname":                          "evntslog",
+				"syslog.msgid":                            "ID47",
+				"syslog.sd.exampleSDID_32473.iut":         "3",
+				"syslog.sd.exampleSDID_32473.eventSource": "Application",
+			},
+		},
+		{
+			"rfc 3164",
+			Message{Facility: 4, Severity: 3, Hostname: "host01", AppName: "sshd", ProcId: "4242", Message: "error: PAM: Authentication failure\r\n"},
+			utils.ERROR, "host01:sshd", "error: PAM: Authentication failure",
+			map[string]any{
+				"syslog.facility": "auth",
+				"syslog.severity": 3,
+				"syslog.hostname": "host01",
+				"syslog.appname":  "sshd",
+				"syslog.procid":   "4242",
+			},
+		},
+		{
+			"ipv6 hostname",
+			Message{Facility: 0, Severity: 0, Hostname: "fe80::1", Message: "panic"},
+			utils.FATAL, "fe80__1", "panic",
+			map[string]any{"syslog.facility": "kern", "syslog.severity": 0, "syslog.hostname": "fe80::1"},
+		},
+		{
+			"no source",
+			Message{Facility: 23, Severity: 7, Message: "trace"},
+			utils.DEBUG, "syslog", "trace",
+			map[string]any{"syslog.facility": "local7", "syslog.severity": 7},
+		},
+		{"warning", Message{Facility: 1, Severity: 4}, utils.WARN, "syslog", "", map[string]any{"syslog.facility": "user", "syslog.severity": 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ToLog(&tt.msg)
+			if got.LogLevel != tt.level || got.Source != tt.source || got.Message != tt.message || got.Timestamp != tt.msg.Timestamp {
+				t.Errorf("ToLog = %v %q %q %v, want %v %q %q %v", got.LogLevel, got.Source, got.Message, got.Timestamp, tt.level, tt.source, tt.message, tt.msg.Timestamp)
+			}
+			if !reflect.DeepEqual(got.Attributes, tt.attributes) {
+				t.Errorf("ToLog attributes = %v, want %v", got.Attributes, tt.attributes)
+			}
+		})
+	}
+}
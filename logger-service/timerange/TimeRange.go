@@ -0,0 +1,238 @@
+// Package timerange parses the time ranges accepted by date_filter and the time field of LQL queries.
+package timerange
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"tikube-backend/shared/utils"
+	"time"
+	_ "time/tzdata" // the tz parameter must work in images without a zoneinfo database
+)
+
+// Layouts without a zone are interpreted in the location passed to the parser.
+var localLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// Error describes why a time or range could not be parsed. Position is 1-based and 0 when the
+// whole value is at fault.
+type Error struct {
+	Value    string
+	Position int
+	Message  string
+	IsRange  bool
+}
+
+func (e *Error) Error() string {
+	kind := "time"
+	if e.IsRange {
+		kind = "range"
+	}
+	if e.Position > 0 {
+		return fmt.Sprintf("invalid %s %q at position %d: %s", kind, e.Value, e.Position, e.Message)
+	}
+	return fmt.Sprintf("invalid %s %q: %s", kind, e.Value, e.Message)
+}
+
+// LoadLocation resolves the tz parameter. An empty name means UTC.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Parse parses a "from,to" range. Either side may be left empty for an open range, e.g. "now-1h,".
+// Both ends are returned in UTC.
+func Parse(value string, now time.Time, loc *time.Location) (*utils.DateFilterRange, error) {
+	fromStr, toStr, ok := strings.Cut(value, ",")
+	if !ok || strings.Contains(toStr, ",") {
+		return nil, &Error{Value: value, IsRange: true, Message: "expected from,to, leave one side empty for an open range"}
+	}
+
+	fromStr, toStr = strings.TrimSpace(fromStr), strings.TrimSpace(toStr)
+	if fromStr == "" && toStr == "" {
+		return nil, &Error{Value: value, IsRange: true, Message: "at least one end of the range is required"}
+	}
+
+	var result utils.DateFilterRange
+	if fromStr != "" {
+		from, err := ParseTime(fromStr, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		result.From = &from
+	}
+	if toStr != "" {
+		to, err := ParseTime(toStr, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		result.To = &to
+	}
+
+	if result.From != nil && result.To != nil && result.To.Before(*result.From) {
+		return nil, &Error{Value: value, IsRange: true, Message: "from must not be after to"}
+	}
+	return &result, nil
+}
+
+// ParseTime parses a single point in time and returns it in UTC. Accepted forms are RFC3339, Unix
+// epoch milliseconds, the zone-less layouts in localLayouts and relative expressions: "now"
+// followed by any number of offsets (+1h, -15m) and roundings (/d), e.g. "now-1d/d".
+// Units are s, m, h, d, w, M (month) and y.
+func ParseTime(value string, now time.Time, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, &Error{Value: value, Message: "empty time"}
+	}
+
+	if strings.HasPrefix(value, "now") {
+		return parseDateMath(value, now.In(loc))
+	}
+
+	if isDigits(value) {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || ms > maxEpochMillis {
+			return time.Time{}, &Error{Value: value, Message: "epoch milliseconds out of range"}
+		}
+		return time.UnixMilli(ms).UTC(), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return checkRange(value, 0, t)
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return checkRange(value, 0, t)
+		}
+	}
+
+	return time.Time{}, &Error{Value: value, Message: "expected RFC3339, epoch milliseconds, 2006-01-02 15:04:05 or a relative time such as now-15m"}
+}
+
+// The range of a MySQL DATETIME, times outside of it can't be bound to a query.
+var (
+	minTime = time.Date(1000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	maxTime = time.Date(9999, time.December, 31, 23, 59, 59, 999_999_999, time.UTC)
+)
+
+// maxTime in epoch milliseconds, larger values are rejected before they are converted.
+const maxEpochMillis = 253402300799999
+
+// checkRange returns t in UTC, or an error at position when it is outside of what MySQL can store.
+func checkRange(value string, position int, t time.Time) (time.Time, error) {
+	if t.Before(minTime) || t.After(maxTime) {
+		return time.Time{}, &Error{Value: value, Position: position, Message: "time is outside of 1000-01-01 to 9999-12-31"}
+	}
+	return t.UTC(), nil
+}
+
+// parseDateMath applies the offsets and roundings that follow "now" to t. Calendar units are
+// applied in t's location so that /d rounds to midnight in the requested time zone.
+func parseDateMath(value string, t time.Time) (time.Time, error) {
+	i := len("now")
+	for i < len(value) {
+		opPos := i + 1
+		op := value[i]
+		if op != '+' && op != '-' && op != '/' {
+			return time.Time{}, &Error{Value: value, Position: i + 1, Message: "expected +, - or /"}
+		}
+		i++
+
+		amount := 0
+		if op != '/' {
+			start := i
+			for i < len(value) && value[i] >= '0' && value[i] <= '9' {
+				i++
+			}
+			if i == start {
+				return time.Time{}, &Error{Value: value, Position: i + 1, Message: fmt.Sprintf("expected a number after %c", op)}
+			}
+			n, err := strconv.Atoi(value[start:i])
+			if err != nil || n > 1_000_000 {
+				return time.Time{}, &Error{Value: value, Position: start + 1, Message: "offset is too large"}
+			}
+			amount = n
+			if op == '-' {
+				amount = -n
+			}
+		}
+
+		if i >= len(value) {
+			return time.Time{}, &Error{Value: value, Position: i + 1, Message: "expected a unit (s, m, h, d, w, M, y)"}
+		}
+		unit := value[i]
+
+		var ok bool
+		if op == '/' {
+			t, ok = roundDown(t, unit)
+		} else {
+			t, ok = addUnits(t, amount, unit)
+		}
+		if !ok {
+			return time.Time{}, &Error{Value: value, Position: i + 1, Message: fmt.Sprintf("unknown unit %q, expected s, m, h, d, w, M or y", unit)}
+		}
+		// Checked at every step, the error points at the offset that leaves the range
+		if _, err := checkRange(value, opPos, t); err != nil {
+			return time.Time{}, err
+		}
+		i++
+	}
+	return t.UTC(), nil
+}
+
+func addUnits(t time.Time, amount int, unit byte) (time.Time, bool) {
+	switch unit {
+	case 's':
+		return t.Add(time.Duration(amount) * time.Second), true
+	case 'm':
+		return t.Add(time.Duration(amount) * time.Minute), true
+	case 'h':
+		return t.Add(time.Duration(amount) * time.Hour), true
+	case 'd':
+		return t.AddDate(0, 0, amount), true
+	case 'w':
+		return t.AddDate(0, 0, 7*amount), true
+	case 'M':
+		return t.AddDate(0, amount, 0), true
+	case 'y':
+		return t.AddDate(amount, 0, 0), true
+	}
+	return t, false
+}
+
+// roundDown truncates t to the start of the unit. Weeks start on Monday.
+func roundDown(t time.Time, unit byte) (time.Time, bool) {
+	y, mo, d := t.Date()
+	loc := t.Location()
+	switch unit {
+	case 's':
+		return time.Date(y, mo, d, t.Hour(), t.Minute(), t.Second(), 0, loc), true
+	case 'm':
+		return time.Date(y, mo, d, t.Hour(), t.Minute(), 0, 0, loc), true
+	case 'h':
+		return time.Date(y, mo, d, t.Hour(), 0, 0, 0, loc), true
+	case 'd':
+		return time.Date(y, mo, d, 0, 0, 0, 0, loc), true
+	case 'w':
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, mo, d-offset, 0, 0, 0, 0, loc), true
+	case 'M':
+		return time.Date(y, mo, 1, 0, 0, 0, 0, loc), true
+	case 'y':
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, loc), true
+	}
+	return t, false
+}
+
+func isDigits(value string) bool {
+	for i := 0; i < len(value); i++ {
+		if value[i] < '0' || value[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
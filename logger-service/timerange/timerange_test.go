@@ -0,0 +1,154 @@
+package timerange
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestParseTime(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	tokyo := mustLocation(t, "Asia/Tokyo")
+	// Wednesday
+	now := time.Date(2024, 5, 15, 13, 47, 21, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		now   time.Time
+		loc   *time.Location
+		want  time.Time
+	}{
+		{"now", "now", now, time.UTC, now},
+		{"offsets", "now-1h+15m", now, time.UTC, now.Add(-45 * time.Minute)},
+		{"round seconds", "now/s", now, time.UTC, time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)},
+		{"round minutes", "now/m", now, time.UTC, time.Date(2024, 5, 15, 13, 47, 0, 0, time.UTC)},
+		{"round hours", "now/h", now, time.UTC, time.Date(2024, 5, 15, 13, 0, 0, 0, time.UTC)},
+		{"round day", "now/d", now, time.UTC, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", "now-1d/d", now, time.UTC, time.Date(2024, 5, 14, 0, 0, 0, 0, time.UTC)},
+		{"round week to monday", "now/w", now, time.UTC, time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"round week on sunday", "now/w", time.Date(2024, 5, 19, 10, 0, 0, 0, time.UTC), time.UTC, time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)},
+		{"round month", "now/M", now, time.UTC, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"round year", "now/y", now, time.UTC, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"offset after rounding", "now/d+8h", now, time.UTC, time.Date(2024, 5, 15, 8, 0, 0, 0, time.UTC)},
+		{"month end", "now-1M", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), time.UTC, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)},
+
+		// /d rounds to midnight in the requested zone, the result is returned in UTC
+		{"day in tokyo", "now/d", now, tokyo, time.Date(2024, 5, 14, 15, 0, 0, 0, time.UTC)},
+		{"day in new york", "now/d", now, newYork, time.Date(2024, 5, 15, 4, 0, 0, 0, time.UTC)},
+		{"day across utc midnight", "now/d", time.Date(2024, 5, 15, 2, 0, 0, 0, time.UTC), newYork, time.Date(2024, 5, 14, 4, 0, 0, 0, time.UTC)},
+
+		// Days follow the wall clock, so around a DST change they are 23 or 25 hours long
+		{"day across spring forward", "now-1d", time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC), newYork, time.Date(2024, 3, 9, 17, 0, 0, 0, time.UTC)},
+		{"day across fall back", "now-1d", time.Date(2024, 11, 3, 17, 0, 0, 0, time.UTC), newYork, time.Date(2024, 11, 2, 16, 0, 0, 0, time.UTC)},
+		{"spring forward midnight", "now/d", time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC), newYork, time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC)},
+		{"fall back midnight", "now/d", time.Date(2024, 11, 3, 16, 0, 0, 0, time.UTC), newYork, time.Date(2024, 11, 3, 4, 0, 0, 0, time.UTC)},
+		{"hours ignore dst", "now-24h", time.Date(2024, 3, 10, 16, 0, 0, 0, time.UTC), newYork, time.Date(2024, 3, 9, 16, 0, 0, 0, time.UTC)},
+
+		{"epoch millis", "1715780841500", now, newYork, now},
+		{"rfc3339 keeps its offset", "2024-05-15T09:47:21.5-04:00", now, tokyo, now},
+		{"local layout in zone", "2024-05-15 09:47:21", now, newYork, time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)},
+		{"date in zone", "2024-05-15", now, tokyo, time.Date(2024, 5, 14, 15, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTime(tt.value, tt.now, tt.loc)
+			if err != nil {
+				t.Fatalf("ParseTime(%q): %v", tt.value, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+			if got.Location() != time.UTC {
+				t.Errorf("ParseTime(%q) returned location %s, want UTC", tt.value, got.Location())
+			}
+		})
+	}
+}
+
+func TestParseTimeErrors(t *testing.T) {
+	now := time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		position int
+	}{
+		{"", 0},
+		{"yesterday", 0},
+		{"now*1d", 4},
+		{"now-d", 5},
+		{"now-1", 6},
+		{"now-1x", 6},
+		{"now/q", 5},
+		{"now-9999999d", 5},
+		{"99999999999999999", 0},
+
+		// Results a MySQL DATETIME can't hold
+		{"now-3000y", 4},
+		{"now-100000M", 4},
+		{"now+8000y", 4},
+		{"now-1d-1000000w", 7},
+		{"0999-12-31", 0},
+		{"0999-12-31T23:59:59Z", 0},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseTime(tt.value, now, time.UTC)
+		var parseErr *Error
+		if !errors.As(err, &parseErr) {
+			t.Errorf("ParseTime(%q) error = %v, want *Error", tt.value, err)
+			continue
+		}
+		if parseErr.Position != tt.position {
+			t.Errorf("ParseTime(%q) position = %d, want %d (%v)", tt.value, parseErr.Position, tt.position, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 5, 15, 13, 47, 21, 0, time.UTC)
+
+	got, err := Parse("now-1d/d, now/d", now, mustLocation(t, "Europe/Berlin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 5, 13, 22, 0, 0, 0, time.UTC); got.From == nil || !got.From.Equal(want) {
+		t.Errorf("from = %v, want %s", got.From, want)
+	}
+	if want := time.Date(2024, 5, 14, 22, 0, 0, 0, time.UTC); got.To == nil || !got.To.Equal(want) {
+		t.Errorf("to = %v, want %s", got.To, want)
+	}
+
+	open, err := Parse("now-15m,", now, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if open.From == nil || open.To != nil {
+		t.Errorf("Parse(%q) = %+v, want an open end", "now-15m,", open)
+	}
+
+	for _, value := range []string{"now", ",", "now,now,now", "now,now-1h", "now-1h,bad"} {
+		if _, err := Parse(value, now, time.UTC); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc, err := LoadLocation(""); err != nil || loc != time.UTC {
+		t.Errorf("LoadLocation(\"\") = %v, %v, want UTC", loc, err)
+	}
+	if _, err := LoadLocation("Mars/Olympus_Mons"); err == nil {
+		t.Error("LoadLocation accepted an unknown zone")
+	}
+}
@@ -0,0 +1,276 @@
+// Package tikubelog ships logs to the logger service. Logs are buffered in memory and sent in
+// batches by a background goroutine, over HTTP or straight to the Kafka log topic.
+//
+//	client := tikubelog.New(tikubelog.NewHTTPTransport("https://logger.example.com"), tikubelog.Options{Source: "PAYMENTS:API"})
+//	defer client.Close(context.Background())
+//	slog.SetDefault(slog.New(tikubelog.NewHandler(client, nil)))
+package tikubelog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"tikube-backend/shared/tracing"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+const (
+	defaultBufferSize    = 10_000
+	defaultBatchSize     = 500
+	defaultFlushInterval = time.Second
+	defaultMaxRetries    = 5
+	defaultRetryBackoff  = 500 * time.Millisecond
+	maxRetryBackoff      = 30 * time.Second
+)
+
+var (
+	ErrBufferFull = errors.New("tikubelog: buffer is full, log dropped")
+	ErrClosed     = errors.New("tikubelog: client is closed")
+)
+
+// OverflowPolicy decides what happens to a log when the buffer is full.
+type OverflowPolicy int
+
+const (
+	// Drop discards the log and counts it in Dropped, logging never slows the caller down.
+	Drop OverflowPolicy = iota
+	// Block waits until there is room in the buffer or the context is done.
+	Block
+)
+
+// Options configures a Client. Zero values use the defaults.
+type Options struct {
+	// Source of the logs that don't set one, e.g. PAYMENTS:API.
+	Source string
+	// Number of logs held in memory while waiting to be sent, 10000 by default.
+	BufferSize int
+	// Largest number of logs sent in one request, 500 by default.
+	BatchSize int
+	// How long a log waits for its batch to fill up, 1s by default.
+	FlushInterval time.Duration
+	// Retries of a failed batch before it is dropped, 5 by default. Retries back off exponentially
+	// from RetryBackoff, 500ms by default.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Overflow     OverflowPolicy
+	// OnError is called from the sending goroutine when a batch is dropped.
+	OnError func(err error, logs []utils.CreateLogSchema)
+}
+
+type Client struct {
+	transport Transport
+	options   Options
+
+	buffer  chan utils.CreateLogSchema
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+	dropped   atomic.Uint64
+}
+
+// New starts a client sending logs through transport. Close must be called to send the buffered logs.
+func New(transport Transport, options Options) *Client {
+	if options.BufferSize <= 0 {
+		options.BufferSize = defaultBufferSize
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBatchSize
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = defaultFlushInterval
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	} else if options.MaxRetries == 0 {
+		options.MaxRetries = defaultMaxRetries
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = defaultRetryBackoff
+	}
+
+	c := &Client{
+		transport: transport,
+		options:   options,
+		buffer:    make(chan utils.CreateLogSchema, options.BufferSize),
+		flushes:   make(chan chan struct{}),
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Log buffers a log. The trace context of ctx, if any, is attached to it.
+func (c *Client) Log(ctx context.Context, level utils.LogLevel, message string, attributes map[string]any) error {
+	log := utils.CreateLogSchema{LogLevel: level, Message: message, Attributes: attributes}
+	if tc, ok := tracing.FromContext(ctx); ok {
+		log.TraceId, log.SpanId = tc.TraceId, tc.SpanId
+	}
+	return c.Write(ctx, log)
+}
+
+// Write buffers a log as is, filling in the source and timestamp when they are missing. With the
+// Drop policy it returns ErrBufferFull when the log was discarded.
+func (c *Client) Write(ctx context.Context, log utils.CreateLogSchema) error {
+	if c.closed.Load() {
+		return ErrClosed
+	}
+	if log.Source == "" {
+		log.Source = c.options.Source
+	}
+	if log.Timestamp == nil {
+		now := time.Now().UTC()
+		log.Timestamp = &now
+	}
+
+	if c.options.Overflow == Block {
+		select {
+		case c.buffer <- log:
+			return nil
+		case <-ctx.Done():
+			c.dropped.Add(1)
+			return ctx.Err()
+		case <-c.done:
+			return ErrClosed
+		}
+	}
+
+	select {
+	case c.buffer <- log:
+		return nil
+	default:
+		c.dropped.Add(1)
+		return ErrBufferFull
+	}
+}
+
+// Dropped is the number of logs discarded because the buffer was full.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Flush sends every buffered log and waits until they are delivered or dropped.
+func (c *Client) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case c.flushes <- ack:
+	case <-c.stopped:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close sends the buffered logs and stops the client. Logs still buffered when ctx is done are lost.
+func (c *Client) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		close(c.done)
+	})
+
+	select {
+	case <-c.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return c.transport.Close()
+}
+
+func (c *Client) run() {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]utils.CreateLogSchema, 0, c.options.BatchSize)
+	send := func() {
+		if len(batch) > 0 {
+			c.send(batch)
+			batch = make([]utils.CreateLogSchema, 0, c.options.BatchSize)
+		}
+	}
+	// drain moves everything buffered so far into batches, sending the full ones
+	drain := func() {
+		for {
+			select {
+			case log := <-c.buffer:
+				if batch = append(batch, log); len(batch) >= c.options.BatchSize {
+					send()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case log := <-c.buffer:
+			if batch = append(batch, log); len(batch) >= c.options.BatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case ack := <-c.flushes:
+			drain()
+			send()
+			close(ack)
+		case <-c.done:
+			drain()
+			send()
+			return
+		}
+	}
+}
+
+// send delivers a batch, retrying with exponential backoff unless the transport reports that the
+// batch can never be accepted.
+func (c *Client) send(batch []utils.CreateLogSchema) {
+	backoff := c.options.RetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = c.transport.Send(context.Background(), batch)
+		if err == nil {
+			return
+		}
+
+		var permanent *PermanentError
+		if errors.As(err, &permanent) || attempt >= c.options.MaxRetries {
+			break
+		}
+		var partial *PartialError
+		if errors.As(err, &partial) {
+			batch = partial.Failed
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-c.done:
+			// Closing: retry once without waiting so that Close doesn't hang on an unreachable service
+			if attempt > 0 {
+				c.report(err, batch)
+				return
+			}
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+	c.report(err, batch)
+}
+
+func (c *Client) report(err error, batch []utils.CreateLogSchema) {
+	if c.options.OnError != nil {
+		c.options.OnError(err, batch)
+	}
+}
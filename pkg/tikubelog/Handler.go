@@ -0,0 +1,235 @@
+package tikubelog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"tikube-backend/shared/tracing"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// Attributes holding the trace context, for code that logs the ids instead of passing a context
+// carrying them.
+var (
+	traceIdKeys = []string{"trace_id", "traceId", "traceID"}
+	spanIdKeys  = []string{"span_id", "spanId", "spanID"}
+)
+
+type HandlerOptions struct {
+	// Minimum level logged, slog.LevelInfo by default.
+	Level slog.Leveler
+	// Source of the logs, the client's source by default.
+	Source string
+	// AddSource adds the code.function, code.filepath and code.lineno attributes.
+	AddSource bool
+}
+
+// Handler is a slog.Handler writing to a Client. Groups are flattened into dotted attribute keys,
+// e.g. slog.Group("http", "status", 200) becomes http.status.
+type Handler struct {
+	client     *Client
+	options    HandlerOptions
+	attributes map[string]any
+	prefix     string
+}
+
+func NewHandler(client *Client, options *HandlerOptions) *Handler {
+	h := &Handler{client: client, attributes: map[string]any{}}
+	if options != nil {
+		h.options = *options
+	}
+	if h.options.Level == nil {
+		h.options.Level = slog.LevelInfo
+	}
+	return h
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.options.Level.Level()
+}
+
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	attributes := make(map[string]any, len(h.attributes)+record.NumAttrs())
+	for key, value := range h.attributes {
+		attributes[key] = value
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addAttr(attributes, h.prefix, attr)
+		return true
+	})
+
+	if h.options.AddSource && record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		attributes["code.function"] = frame.Function
+		attributes["code.filepath"] = frame.File
+		attributes["code.lineno"] = frame.Line
+	}
+
+	log := utils.CreateLogSchema{
+		LogLevel: Level(record.Level),
+		Source:   h.options.Source,
+		Message:  record.Message,
+	}
+	if !record.Time.IsZero() {
+		timestamp := record.Time.UTC()
+		log.Timestamp = &timestamp
+	}
+
+	if tc, ok := tracing.FromContext(ctx); ok {
+		log.TraceId, log.SpanId = tc.TraceId, tc.SpanId
+	} else if traceId, ok := takeString(attributes, traceIdKeys); ok {
+		log.TraceId = strings.ToLower(traceId)
+		if spanId, ok := takeString(attributes, spanIdKeys); ok {
+			log.SpanId = strings.ToLower(spanId)
+		}
+	}
+
+	if len(attributes) > 0 {
+		log.Attributes = limitAttributes(attributes)
+	}
+
+	err := h.client.Write(ctx, log)
+	if errors.Is(err, ErrBufferFull) {
+		// Dropping is the configured behaviour, it is counted by the client
+		return nil
+	}
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	clone := *h
+	clone.attributes = make(map[string]any, len(h.attributes)+len(attrs))
+	for key, value := range h.attributes {
+		clone.attributes[key] = value
+	}
+	for _, attr := range attrs {
+		addAttr(clone.attributes, h.prefix, attr)
+	}
+	return &clone
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// Level maps a slog level onto the logger's levels. Levels below slog.LevelDebug are TRACE and
+// levels four above slog.LevelError, the next step of slog's scale, are FATAL.
+func Level(level slog.Level) utils.LogLevel {
+	switch {
+	case level < slog.LevelDebug:
+		return utils.TRACE
+	case level < slog.LevelInfo:
+		return utils.DEBUG
+	case level < slog.LevelWarn:
+		return utils.INFO
+	case level < slog.LevelError:
+		return utils.WARN
+	case level < slog.LevelError+4:
+		return utils.ERROR
+	}
+	return utils.FATAL
+}
+
+func addAttr(attributes map[string]any, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if attr.Key == "" && value.Kind() != slog.KindGroup {
+		return
+	}
+
+	if value.Kind() == slog.KindGroup {
+		// Inline groups without a key, as slog's own handlers do
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range value.Group() {
+			addAttr(attributes, prefix, member)
+		}
+		return
+	}
+
+	// The logger service rejects logs with invalid keys, so they are fixed rather than dropped
+	if key := utils.SanitizeAttributeKey(prefix + attr.Key); key != "" {
+		attributes[key] = attrValue(value)
+	}
+}
+
+func attrValue(value slog.Value) any {
+	switch value.Kind() {
+	case slog.KindString:
+		return value.String()
+	case slog.KindInt64:
+		return value.Int64()
+	case slog.KindUint64:
+		return value.Uint64()
+	case slog.KindFloat64:
+		return value.Float64()
+	case slog.KindBool:
+		return value.Bool()
+	case slog.KindDuration:
+		return value.Duration().String()
+	case slog.KindTime:
+		return value.Time().UTC().Format(time.RFC3339Nano)
+	}
+
+	switch v := value.Any().(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		// A value that can't be encoded would make the whole batch fail
+		if _, err := json.Marshal(v); err != nil {
+			return fmt.Sprint(v)
+		}
+		return v
+	}
+}
+
+func takeString(attributes map[string]any, keys []string) (string, bool) {
+	for _, key := range keys {
+		if value, ok := attributes[key].(string); ok {
+			delete(attributes, key)
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// limitAttributes keeps at most utils.MaxAttributes, preferring the shortest keys so that top level
+// attributes survive over deeply grouped ones.
+func limitAttributes(attributes map[string]any) map[string]any {
+	if len(attributes) <= utils.MaxAttributes {
+		return attributes
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) < len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	limited := make(map[string]any, utils.MaxAttributes)
+	for _, key := range keys[:utils.MaxAttributes] {
+		limited[key] = attributes[key]
+	}
+	return limited
+}
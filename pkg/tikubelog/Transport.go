@@ -0,0 +1,204 @@
+package tikubelog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBM/sarama"
+	"io"
+	"net/http"
+	"strings"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// Transport delivers a batch of logs. Send is only called from the client's sending goroutine.
+type Transport interface {
+	Send(ctx context.Context, logs []utils.CreateLogSchema) error
+	Close() error
+}
+
+// PermanentError is returned by a transport when retrying the batch can't succeed, e.g. when the
+// service rejected it as invalid.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// PartialError is returned by a transport when only some logs of the batch were delivered. Only
+// the Failed ones are retried.
+type PartialError struct {
+	Err    error
+	Failed []utils.CreateLogSchema
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d logs were not delivered: %v", len(e.Failed), e.Err)
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPTransport posts batches to the /logger/logs endpoint of the logger service.
+type HTTPTransport struct {
+	url    string
+	client *http.Client
+	gzip   bool
+	header http.Header
+}
+
+type HTTPOption func(*HTTPTransport)
+
+// WithHTTPClient replaces the default client, which times out after 30s.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.client = client
+	}
+}
+
+// WithoutGzip sends uncompressed bodies, batches are gzip compressed by default.
+func WithoutGzip() HTTPOption {
+	return func(t *HTTPTransport) {
+		t.gzip = false
+	}
+}
+
+// WithHeader adds a header to every request, e.g. for authentication.
+func WithHeader(key, value string) HTTPOption {
+	return func(t *HTTPTransport) {
+		t.header.Add(key, value)
+	}
+}
+
+// NewHTTPTransport sends logs to the logger service at baseURL, e.g. https://logger.example.com.
+func NewHTTPTransport(baseURL string, options ...HTTPOption) *HTTPTransport {
+	t := &HTTPTransport{
+		url:    strings.TrimSuffix(baseURL, "/") + "/logger/logs",
+		client: &http.Client{Timeout: 30 * time.Second},
+		gzip:   true,
+		header: http.Header{},
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+func (t *HTTPTransport) Send(ctx context.Context, logs []utils.CreateLogSchema) error {
+	data, err := json.Marshal(logs)
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+
+	if t.gzip {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(data); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		data = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return &PermanentError{Err: err}
+	}
+	for key, values := range t.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err = fmt.Errorf("logger service responded %s: %s", res.Status, strings.TrimSpace(string(body)))
+	// Rate limiting and server errors are worth retrying, other client errors are not
+	if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+		return &PermanentError{Err: err}
+	}
+	return err
+}
+
+func (t *HTTPTransport) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// KafkaTransport produces logs straight to the log topic, one message per log as the logger service
+// consumes them. The producer should come from a config built with KafkaConfig.
+type KafkaTransport struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+// KafkaConfig returns a producer config suited to KafkaTransport: gzip compressed batches and
+// acknowledged writes. Brokers, TLS and SASL are left to the caller.
+func KafkaConfig() *sarama.Config {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForLocal
+	config.Producer.Compression = sarama.CompressionGZIP
+	// Retries are done by the client so that OnError sees the batches that could not be delivered
+	config.Producer.Retry.Max = 0
+	return config
+}
+
+// NewKafkaTransport produces to the logger service's topic. The transport closes the producer.
+func NewKafkaTransport(producer sarama.SyncProducer) *KafkaTransport {
+	return &KafkaTransport{producer: producer, topic: utils.LoggerTopic}
+}
+
+func (t *KafkaTransport) Send(ctx context.Context, logs []utils.CreateLogSchema) error {
+	messages := make([]*sarama.ProducerMessage, 0, len(logs))
+	for _, log := range logs {
+		data, err := json.Marshal(log)
+		if err != nil {
+			return &PermanentError{Err: err}
+		}
+		messages = append(messages, &sarama.ProducerMessage{Topic: t.topic, Value: sarama.ByteEncoder(data), Metadata: log})
+	}
+
+	err := t.producer.SendMessages(messages)
+	var errs sarama.ProducerErrors
+	if errors.As(err, &errs) && len(errs) < len(messages) {
+		// Only the failed messages are retried, the others are already in the topic
+		failed := make([]utils.CreateLogSchema, 0, len(errs))
+		for _, e := range errs {
+			failed = append(failed, e.Msg.Metadata.(utils.CreateLogSchema))
+		}
+		return &PartialError{Err: err, Failed: failed}
+	}
+	return err
+}
+
+func (t *KafkaTransport) Close() error {
+	return t.producer.Close()
+}
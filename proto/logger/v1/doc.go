@@ -0,0 +1,4 @@
+// Package loggerv1 is the gRPC API of the logger service, generated from logger.proto.
+package loggerv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative logger/v1/logger.proto
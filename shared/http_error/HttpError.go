@@ -1,9 +1,39 @@
 package http_error
 
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrorCode identifies the kind of an error. Codes are part of the API, clients match on them
+// instead of on messages, so existing codes must not change.
+type ErrorCode string
+
+const (
+	CodeBadRequest                  ErrorCode = "bad_request"
+	CodeValidationFailed            ErrorCode = "validation_failed"
+	CodeUnauthorized                ErrorCode = "unauthorized"
+	CodeForbidden                   ErrorCode = "forbidden"
+	CodeNotFound                    ErrorCode = "not_found"
+	CodeConflict                    ErrorCode = "conflict"
+	CodePayloadTooLarge             ErrorCode = "payload_too_large"
+	CodeUnsupportedMediaType        ErrorCode = "unsupported_media_type"
+	CodeRateLimited                 ErrorCode = "rate_limited"
+	CodeRequestHeaderFieldsTooLarge ErrorCode = "request_header_fields_too_large"
+	CodeInternal                    ErrorCode = "internal_error"
+	CodeUnavailable                 ErrorCode = "unavailable"
+)
+
 // HTTPError struct represents an error with an associated HTTP status code.
 type HTTPError struct {
 	StatusCode int
 	Message    string
+	Code       ErrorCode
+	// Errors lists the invalid fields of a request that failed validation.
+	Errors FieldErrors
+	// Cause is the internal error behind the response. It is logged but never sent to the client.
+	Cause error
 }
 
 // Error implements the error interface.
@@ -11,6 +41,83 @@ func (e HTTPError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the cause so that errors.Is and errors.As see through the HTTP error.
+func (e HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// WithCause records the internal error behind e.
+func (e *HTTPError) WithCause(cause error) *HTTPError {
+	e.Cause = cause
+	return e
+}
+
+// FieldError describes why one field of a request is invalid. Field is the JSON path of the field,
+// e.g. source or [2].traceId for the third log of a batch.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// FieldErrors is returned by validations that check every field instead of stopping at the first
+// invalid one.
+type FieldErrors []FieldError
+
+func (fe FieldErrors) Error() string {
+	messages := make([]string, len(fe))
+	for i, err := range fe {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Prefix returns the errors with prefix put in front of each field.
+func (fe FieldErrors) Prefix(prefix string) FieldErrors {
+	prefixed := make(FieldErrors, len(fe))
+	for i, err := range fe {
+		prefixed[i] = FieldError{Field: prefix + err.Field, Message: err.Message}
+	}
+	return prefixed
+}
+
+// Problem is the RFC 7807 body of an error response.
+type Problem struct {
+	Type      string      `json:"type"`
+	Title     string      `json:"title"`
+	Status    int         `json:"status"`
+	Detail    string      `json:"detail"`
+	Instance  string      `json:"instance"`
+	Code      ErrorCode   `json:"code"`
+	RequestId string      `json:"requestId,omitempty"`
+	Errors    FieldErrors `json:"errors,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// ProblemContentType is the media type of Problem.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypePrefix is followed by the error code to form the type of a Problem.
+const ProblemTypePrefix = "urn:tikube:error:"
+
+// Problem describes e for the request to instance, the path of the request.
+func (e *HTTPError) Problem(instance string, requestId string) Problem {
+	code := e.Code
+	if code == "" {
+		code = CodeInternal
+	}
+	return Problem{
+		Type:      ProblemTypePrefix + string(code),
+		Title:     http.StatusText(e.StatusCode),
+		Status:    e.StatusCode,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      code,
+		RequestId: requestId,
+		Errors:    e.Errors,
+		Timestamp: time.Now().UTC(),
+	}
+}
+
 // BadRequest returns a 400 Bad Request error.
 func BadRequest(messages ...string) *HTTPError {
 	message := "Bad Request"
@@ -19,7 +126,12 @@ func BadRequest(messages ...string) *HTTPError {
 		message = messages[0]
 	}
 
-	return &HTTPError{400, message}
+	return &HTTPError{StatusCode: 400, Message: message, Code: CodeBadRequest}
+}
+
+// ValidationFailed returns a 400 Bad Request error for a payload with invalid fields.
+func ValidationFailed(message string, errs FieldErrors) *HTTPError {
+	return &HTTPError{StatusCode: 400, Message: message, Code: CodeValidationFailed, Errors: errs}
 }
 
 // Unauthorized returns a 401 Unauthorized error.
@@ -30,7 +142,7 @@ func Unauthorized(messages ...string) *HTTPError {
 		message = messages[0]
 	}
 
-	return &HTTPError{401, message}
+	return &HTTPError{StatusCode: 401, Message: message, Code: CodeUnauthorized}
 }
 
 // Forbidden returns a 403 Forbidden error.
@@ -40,7 +152,7 @@ func Forbidden(messages ...string) *HTTPError {
 	if len(messages) > 0 {
 		message = messages[0]
 	}
-	return &HTTPError{403, message}
+	return &HTTPError{StatusCode: 403, Message: message, Code: CodeForbidden}
 }
 
 // NotFound returns a 404 Not Found error.
@@ -50,7 +162,17 @@ func NotFound(messages ...string) *HTTPError {
 	if len(messages) > 0 {
 		message = messages[0]
 	}
-	return &HTTPError{404, message}
+	return &HTTPError{StatusCode: 404, Message: message, Code: CodeNotFound}
+}
+
+// Conflict returns a 409 Conflict error.
+func Conflict(messages ...string) *HTTPError {
+	message := "Conflict"
+
+	if len(messages) > 0 {
+		message = messages[0]
+	}
+	return &HTTPError{StatusCode: 409, Message: message, Code: CodeConflict}
 }
 
 // PayloadTooLarge returns a 413 Payload Too Large error.
@@ -60,7 +182,17 @@ func PayloadTooLarge(messages ...string) *HTTPError {
 	if len(messages) > 0 {
 		message = messages[0]
 	}
-	return &HTTPError{413, message}
+	return &HTTPError{StatusCode: 413, Message: message, Code: CodePayloadTooLarge}
+}
+
+// UnsupportedMediaType returns a 415 Unsupported Media Type error.
+func UnsupportedMediaType(messages ...string) *HTTPError {
+	message := "Unsupported Media Type"
+
+	if len(messages) > 0 {
+		message = messages[0]
+	}
+	return &HTTPError{StatusCode: 415, Message: message, Code: CodeUnsupportedMediaType}
 }
 
 // TooManyRequests returns a 429 Too Many Requests error.
@@ -70,7 +202,7 @@ func TooManyRequests(messages ...string) *HTTPError {
 	if len(messages) > 0 {
 		message = messages[0]
 	}
-	return &HTTPError{429, message}
+	return &HTTPError{StatusCode: 429, Message: message, Code: CodeRateLimited}
 }
 
 // RequestHeaderFieldsTooLarge returns a 431 Request Header Fields Too Large error.
@@ -80,7 +212,7 @@ func RequestHeaderFieldsTooLarge(messages ...string) *HTTPError {
 	if len(messages) > 0 {
 		message = messages[0]
 	}
-	return &HTTPError{431, message}
+	return &HTTPError{StatusCode: 431, Message: message, Code: CodeRequestHeaderFieldsTooLarge}
 }
 
 // InternalServerError returns a 500 Internal Server Error.
@@ -90,5 +222,15 @@ func InternalServerError(messages ...string) *HTTPError {
 	if len(messages) > 0 {
 		message = messages[0]
 	}
-	return &HTTPError{500, message}
+	return &HTTPError{StatusCode: 500, Message: message, Code: CodeInternal}
+}
+
+// ServiceUnavailable returns a 503 Service Unavailable error, for failures the client should retry.
+func ServiceUnavailable(messages ...string) *HTTPError {
+	message := "Service Unavailable"
+
+	if len(messages) > 0 {
+		message = messages[0]
+	}
+	return &HTTPError{StatusCode: 503, Message: message, Code: CodeUnavailable}
 }
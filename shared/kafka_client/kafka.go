@@ -52,6 +52,7 @@ func KafkaClient(groupId string) (sarama.AsyncProducer, sarama.ConsumerGroup, er
 	if err != nil {
 		log.Fatal(err)
 	}
+	go dispatchResults(producer)
 
 	consumer, err := sarama.NewConsumerGroup(brokers, groupId, config)
 	if err != nil {
@@ -68,10 +69,70 @@ func SendLogToKafka(logMessage string, topic string, producer sarama.AsyncProduc
 		Value: sarama.StringEncoder(logMessage),
 	}
 
-	select {
-	case producer.Input() <- msg:
-	case err := <-producer.Errors():
-		log.Println("Failed to write log to Kafka:", err)
+	producer.Input() <- msg
+}
+
+// SendLogsToKafkaAndWait sends the messages and returns once Kafka has acknowledged all of them, or
+// with the first error that kept one from being written. Messages already sent when ctx is done may
+// still be written.
+func SendLogsToKafkaAndWait(ctx context.Context, logMessages []string, topic string, producer sarama.AsyncProducer) error {
+	delivery := make(chan error, len(logMessages))
+	for _, logMessage := range logMessages {
+		msg := &sarama.ProducerMessage{
+			Topic:    topic,
+			Value:    sarama.StringEncoder(logMessage),
+			Metadata: deliveryReport(delivery),
+		}
+
+		select {
+		case producer.Input() <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	var first error
+	for range logMessages {
+		select {
+		case err := <-delivery:
+			if first == nil {
+				first = err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return first
+}
+
+// deliveryReport receives the result of a message, it must have room for every message sent with it.
+type deliveryReport chan<- error
+
+// dispatchResults drains the Successes and Errors channels, the producer stops once they are full.
+// Messages sent with a deliveryReport get their result there, errors of the others are logged.
+func dispatchResults(producer sarama.AsyncProducer) {
+	successes, errs := producer.Successes(), producer.Errors()
+	for successes != nil || errs != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			if report, ok := msg.Metadata.(deliveryReport); ok {
+				report <- nil
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			if report, ok := err.Msg.Metadata.(deliveryReport); ok {
+				report <- err.Err
+				continue
+			}
+			log.Println("Failed to write log to Kafka:", err)
+		}
 	}
 }
 
@@ -0,0 +1,111 @@
+package shared_middleware
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"tikube-backend/shared/tracing"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// AccessLogMiddleware wraps the router, so that requests no route matches, answered with a 404 or
+// 405, are covered as well. It keeps the X-Request-ID of the request, or assigns one, stores it in
+// the context and echoes it in the response, then writes one log line per request once the response
+// is complete, with the principal the Authorization header names. Requests aborted by a panic are
+// logged too.
+func AccessLogMiddleware(logger *slog.Logger, router *mux.Router) http.Handler {
+	router.Use(recordRoute)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestId := r.Header.Get(utils.RequestIdHeader)
+		if !utils.IsValidRequestId(requestId) {
+			requestId = utils.NewRequestId()
+		}
+		info := &utils.RequestInfo{Id: requestId, Principal: utils.PrincipalFromAuthorization(r.Header.Get("Authorization"))}
+		w.Header().Set(utils.RequestIdHeader, requestId)
+
+		recorder := &statusRecorder{ResponseWriter: w}
+		completed := false
+		defer func() {
+			status := recorder.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			attrs := []slog.Attr{
+				slog.String("requestId", requestId),
+				slog.String("method", r.Method),
+				slog.String("route", info.Route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", recorder.bytes),
+				slog.Float64("durationMs", float64(time.Since(start).Microseconds())/1000),
+				slog.String("clientIp", utils.GetClientIP(r)),
+				slog.String("userAgent", r.UserAgent()),
+				slog.String("principal", info.Principal),
+			}
+			// The trace context is set further in, the response header is the only place it is visible here
+			if tc, ok := tracing.ParseTraceParent(recorder.Header().Get(tracing.TraceParentHeader)); ok {
+				attrs = append(attrs, slog.String("traceId", tc.TraceId))
+			}
+
+			level := slog.LevelInfo
+			if !completed {
+				level = slog.LevelError
+				attrs = append(attrs, slog.Bool("aborted", true))
+			} else if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(context.Background(), level, "request", attrs...)
+		}()
+
+		router.ServeHTTP(recorder, r.WithContext(utils.WithRequestInfo(r.Context(), info)))
+		completed = true
+	})
+}
+
+// recordRoute stores the path template of the matched route, e.g. /logger/logs/{id:[0-9]+}, which
+// unlike the path groups requests to the same endpoint. The router only runs it for matched routes.
+func recordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info := utils.RequestInfoFromContext(r.Context()); info != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					info.Route = template
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder captures the status code and size of a response. Unwrap lets
+// http.ResponseController reach the flushing and deadline methods of the underlying writer.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(p)
+	sr.bytes += int64(n)
+	return n, err
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -9,8 +9,9 @@ func CorsMiddleware(next utils.HTTPHandler) utils.HTTPHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		// Set CORS headers
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
+		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, traceparent, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "traceparent, X-Request-ID")
 
 		// Check if it is a preflight request
 		if r.Method == "OPTIONS" {
@@ -1,28 +1,52 @@
 package shared_middleware
 
 import (
+	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 	"tikube-backend/shared/http_error"
 	"tikube-backend/shared/utils"
-	"time"
 )
 
+// ErrorHandlerMiddleware answers failed requests with an RFC 7807 problem. Errors that are not an
+// HTTPError become a 500 whose cause is logged together with the request id but not sent to the client.
 func ErrorHandlerMiddleware(handler utils.HTTPHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// The request id is set by AccessLogMiddleware, it is only created here for handlers served without it
+		requestId := utils.RequestIdFromContext(r.Context())
+		if requestId == "" {
+			requestId = utils.NewRequestId()
+			r = r.WithContext(utils.WithRequestInfo(r.Context(), &utils.RequestInfo{Id: requestId}))
+			w.Header().Set(utils.RequestIdHeader, requestId)
+		}
+
 		err := handler(w, r)
-		if err != nil {
-			path := r.URL.Path
-			method := r.Method
-			// Check if the error is of type HTTPError
-			var httpErr *http_error.HTTPError
-			if errors.As(err, &httpErr) {
-				// Construct and send the JSON response with the status code and message from the error
-				_ = utils.JSONResponse(w, httpErr.StatusCode, map[string]interface{}{"message": httpErr.Message, "statusCode": httpErr.StatusCode, "path": path, "method": method, "timestamp": time.Now().UTC()})
-			} else {
-				// For non-HTTPError, send a generic server error
-				_ = utils.JSONResponse(w, http.StatusInternalServerError, map[string]interface{}{"message": "Internal Server Error", "statusCode": 500, "path": path, "method": method, "timestamp": time.Now().UTC()})
-			}
+		if err == nil {
+			return
+		}
+
+		// Check if the error is of type HTTPError
+		var httpErr *http_error.HTTPError
+		if !errors.As(err, &httpErr) {
+			// For non-HTTPError, send a generic server error
+			httpErr = http_error.InternalServerError().WithCause(err)
+		}
+		if httpErr.StatusCode >= http.StatusInternalServerError {
+			slog.Error("request failed", "requestId", requestId, "method", r.Method, "path", r.URL.Path, "error", describeCause(err).Error())
 		}
+
+		w.Header().Set("Content-Type", http_error.ProblemContentType)
+		w.WriteHeader(httpErr.StatusCode)
+		_ = json.NewEncoder(w).Encode(httpErr.Problem(r.URL.Path, requestId))
+	}
+}
+
+// describeCause returns the internal cause of err, or err itself when it has none.
+func describeCause(err error) error {
+	var httpErr *http_error.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Cause != nil {
+		return httpErr.Cause
 	}
+	return err
 }
@@ -3,6 +3,7 @@ package shared_middleware
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"tikube-backend/shared/http_error"
 	"tikube-backend/shared/utils"
@@ -22,9 +23,11 @@ func PayloadValidationMiddleware[T utils.ValidatableSchema](factory func() T) ut
 				return http_error.BadRequest("Invalid payload: " + err.Error())
 			}
 
-			// Validate fields
+			// Validate fields, schemas that check every field report each invalid one
 			if err := payloadSchema.Validate(); err != nil {
-				return http_error.BadRequest("Validation error: " + err.Error())
+				var fieldErrors http_error.FieldErrors
+				errors.As(err, &fieldErrors)
+				return http_error.ValidationFailed("Validation error: "+err.Error(), fieldErrors)
 			}
 
 			ctx := context.WithValue(r.Context(), utils.PayloadKey{}, payloadSchema)
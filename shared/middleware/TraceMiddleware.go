@@ -0,0 +1,21 @@
+package shared_middleware
+
+import (
+	"net/http"
+	"tikube-backend/shared/tracing"
+	"tikube-backend/shared/utils"
+)
+
+// TraceMiddleware continues the trace of an incoming traceparent header, or starts a new one, and
+// stores the request's span in the context. The span is echoed in the traceparent response header.
+func TraceMiddleware(next utils.HTTPHandler) utils.HTTPHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		tc := tracing.NewTraceContext()
+		if parent, ok := tracing.ParseTraceParent(r.Header.Get(tracing.TraceParentHeader)); ok {
+			tc = parent.NewChild()
+		}
+
+		w.Header().Set(tracing.TraceParentHeader, tc.TraceParent())
+		return next(w, r.WithContext(tracing.WithTraceContext(r.Context(), tc)))
+	}
+}
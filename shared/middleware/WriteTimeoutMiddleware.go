@@ -0,0 +1,21 @@
+package shared_middleware
+
+import (
+	"net/http"
+	"tikube-backend/shared/utils"
+	"time"
+)
+
+// WriteTimeoutMiddleware replaces the server wide WriteTimeout for a single route, for handlers
+// such as exports that stream for longer than regular requests.
+func WriteTimeoutMiddleware(timeout time.Duration) utils.Middleware {
+	return func(next utils.HTTPHandler) utils.HTTPHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout))
+			if err != nil {
+				return err
+			}
+			return next(w, r)
+		}
+	}
+}
@@ -2,7 +2,7 @@ package mysql
 
 import (
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
+	driver "github.com/go-sql-driver/mysql"
 	"log"
 	"os"
 	"time"
@@ -12,12 +12,28 @@ func ConnectToDatabase() *sql.DB {
 
 	dns := os.Getenv("MSQL_DB")
 
-	// Open a new database connection.
-	db, err := sql.Open("mysql", dns)
+	config, err := driver.ParseDSN(dns)
+	if err != nil {
+		log.Fatalf("Error parsing database DSN: %v", err)
+	}
+
+	// Times are stored in UTC. Pinning the session time zone makes CURRENT_TIMESTAMP, NOW() and
+	// UNIX_TIMESTAMP() agree with the UTC times the service passes in, whatever the server's zone.
+	// Logs stored before the pin are converted by migration 0007_logs_utc_times.
+	if config.Params == nil {
+		config.Params = map[string]string{}
+	}
+	config.Params["time_zone"] = "'+00:00'"
+	config.Loc = time.UTC
+
+	connector, err := driver.NewConnector(config)
 	if err != nil {
 		log.Fatalf("Error opening database: %v", err)
 	}
 
+	// Open a new database connection.
+	db := sql.OpenDB(connector)
+
 	db.SetConnMaxLifetime(time.Minute * 3)
 	db.SetMaxOpenConns(10)
 	db.SetMaxIdleConns(10)
@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	driver "github.com/go-sql-driver/mysql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MySQL error numbers of a column or index that already exists. Databases created by an older
+// setup.sql can already have what a migration adds, the statement is then treated as applied.
+const (
+	errDuplicateColumn = 1060
+	errDuplicateKey    = 1061
+)
+
+// Migrate brings the schema in dir up to date. setup.sql creates the tables that don't exist yet,
+// then every file of dir/migrations that has not been applied runs in the order of its name, e.g.
+// 0001_logs_fingerprint.sql. Applied migrations are recorded in the schema_migrations table.
+func Migrate(db *sql.DB, dir string) error {
+	if err := execScript(db, filepath.Join(dir, "setup.sql")); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version VARCHAR(255) PRIMARY KEY,
+		appliedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "migrations", "*.sql"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(filepath.Base(file), ".sql")
+		if applied[version] {
+			continue
+		}
+		if err := execScript(db, file); err != nil {
+			return err
+		}
+		if _, err := db.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
+			return fmt.Errorf("recording migration %s: %w", version, err)
+		}
+	}
+	return nil
+}
+
+func appliedMigrations(db *sql.DB) (map[string]bool, error) {
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[string]bool{}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// execScript runs the statements of a script one at a time, the driver only accepts several
+// statements in one call when the DSN sets multiStatements. They share a connection, so that a SET
+// applies to the statements after it.
+func execScript(db *sql.DB, path string) error {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// The connection is closed rather than returned to the pool with the session settings of the script
+		_ = conn.Raw(func(any) error { return sqldriver.ErrBadConn })
+		_ = conn.Close()
+	}()
+
+	for _, statement := range splitStatements(string(script)) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil && !isAlreadyApplied(err) {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return nil
+}
+
+// splitStatements splits a script on the semicolons that end a line. Lines starting with -- are
+// comments. The scripts don't contain semicolons inside of strings.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+func isAlreadyApplied(err error) bool {
+	var mysqlErr *driver.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == errDuplicateColumn || mysqlErr.Number == errDuplicateKey)
+}
@@ -0,0 +1,27 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Writer writes an object. Sync returns once everything written so far is durable, callers use it
+// before recording how much of an object has been written.
+type Writer interface {
+	io.WriteCloser
+	Sync() error
+}
+
+// Storage keeps generated files such as exports. Keys are generated by the service, never taken
+// from user input.
+type Storage interface {
+	// Create returns a writer for a new object, replacing any existing one.
+	Create(key string) (Writer, error)
+	// Append truncates the object to offset and returns a writer positioned at its end.
+	Append(key string, offset int64) (Writer, error)
+	// Open returns a reader for the object and its size.
+	Open(key string) (io.ReadSeekCloser, int64, error)
+	Delete(key string) error
+}
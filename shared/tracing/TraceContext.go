@@ -0,0 +1,100 @@
+// Package tracing implements the W3C Trace Context traceparent header used to correlate logs
+// across services.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// TraceParentHeader is the HTTP and Kafka header carrying the trace context.
+const TraceParentHeader = "traceparent"
+
+// TraceContext identifies the current span. Ids are lowercase hex.
+type TraceContext struct {
+	TraceId string
+	SpanId  string
+	Flags   byte
+}
+
+const sampledFlag byte = 0x01
+
+// ParseTraceParent parses a version 00 traceparent header, e.g.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceParent(header string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	// Future versions may append fields, only the first four are understood
+	if len(parts) < 4 || parts[0] == "ff" || len(parts[0]) != 2 || !isHex(parts[0]) {
+		return TraceContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceContext{}, false
+	}
+
+	traceId, spanId := strings.ToLower(parts[1]), strings.ToLower(parts[2])
+	if !IsValidTraceId(traceId) || !IsValidSpanId(spanId) {
+		return TraceContext{}, false
+	}
+
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return TraceContext{}, false
+	}
+	return TraceContext{TraceId: traceId, SpanId: spanId, Flags: flags[0]}, true
+}
+
+// TraceParent formats the context as a traceparent header value.
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceId, tc.SpanId, tc.Flags)
+}
+
+// NewTraceContext starts a new sampled trace.
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceId: randomHex(16), SpanId: randomHex(8), Flags: sampledFlag}
+}
+
+// NewChild returns a new span in the same trace.
+func (tc TraceContext) NewChild() TraceContext {
+	return TraceContext{TraceId: tc.TraceId, SpanId: randomHex(8), Flags: tc.Flags}
+}
+
+// IsValidTraceId reports whether id is 32 hex characters and not all zeros, as the spec requires.
+func IsValidTraceId(id string) bool {
+	return len(id) == 32 && isHex(id) && strings.Trim(id, "0") != ""
+}
+
+// IsValidSpanId reports whether id is 16 hex characters and not all zeros.
+func IsValidSpanId(id string) bool {
+	return len(id) == 16 && isHex(id) && strings.Trim(id, "0") != ""
+}
+
+type contextKey struct{}
+
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, contextKey{}, tc)
+}
+
+func FromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(contextKey{}).(TraceContext)
+	return tc, ok
+}
+
+func isHex(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand does not fail on supported platforms; an all zero id would be rejected by receivers anyway
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
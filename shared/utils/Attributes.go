@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Limits on the attributes of a log. The logger service rejects logs beyond them, clients such as
+// pkg/tikubelog apply them before sending.
+const (
+	MaxAttributes         = 64
+	MaxAttributeKeyLength = 128
+)
+
+// Attribute keys are used in JSON paths and facet queries, so they are restricted to a safe character set.
+var (
+	attributeKeyPattern           = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9_.\-]{1,%d}$`, MaxAttributeKeyLength))
+	invalidAttributeKeyCharacters = regexp.MustCompile(`[^A-Za-z0-9_.\-]`)
+)
+
+// ValidAttributeKey reports whether key can be used as an attribute key.
+func ValidAttributeKey(key string) bool {
+	return attributeKeyPattern.MatchString(key)
+}
+
+// SanitizeAttributeKey turns key into a valid one, unless it is empty, by replacing the characters
+// that are not allowed with _ and truncating it.
+func SanitizeAttributeKey(key string) string {
+	key = invalidAttributeKeyCharacters.ReplaceAllString(key, "_")
+	if len(key) > MaxAttributeKeyLength {
+		key = key[:MaxAttributeKeyLength]
+	}
+	return key
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"tikube-backend/shared/http_error"
 )
@@ -14,7 +15,7 @@ func JSONResponse(w http.ResponseWriter, statusCode int, data any) error {
 	if data != nil {
 		err := json.NewEncoder(w).Encode(data)
 		if err != nil {
-			return http_error.InternalServerError()
+			return http_error.InternalServerError().WithCause(fmt.Errorf("encoding response: %w", err))
 		}
 	}
 	return nil
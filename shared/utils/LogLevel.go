@@ -0,0 +1,52 @@
+package utils
+
+import "strings"
+
+// logLevelAliases maps the lowercase spellings accepted from clients to a level.
+var logLevelAliases = map[string]LogLevel{
+	"trace":    TRACE,
+	"debug":    DEBUG,
+	"info":     INFO,
+	"warn":     WARN,
+	"warning":  WARN,
+	"error":    ERROR,
+	"err":      ERROR,
+	"fatal":    FATAL,
+	"crit":     FATAL,
+	"critical": FATAL,
+}
+
+// ParseLogLevel resolves a level name or one of its aliases, ignoring case.
+func ParseLogLevel(value string) (LogLevel, bool) {
+	level, ok := logLevelAliases[strings.ToLower(strings.TrimSpace(value))]
+	return level, ok
+}
+
+// Severity is the OpenTelemetry severity number of the level: the first number of the level's
+// range, e.g. 9 for INFO and 17 for ERROR. Unknown levels have severity 0.
+func (l LogLevel) Severity() int {
+	for i, level := range LogLevels {
+		if level == l {
+			return i*4 + 1
+		}
+	}
+	return 0
+}
+
+// LogLevelFromSeverity maps an OpenTelemetry severity number (1 to 24) to its level.
+func LogLevelFromSeverity(severity int) (LogLevel, bool) {
+	if severity < 1 || severity > len(LogLevels)*4 {
+		return "", false
+	}
+	return LogLevels[(severity-1)/4], true
+}
+
+// LogLevelsAtLeast returns the levels at least as severe as min, least severe first.
+func LogLevelsAtLeast(min LogLevel) []LogLevel {
+	for i, level := range LogLevels {
+		if level == min {
+			return LogLevels[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// AnonymousPrincipal is logged for requests that carry no credentials.
+const AnonymousPrincipal = "anonymous"
+
+// Subjects longer than this are not logged as is, the key id is used instead.
+const maxSubjectLength = 128
+
+// PrincipalFromAuthorization returns who a request claims to come from, for the access log. A
+// bearer JWT is identified by its sub claim, any other bearer token, such as the API key the CLI
+// sends, by "key:" and the start of its SHA-256 so that the secret itself is never logged. The
+// service does not verify credentials, so this is what the client claims, not who it is.
+func PrincipalFromAuthorization(authorization string) string {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return AnonymousPrincipal
+	}
+
+	if subject := jwtSubject(token); subject != "" {
+		return subject
+	}
+	sum := sha256.Sum256([]byte(token))
+	return "key:" + hex.EncodeToString(sum[:6])
+}
+
+// jwtSubject returns the sub claim of a JWT without checking its signature, or "" when token is
+// not a JWT or its subject is not safe to log.
+func jwtSubject(token string) string {
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if json.Unmarshal(payload, &claims) != nil || len(claims.Subject) > maxSubjectLength {
+		return ""
+	}
+	if strings.IndexFunc(claims.Subject, unicode.IsControl) >= 0 {
+		return ""
+	}
+	return claims.Subject
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+)
+
+// RequestIdHeader carries the id of a request, it is echoed in the response and in error bodies.
+const RequestIdHeader = "X-Request-ID"
+
+// Ids sent by clients or proxies are kept when they are reasonably short and safe to log.
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// RequestInfo describes the request being served. It is stored by pointer so that the router can
+// fill in the matched route for the access log, which is written outside of it.
+type RequestInfo struct {
+	Id string
+	// Principal is who the request claims to come from, see PrincipalFromAuthorization.
+	Principal string
+	// Route is the path template of the matched route, empty when no route matched.
+	Route string
+}
+
+type requestInfoKey struct{}
+
+// NewRequestId returns a random 32 character hex id.
+func NewRequestId() string {
+	b := make([]byte, 16)
+	// crypto/rand does not fail on supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// IsValidRequestId reports whether an id received in the X-Request-ID header can be used as is.
+func IsValidRequestId(id string) bool {
+	return requestIdPattern.MatchString(id)
+}
+
+// WithRequestInfo returns a copy of ctx that carries info.
+func WithRequestInfo(ctx context.Context, info *RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request being served, or nil outside of a request.
+func RequestInfoFromContext(ctx context.Context) *RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*RequestInfo)
+	return info
+}
+
+// RequestIdFromContext returns the id of the request, or "" outside of a request.
+func RequestIdFromContext(ctx context.Context) string {
+	if info := RequestInfoFromContext(ctx); info != nil {
+		return info.Id
+	}
+	return ""
+}
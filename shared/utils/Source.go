@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+// SourceSeparator splits a source into its hierarchy, e.g. LOGGER:REPOSITORY.
+const SourceSeparator = ":"
+
+// NormalizeSource trims the source and each of its segments and upper-cases it, so that
+// " payments : api" and "PAYMENTS:API" are the same source.
+func NormalizeSource(source string) string {
+	segments := strings.Split(strings.TrimSpace(source), SourceSeparator)
+	for i, segment := range segments {
+		segments[i] = strings.ToUpper(strings.TrimSpace(segment))
+	}
+	return strings.Join(segments, SourceSeparator)
+}
+
+// ParseSourcePattern normalizes a source filter value. A pattern ending in ":*" matches the source
+// before it and everything below it; prefix is then that source and isPrefix is true.
+func ParseSourcePattern(pattern string) (prefix string, isPrefix bool, err error) {
+	normalized := NormalizeSource(pattern)
+	if base, ok := strings.CutSuffix(normalized, SourceSeparator+"*"); ok {
+		normalized, isPrefix = base, true
+	}
+
+	if normalized == "" || normalized == "*" {
+		return "", false, errors.New("source pattern needs a source, e.g. PAYMENTS or PAYMENTS:*")
+	}
+	if strings.Contains(normalized, "*") {
+		return "", false, errors.New("* is only allowed as the last segment of a source pattern")
+	}
+	for _, segment := range strings.Split(normalized, SourceSeparator) {
+		if segment == "" {
+			return "", false, errors.New("source pattern has an empty segment")
+		}
+	}
+	return normalized, isPrefix, nil
+}
+
+// MatchSource reports whether source matches a pattern accepted by ParseSourcePattern, the same way
+// the source filter of log queries does. Invalid patterns match nothing.
+func MatchSource(pattern string, source string) bool {
+	prefix, isPrefix, err := ParseSourcePattern(pattern)
+	if err != nil {
+		return false
+	}
+	source = NormalizeSource(source)
+	return source == prefix || isPrefix && strings.HasPrefix(source, prefix+SourceSeparator)
+}
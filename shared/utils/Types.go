@@ -1,19 +1,26 @@
 package utils
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"time"
 )
 
 type LogLevel string
 
 const (
+	TRACE LogLevel = "TRACE"
+	DEBUG LogLevel = "DEBUG"
 	INFO  LogLevel = "INFO"
 	WARN  LogLevel = "WARN"
 	ERROR LogLevel = "ERROR"
 	FATAL LogLevel = "FATAL"
 )
 
+// LogLevels lists every level from the least to the most severe.
+var LogLevels = []LogLevel{TRACE, DEBUG, INFO, WARN, ERROR, FATAL}
+
 const LoggerGroupId = "logger-consumers"
 const LoggerTopic = "log_events"
 
@@ -26,12 +33,82 @@ type Pagination struct {
 }
 
 type Log struct {
-	Id        int64     `json:"id"`
-	LogLevel  LogLevel  `json:"logLevel"`
-	Source    string    `json:"source"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	Id          int64          `json:"id"`
+	LogLevel    LogLevel       `json:"logLevel"`
+	Source      string         `json:"source"`
+	Message     string         `json:"message"`
+	Fingerprint string         `json:"fingerprint,omitempty"`
+	TraceId     string         `json:"traceId,omitempty"`
+	SpanId      string         `json:"spanId,omitempty"`
+	Attributes  map[string]any `json:"attributes,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+// LogColumns are the fields of a Log by their JSON names, which are also their column names, in the
+// order in which they are selected and exported.
+var LogColumns = []string{"id", "logLevel", "source", "message", "fingerprint", "traceId", "spanId", "attributes", "createdAt", "updatedAt"}
+
+// IsLogColumn reports whether column is one of LogColumns.
+func IsLogColumn(column string) bool {
+	return slices.Contains(LogColumns, column)
+}
+
+type IssueStatus string
+
+const (
+	IssueOpen     IssueStatus = "open"
+	IssueResolved IssueStatus = "resolved"
+	IssueIgnored  IssueStatus = "ignored"
+)
+
+// Issue groups ERROR and FATAL logs that share a fingerprint.
+type Issue struct {
+	Id          int64       `json:"id"`
+	Fingerprint string      `json:"fingerprint"`
+	LogLevel    LogLevel    `json:"logLevel"`
+	Source      string      `json:"source"`
+	Message     string      `json:"message"`
+	Status      IssueStatus `json:"status"`
+	Occurrences int64       `json:"occurrences"`
+	FirstSeen   time.Time   `json:"firstSeen"`
+	LastSeen    time.Time   `json:"lastSeen"`
+}
+
+type AlertState string
+
+const (
+	AlertFiring   AlertState = "firing"
+	AlertResolved AlertState = "resolved"
+)
+
+// AlertRule fires when more than Threshold logs matching Levels and Source arrive within WindowSeconds.
+type AlertRule struct {
+	Id              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Levels          []LogLevel `json:"levels"`
+	Source          string     `json:"source"`
+	Threshold       int        `json:"threshold"`
+	WindowSeconds   int        `json:"windowSeconds"`
+	CooldownSeconds int        `json:"cooldownSeconds"`
+	WebhookURL      string     `json:"webhookUrl"`
+	Enabled         bool       `json:"enabled"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+type AlertEvent struct {
+	Id        int64      `json:"id"`
+	RuleId    int64      `json:"ruleId"`
+	State     AlertState `json:"state"`
+	Count     int64      `json:"count"`
+	Message   string     `json:"message"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type IssueFilter struct {
+	StatusFilter []string `json:"statusFilter"`
+	Source       string   `json:"source"`
 }
 
 type PaginationResult[T any] struct {
@@ -39,22 +116,186 @@ type PaginationResult[T any] struct {
 	Total int `json:"total"`
 }
 
+// DateFilterRange is a resolved time range in UTC. A nil end leaves the range open on that side.
 type DateFilterRange struct {
-	From string `json:"from"`
-	To   string `json:"to"`
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
 }
 
 type LogFilter struct {
-	LevelFilter []string         `json:"levelFilter"`
-	DateFilter  *DateFilterRange `json:"dateFilter"`
+	LevelFilter  []string         `json:"levelFilter"`
+	DateFilter   *DateFilterRange `json:"dateFilter"`
+	MinLevel     LogLevel         `json:"minLevel,omitempty"`     // keeps logs at this severity or higher
+	SourceFilter []string         `json:"sourceFilter,omitempty"` // sources or prefixes such as PAYMENTS:*
+	Query        string           `json:"query"`                  // LQL query, validated by the handler
+}
+
+// SourceNode is one segment of the source hierarchy. Count includes the logs of every source below it.
+type SourceNode struct {
+	Name     string        `json:"name"`
+	Path     string        `json:"path"`
+	Count    int64         `json:"count"`
+	Children []*SourceNode `json:"children,omitempty"`
+}
+
+// LogContext is a log together with the logs written right before and after it, both in time order.
+type LogContext struct {
+	Log    Log   `json:"log"`
+	Before []Log `json:"before"`
+	After  []Log `json:"after"`
+}
+
+// SortField orders logs by one field. Field uses the JSON names of Log.
+type SortField struct {
+	Field string `json:"field"`
+	Desc  bool   `json:"desc"`
+}
+
+// LogView controls the order and shape of the logs returned by GetLogs. The zero value returns
+// every field in insertion order.
+type LogView struct {
+	Sort          []SortField
+	Fields        []string // JSON names of the fields to return, empty means all of them
+	MessageLength int      // messages are cut to this many characters, 0 keeps them whole
+}
+
+// HistogramCount is one row of the histogram query: the number of logs of a group in a bucket.
+type HistogramCount struct {
+	Bucket time.Time `json:"bucket"`
+	Group  string    `json:"group"`
+	Count  int64     `json:"count"`
+}
+
+type HistogramBucket struct {
+	Time   time.Time        `json:"time"`
+	Total  int64            `json:"total"`
+	Counts map[string]int64 `json:"counts"`
+}
+
+type Histogram struct {
+	Interval string            `json:"interval"`
+	GroupBy  string            `json:"groupBy"`
+	Buckets  []HistogramBucket `json:"buckets"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets holds the counts shown next to the log list. When Approximate is set the counts come from
+// the hourly Redis counters, which only honour the date range of the filter; the query parameters
+// they ignored are listed in IgnoredFilters. The logs themselves always match the whole filter.
+type Facets struct {
+	Logs            *PaginationResult[Log]  `json:"logs"`
+	Levels          []FacetValue            `json:"levels"`
+	Sources         []FacetValue            `json:"sources"`
+	Attributes      map[string][]FacetValue `json:"attributes"`
+	DistinctSources int64                   `json:"distinctSources,omitempty"`
+	Approximate     bool                    `json:"approximate"`
+	IgnoredFilters  []string                `json:"ignoredFilters,omitempty"`
+}
+
+// SearchTimeRange is either relative to the time a search runs (e.g. "15m", "7d") or absolute.
+type SearchTimeRange struct {
+	Relative string `json:"relative,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+}
+
+type SavedSearch struct {
+	Id         int64            `json:"id"`
+	Name       string           `json:"name"`
+	Owner      string           `json:"owner"`
+	Filter     LogFilter        `json:"filter"`
+	Columns    []string         `json:"columns"`
+	TimeRange  *SearchTimeRange `json:"timeRange"`
+	ShareToken string           `json:"shareToken,omitempty"`
+	CreatedAt  time.Time        `json:"createdAt"`
+	UpdatedAt  time.Time        `json:"updatedAt"`
+}
+
+type SavedSearchResult struct {
+	Search *SavedSearch           `json:"search"`
+	Logs   *PaginationResult[Log] `json:"logs"`
+}
+
+type ExportStatus string
+
+const (
+	ExportQueued    ExportStatus = "queued"
+	ExportRunning   ExportStatus = "running"
+	ExportCompleted ExportStatus = "completed"
+	ExportFailed    ExportStatus = "failed"
+	ExportExpired   ExportStatus = "expired"
+)
+
+// ExportJob is an asynchronous export. LastLogId and SizeBytes form the checkpoint an interrupted
+// job resumes from.
+type ExportJob struct {
+	Id          string       `json:"id"`
+	Format      string       `json:"format"`
+	Filter      LogFilter    `json:"filter"`
+	MaxRows     int          `json:"maxRows"`
+	Status      ExportStatus `json:"status"`
+	RowsWritten int64        `json:"rowsWritten"`
+	LastLogId   int64        `json:"-"`
+	SizeBytes   int64        `json:"sizeBytes"`
+	Error       string       `json:"error,omitempty"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	UpdatedAt   time.Time    `json:"updatedAt"`
+	CompletedAt *time.Time   `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time   `json:"expiresAt,omitempty"`
 }
 
 type CreateLogSchema struct {
-	LogLevel LogLevel
-	Source   string
-	Message  string
+	LogLevel   LogLevel       `json:"logLevel"`
+	Source     string         `json:"source"`
+	Message    string         `json:"message"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+	TraceId    string         `json:"traceId,omitempty"`
+	SpanId     string         `json:"spanId,omitempty"`
+	Timestamp  *time.Time     `json:"timestamp,omitempty"`
+}
+
+// Trace is every log of a distributed trace in time order.
+type Trace struct {
+	TraceId   string   `json:"traceId"`
+	Sources   []string `json:"sources"`
+	Logs      []Log    `json:"logs"`
+	Truncated bool     `json:"truncated"`
 }
 
+// IngestResult reports how many logs of a batch were queued for ingestion. Error is the reason the
+// first rejected log was rejected.
+type IngestResult struct {
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SyslogStats counts the messages received by the syslog listener since it started.
+type SyslogStats struct {
+	Enabled       bool   `json:"enabled"`
+	Received      uint64 `json:"received"`
+	Published     uint64 `json:"published"`
+	Rejected      uint64 `json:"rejected"`
+	ParseErrors   uint64 `json:"parseErrors"`
+	FramingErrors uint64 `json:"framingErrors"`
+}
+
+// Listener is a network receiver that runs next to the HTTP server, such as the syslog listener.
+type Listener interface {
+	Name() string
+	// Enabled reports whether the listener is configured, disabled listeners are not started.
+	Enabled() bool
+	// ListenAndServe blocks until Close is called and then returns ErrListenerClosed.
+	ListenAndServe() error
+	Close() error
+}
+
+var ErrListenerClosed = errors.New("listener closed")
+
 type Middleware func(HTTPHandler) HTTPHandler
 
 type HTTPHandler func(http.ResponseWriter, *http.Request) error
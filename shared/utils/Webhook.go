@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"net/netip"
+	"net/url"
+	"strings"
+)
+
+// Ranges that IsGlobalUnicast and IsPrivate don't cover but that never belong to a public host.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+}
+
+// ValidateWebhookURL checks that raw is an absolute http(s) URL that doesn't point at the host
+// itself or at a private network. Host names are checked again once resolved, see IsPublicAddress.
+func ValidateWebhookURL(raw string) error {
+	webhook, err := url.Parse(raw)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Hostname() == "" {
+		return errors.New("webhookUrl must be an absolute http(s) URL")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(webhook.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("webhookUrl must not point at localhost")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicAddress(addr) {
+		return errors.New("webhookUrl must not point at a loopback, private or link-local address")
+	}
+	return nil
+}
+
+// IsPublicAddress reports whether addr is a public unicast address, i.e. not loopback, private,
+// link-local (which includes cloud metadata endpoints), multicast or unspecified.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"net"
 	"net/http"
+	"tikube-backend/shared/tracing"
 )
 
 func GetClientIP(req *http.Request) string {
@@ -29,11 +31,19 @@ func SerializeKafkaMessage[T any](message T) (string, error) {
 	return string(jsonData), nil
 }
 
-func CreateSerializedLog(level LogLevel, source string, message string) string {
+// CreateSerializedLog builds a log of the service itself. Logs written while serving a request
+// carry its request id and trace context.
+func CreateSerializedLog(ctx context.Context, level LogLevel, source string, message string) string {
 	log := CreateLogSchema{
-		level,
-		source,
-		message,
+		LogLevel: level,
+		Source:   source,
+		Message:  message,
+	}
+	if requestId := RequestIdFromContext(ctx); requestId != "" {
+		log.Attributes = map[string]any{"requestId": requestId}
+	}
+	if tc, ok := tracing.FromContext(ctx); ok {
+		log.TraceId, log.SpanId = tc.TraceId, tc.SpanId
 	}
 	d, _ := SerializeKafkaMessage[CreateLogSchema](log)
 	return d